@@ -1,13 +1,29 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"go-job/internal/pkg/auth"
+	"go-job/internal/pkg/httpClient"
 	"go-job/internal/pkg/log"
+	"go-job/internal/upload"
+	"go-job/master/pkg/cluster"
 	"go-job/master/pkg/config"
+	"go-job/master/pkg/dispatcher"
 	"go-job/master/pkg/ioc"
 	"go-job/master/pkg/job"
+	"go-job/master/pkg/metrics"
+	"go-job/master/pkg/nodecert"
+	"go-job/master/pkg/nodeconn"
+	"go-job/master/repo/cache"
 	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
@@ -26,15 +42,94 @@ func main() {
 	RunApp()
 }
 
+// defaultShutdownTimeout 未配置 shutdown_timeout 时退出的最长等待时间
+const defaultShutdownTimeout = 30 * time.Second
+
 func RunApp() {
 	container := ioc.InitWebServer()
-	bootstrap(container)
-	container.Engine.Run(fmt.Sprintf("%s:%d",
-		config.App.Server.Ip, config.App.Server.Port))
+	// 退出时结束后台的集群同步、故障转移、对账等
+	ctx, cancel := context.WithCancel(context.Background())
+	bootstrap(ctx, container)
+	srv := &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", config.App.Server.Ip, config.App.Server.Port),
+		Handler: container.Engine,
+	}
+	if nodecert.Enabled() {
+		srv.TLSConfig = nodecert.ServerTLSConfig()
+	}
+	go func() {
+		var err error
+		if srv.TLSConfig != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			panic(err)
+		}
+	}()
+	slog.Info("master started", "addr", srv.Addr, "tls", srv.TLSConfig != nil)
+
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	<-signalCtx.Done()
+	// 之后再收到信号时直接退出
+	stop()
+	shutdown(srv, container, cancel)
 }
 
-func bootstrap(c *ioc.WebContainer) {
-	err := job.InitGlobalData(c.MysqlDB, c.JobSvc, c.NotifyStore)
+// shutdown 停止调度和接收请求，等待处理中的请求和通知队列发送完成，最多等待 shutdown_timeout
+func shutdown(srv *http.Server, c *ioc.WebContainer, cancel context.CancelFunc) {
+	timeout := time.Duration(config.App.Server.ShutdownTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	slog.Warn("master shutting down", "timeout", timeout)
+	ctx, cancelTimeout := context.WithTimeout(context.Background(), timeout)
+	defer cancelTimeout()
+
+	// 先停止调度，等待正在下发的任务
+	if d := dispatcher.GetDispatcher(); d != nil {
+		select {
+		case <-d.Stop().Done():
+		case <-ctx.Done():
+			slog.Error("wait dispatching jobs timeout")
+		}
+	}
+	// 释放 leader 租约，其他 master 可以立即接替调度
+	cancel()
+	// 等待处理中的请求，如节点上报的执行结果
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("shutdown http server error", "err", err)
+	}
+	if err := c.NotifyStore.Close(ctx); err != nil {
+		slog.Error("flush notify queue error", "err", err)
+	}
+	slog.Info("master stopped")
+}
+
+func bootstrap(ctx context.Context, c *ioc.WebContainer) {
+	// 开启 TLS 时 httpClient 使用 https，需要在包装传输层之前初始化
+	if err := nodecert.Init(config.App.TLS); err != nil {
+		panic(err)
+	}
+	go nodecert.RunRenew(ctx)
+	// 节点通过反向连接发送的请求由同一个 engine 处理
+	nodeconn.Init(c.Engine)
+	// 发往节点的请求使用节点的密钥签名，签名后再选择是否通过反向连接发送
+	httpClient.WrapTransport(func(base http.RoundTripper) http.RoundTripper {
+		return &auth.SignTransport{Base: base, Secret: func(address string) string {
+			return metrics.GetNodeMetrics().SecretByAddress(address)
+		}}
+	})
+	if err := cluster.Init(c.Redis, config.App.Cluster); err != nil {
+		panic(err)
+	}
+	// 多个 master 时上传和创建任务可能由不同的 master 处理
+	if cluster.Enabled() {
+		upload.FileUploadOpts(upload.StoreOpt(cache.NewFileMetaCache(c.Redis)))
+	}
+
+	err := job.InitGlobalData(ctx, c.MysqlDB, c.JobSvc, c.DispatchSvc, c.FailoverSvc, c.ReconcileSvc, c.NotifyStore)
 	if err != nil {
 		slog.Error("init job data to node error", "err", err)
 	}
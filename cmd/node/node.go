@@ -1,13 +1,23 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"go-job/node/pkg/auth"
 	"go-job/node/pkg/config"
 	"go-job/node/pkg/ioc"
+	"go-job/node/pkg/job"
+	"go-job/node/pkg/outbox"
 	"go-job/node/pkg/startup"
+	"go-job/node/pkg/worker"
 	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
@@ -24,15 +34,133 @@ func main() {
 	RunApp()
 }
 
-func beforeRunWeb(container *ioc.WebContainer) {
+func beforeRunWeb(ctx context.Context, container *ioc.WebContainer) {
+	// 开启 TLS 时 httpClient 使用 https，需要在包装传输层之前初始化
+	if config.App.TLS.Enabled {
+		if err := startup.InitTLS(); err != nil {
+			panic(err)
+		}
+	}
 	auth.InitJwtToken(config.App.Master.Key)
-	if err := startup.SyncJobFromMaster(container.JobSvc); err != nil {
+	auth.SetSecret(config.App.Master.Secret)
+	if config.App.Master.InsecureSkipVerify {
+		slog.Warn("!!! master.insecure_skip_verify is enabled, requests are not verified until the node has a secret, " +
+			"anyone who can reach the node port can run any script, only use it during upgrade !!!")
+	}
+	auth.SetNodeId(config.App.Master.NodeId)
+	auth.SetTokenSecret(config.App.Master.TokenSecret)
+	if config.App.Master.Tunnel {
+		startup.InitTunnel()
+	}
+	worker.InitPool(config.App.Worker.Size, config.App.Worker.QueueSize,
+		time.Duration(config.App.Worker.MaxWait)*time.Second)
+	// 发件箱需要在调度之前初始化，上次未上报成功的结果在启动后继续上报
+	if config.App.Outbox.Dir != "" {
+		err := outbox.InitOutbox(config.App.Outbox.Dir, job.CallbackJobResult,
+			outbox.WithBackoff(0, time.Duration(config.App.Outbox.MaxBackoff)*time.Second),
+			outbox.WithRetention(time.Duration(config.App.Outbox.Retention)*time.Hour))
+		if err != nil {
+			slog.Error("init outbox error", "dir", config.App.Outbox.Dir, "err", err)
+		}
+		go outbox.Run(ctx)
+	}
+	// 维护状态需要在开始调度之前恢复，避免重启后维护中的节点继续执行任务
+	container.NodeSvc.RestoreMaintenance(ctx)
+	job.StartScheduler()
+	if config.App.Register.Enabled {
+		// 注册失败时由心跳重试
+		reg, err := startup.RegisterToMaster()
+		if err != nil {
+			slog.Error("register to master error", "err", err)
+		}
+		go startup.RunHeartbeat(ctx, reg)
+	}
+	// 申请证书需要节点id，自注册失败时等注册成功后再申请，同步任务之前需要有证书
+	if config.App.TLS.Enabled {
+		if err := startup.RenewCert(); err != nil {
+			slog.Error("request node cert error", "err", err)
+		}
+		go startup.RunCertRenew(ctx)
+	}
+	// 自注册的节点在注册之后才能建立反向连接，失败时会重试
+	if config.App.Master.Tunnel {
+		go startup.RunTunnel(ctx, container.Engine)
+	}
+	// 先恢复本地保存的任务，master 不可用时节点也能继续调度
+	restored := startup.Restored{
+		Ids:  container.JobSvc.RestoreJobs(ctx),
+		Time: time.Now(),
+	}
+	if err := startup.SyncJobFromMaster(container.JobSvc, restored); err != nil {
 		slog.Error("sync job from master error", "err", err)
+		go startup.RetrySyncJobFromMaster(ctx, container.JobSvc, restored)
 	}
 }
 
+// defaultShutdownTimeout 未配置 shutdown_timeout 时退出的最长等待时间
+const defaultShutdownTimeout = 60 * time.Second
+
 func RunApp() {
 	container := ioc.InitWebServer()
-	beforeRunWeb(container)
-	container.Engine.Run(fmt.Sprintf("%s:%d", config.App.Server.Ip, config.App.Server.Port))
+	// 退出时结束心跳、反向连接、发件箱等后台任务
+	ctx, cancel := context.WithCancel(context.Background())
+	beforeRunWeb(ctx, container)
+	srv := &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", config.App.Server.Ip, config.App.Server.Port),
+		Handler: container.Engine,
+	}
+	if config.App.TLS.Enabled {
+		srv.TLSConfig = startup.ServerTLSConfig()
+	}
+	go func() {
+		var err error
+		if srv.TLSConfig != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			panic(err)
+		}
+	}()
+	slog.Info("node started", "addr", srv.Addr, "tls", srv.TLSConfig != nil)
+
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	<-signalCtx.Done()
+	// 之后再收到信号时直接退出
+	stop()
+	shutdown(srv, cancel)
+}
+
+// shutdown 停止调度，等待正在执行的任务结束并上报结果，最多等待 shutdown_timeout，
+// 排队中的任务上报为丢弃，发件箱中未上报成功的结果在下次启动后继续上报
+func shutdown(srv *http.Server, cancel context.CancelFunc) {
+	timeout := time.Duration(config.App.Server.ShutdownTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	slog.Warn("node shutting down", "timeout", timeout)
+	ctx, cancelTimeout := context.WithTimeout(context.Background(), timeout)
+	defer cancelTimeout()
+
+	// 停止调度，不再触发新的执行
+	select {
+	case <-job.StopScheduler().Done():
+	case <-ctx.Done():
+	}
+	cancel()
+	// 不再接收 master 的请求，等待处理中的请求
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("shutdown http server error", "err", err)
+	}
+	if err := worker.GetPool().Shutdown(ctx); err != nil {
+		slog.Error("wait running jobs timeout", "running", worker.GetPool().Stats().Running, "err", err)
+	}
+	if err := job.WaitCallbacks(ctx); err != nil {
+		slog.Error("wait job result callback timeout", "err", err)
+	}
+	if n := outbox.Flush(ctx); n > 0 {
+		slog.Warn("job results not reported, will retry after restart", "count", n)
+	}
+	slog.Info("node stopped")
 }
@@ -23,6 +23,9 @@ const (
 	tagModule
 	jobRecordModule
 	dashboardModule
+	webhookModule
+	failoverModule
+	reconcileModule
 )
 
 const (
@@ -69,11 +72,13 @@ var (
 )
 
 var (
-	JobNotExist     = genCodeMsg(jobModule, 0, "任务不存在")
-	JobAddFailed    = genCodeMsg(jobModule, 1, "任务创建失败")
-	JobUpdateFailed = genCodeMsg(jobModule, 2, "任务更新失败")
-	JobGetFailed    = genCodeMsg(jobModule, 3, "任务查询失败")
-	JobDeleteFailed = genCodeMsg(jobModule, 4, "任务删除失败")
+	JobNotExist      = genCodeMsg(jobModule, 0, "任务不存在")
+	JobAddFailed     = genCodeMsg(jobModule, 1, "任务创建失败")
+	JobUpdateFailed  = genCodeMsg(jobModule, 2, "任务更新失败")
+	JobGetFailed     = genCodeMsg(jobModule, 3, "任务查询失败")
+	JobDeleteFailed  = genCodeMsg(jobModule, 4, "任务删除失败")
+	JobCronPreview   = genCodeMsg(jobModule, 5, "表达式预览失败")
+	JobTriggerFailed = genCodeMsg(jobModule, 6, "任务触发失败")
 )
 
 var (
@@ -84,19 +89,46 @@ var (
 )
 
 var (
-	NodeNotExist         = genCodeMsg(nodeModule, 0, "节点不存在")
-	NodeAddFailed        = genCodeMsg(nodeModule, 1, "节点创建失败")
-	NodeUpdateFailed     = genCodeMsg(nodeModule, 2, "节点更新失败")
-	NodeGetFailed        = genCodeMsg(nodeModule, 3, "节点查询失败")
-	NodeDeleteFailed     = genCodeMsg(nodeModule, 4, "节点删除失败")
-	NodeInstallRefFailed = genCodeMsg(nodeModule, 5, "节点安装依赖失败")
-	NodeInfoFailed       = genCodeMsg(nodeModule, 6, "节点信息查询失败")
+	NodeNotExist          = genCodeMsg(nodeModule, 0, "节点不存在")
+	NodeAddFailed         = genCodeMsg(nodeModule, 1, "节点创建失败")
+	NodeUpdateFailed      = genCodeMsg(nodeModule, 2, "节点更新失败")
+	NodeGetFailed         = genCodeMsg(nodeModule, 3, "节点查询失败")
+	NodeDeleteFailed      = genCodeMsg(nodeModule, 4, "节点删除失败")
+	NodeInstallRefFailed  = genCodeMsg(nodeModule, 5, "节点安装依赖失败")
+	NodeInfoFailed        = genCodeMsg(nodeModule, 6, "节点信息查询失败")
+	NodeSchedulerFailed   = genCodeMsg(nodeModule, 7, "节点调度切换失败")
+	NodeMaintenanceFailed = genCodeMsg(nodeModule, 8, "节点维护状态切换失败")
+	NodeDrainFailed       = genCodeMsg(nodeModule, 9, "节点排空失败")
+	NodeRegisterFailed    = genCodeMsg(nodeModule, 10, "节点注册失败")
+	NodeHeartbeatFailed   = genCodeMsg(nodeModule, 11, "节点心跳上报失败")
+	NodeResourceFailed    = genCodeMsg(nodeModule, 12, "节点资源数据查询失败")
+	NodeJobsFailed        = genCodeMsg(nodeModule, 13, "节点任务查询失败")
+	NodeSecretFailed      = genCodeMsg(nodeModule, 14, "节点密钥查询失败")
+	NodeCertFailed        = genCodeMsg(nodeModule, 15, "节点证书签发失败")
+	NodeCertRevokeFailed  = genCodeMsg(nodeModule, 16, "节点证书吊销失败")
 )
 
 var (
 	DashboardChartFailed = genCodeMsg(dashboardModule, 0, "图表数据查询失败")
 )
 
+var (
+	WebhookNotExist      = genCodeMsg(webhookModule, 0, "webhook不存在")
+	WebhookAddFailed     = genCodeMsg(webhookModule, 1, "webhook创建失败")
+	WebhookGetFailed     = genCodeMsg(webhookModule, 2, "webhook查询失败")
+	WebhookRevokeFailed  = genCodeMsg(webhookModule, 3, "webhook撤销失败")
+	WebhookTriggerFailed = genCodeMsg(webhookModule, 4, "webhook触发失败")
+)
+
+var (
+	FailoverEventGetFailed = genCodeMsg(failoverModule, 0, "故障转移记录查询失败")
+)
+
+var (
+	ReconcileReportFailed = genCodeMsg(reconcileModule, 0, "对账结果查询失败")
+	ReconcileRunFailed    = genCodeMsg(reconcileModule, 1, "对账失败")
+)
+
 var msgMap = map[int]string{
 	CodeSuccess:       "success",
 	ServerError:       "server error",
@@ -6,9 +6,10 @@ type RespDashboardTotalData struct {
 }
 
 type NodeDashboard struct {
-	Total   int `json:"total"`
-	Online  int `json:"online"`
-	Offline int `json:"offline"`
+	Total       int `json:"total"`
+	Online      int `json:"online"`
+	Offline     int `json:"offline"`
+	Maintenance int `json:"maintenance"` // 在线但处于维护中，不计入 Online
 }
 
 type JobDashboard struct {
@@ -0,0 +1,9 @@
+package dto
+
+import "go-job/internal/model"
+
+// ReqFailoverEventList 查询任务的故障转移记录
+type ReqFailoverEventList struct {
+	model.Page
+	JobId int `json:"job_id" form:"job_id" binding:"required"`
+}
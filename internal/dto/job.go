@@ -1,7 +1,11 @@
 package dto
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"go-job/internal/model"
+	"go-job/internal/pkg/cronx"
 	"time"
 )
 
@@ -12,9 +16,26 @@ type ReqNodeJob struct {
 	Id       int                 `json:"id"`
 	Name     string              `json:"name" binding:"required"`       // 任务名称
 	ExecType model.ExecType      `json:"exec_type"  binding:"required"` // 任务类型
-	CronExpr string              `json:"cron_expr" binding:"required"`  // crontab 表达式
+	CronExpr string              `json:"cron_expr"`                     // crontab 表达式，定时调度时必填
 	Active   model.JobActiveType `json:"active" binding:"required"`
 	Filename string              `json:"filename"`
+	Misfire  model.JobMisfire    `json:"misfire"`  // 错过执行时间后的补偿策略
+	Jitter   model.JobJitter     `json:"jitter"`   // 执行前的随机延迟
+	Priority int                 `json:"priority"` // 执行池中的优先级
+
+	ScheduleType model.ScheduleType `json:"schedule_type"` // 调度方式，为空时等同于定时调度
+	FileWatch    model.JobFileWatch `json:"file_watch"`    // 文件触发的监听配置
+	Dispatched   bool               `json:"dispatched"`    // 由 master 调度，节点不创建定时调度，只执行下发的任务
+}
+
+// ReqNodeJobTrigger 立即触发一次节点上的job
+type ReqNodeJobTrigger struct {
+	TriggerType   model.TriggerType `json:"trigger_type" binding:"required"`
+	Stdin         []byte            `json:"stdin"`          // 传给脚本的标准输入
+	Env           map[string]string `json:"env"`            // 传给脚本的环境变量
+	RunId         int               `json:"run_id"`         // master 预先创建的执行记录id
+	ScheduledTime int64             `json:"scheduled_time"` // 计划执行时间，为0时使用节点收到请求的时间
+	NodeId        int               `json:"node_id"`        // 执行节点，节点执行结束后原样上报
 }
 
 type ReqId struct {
@@ -26,12 +47,52 @@ type ReqJobRecords struct {
 	JobId int `json:"job_id" form:"job_id"`
 }
 
+// RespNodeJob 节点上任务的定义和运行时状态
 type RespNodeJob struct {
-	Id            int             `json:"id"`
-	Name          string          `json:"name"`
-	ExecType      model.ExecType  `json:"exec_type"`
-	RunningStatus model.JobStatus `json:"running_status"`
-	FileName      string          `json:"filename"`
+	Id            int                  `json:"id"`
+	Name          string               `json:"name"`
+	ExecType      model.ExecType       `json:"exec_type"`
+	CronExpr      string               `json:"cron_expr"`
+	ScheduleType  model.ScheduleType   `json:"schedule_type"`
+	Dispatched    bool                 `json:"dispatched"`
+	Active        model.JobActiveType  `json:"active"`
+	RunningStatus model.JobStatus      `json:"running_status"`
+	NextExecTime  int64                `json:"next_exec_time"` // 节点调度器中的下一次执行时间，master 调度或未启用时为0
+	LastResult    *model.JobExecResult `json:"last_result"`    // 最近一次执行的结果，列表中不包含输出
+	Processes     []RespNodeJobProcess `json:"processes"`      // 正在执行的进程
+	FileName      string               `json:"filename"`
+	FileChecksum  string               `json:"file_checksum"` // 任务文件的 sha256，文件不存在时为空
+}
+
+// RespNodeJobProcess 正在执行的脚本进程
+type RespNodeJobProcess struct {
+	Pid       int     `json:"pid"`
+	StartTime int64   `json:"start_time"`
+	Elapsed   float64 `json:"elapsed"` // 已执行的秒数
+}
+
+// RespNodeJobInventory 节点上实际持有和正在执行的任务
+type RespNodeJobInventory struct {
+	NodeId   int           `json:"node_id"`
+	NodeName string        `json:"node_name"`
+	Running  int           `json:"running"` // 正在执行的进程数
+	Jobs     []RespNodeJob `json:"jobs"`
+}
+
+// Digest 任务定义的摘要，不包含启用状态，master 与节点比较摘要判断节点上的任务是否和数据库一致
+func (r ReqNodeJob) Digest() string {
+	r.Active = 0
+	data, _ := json.Marshal(r)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// RespNodeJobState 节点上任务的实际状态，master 定期对账时查询
+type RespNodeJobState struct {
+	Id           int                 `json:"id"`
+	Digest       string              `json:"digest"` // 节点收到的任务定义的摘要
+	Active       model.JobActiveType `json:"active"`
+	FileChecksum string              `json:"file_checksum"` // 任务文件的 sha256，文件不存在时为空
 }
 
 // ----------- Master DTO ----------- //
@@ -40,7 +101,7 @@ type ReqJob struct {
 	Id             int                  `json:"id"`
 	Name           string               `json:"name"`                         // 任务名称
 	ExecType       model.ExecType       `json:"exec_type" binding:"required"` // 任务类型
-	CronExpr       string               `json:"cron_expr" binding:"required"` // crontab 表达式
+	CronExpr       string               `json:"cron_expr"`                    // crontab 表达式，定时调度时必填
 	CreatedTime    time.Time            `json:"created_time"`
 	UpdatedTime    time.Time            `json:"updated_time"`
 	Active         model.JobActiveType  `json:"active"`
@@ -50,8 +111,30 @@ type ReqJob struct {
 	NotifyStrategy model.NotifyStrategy `json:"notify_strategy"` // 通知策略
 	NotifyMark     string               `json:"notify_mark" `    // 通知方式的具体内容，可能是邮箱地址，可能是外链。
 	UserId         int                  `json:"user_id"`
-	FileName       string               `json:"filename" ` // 文件名
-	FileKey        string               `json:"file_key"`  // 文件key
+	FileName       string               `json:"filename" `            // 文件名
+	FileKey        string               `json:"file_key"`             // 文件key
+	MisfirePolicy  model.MisfirePolicy  `json:"misfire_policy"`       // 错过执行时间后的补偿策略
+	MisfireMax     int                  `json:"misfire_max_count"`    // 补偿执行的最大次数
+	JitterMax      int                  `json:"jitter_max_seconds"`   // 执行前的最大随机延迟秒数
+	JitterHash     bool                 `json:"jitter_hash"`          // 是否使用固定的延迟
+	Priority       int                  `json:"priority"`             // 执行优先级，0~9，值越大越先执行
+	ScheduleType   model.ScheduleType   `json:"schedule_type"`        // 调度方式，1定时调度，2文件触发
+	WatchPath      string               `json:"watch_path"`           // 文件触发监听的路径，支持 glob
+	WatchStable    int                  `json:"watch_stable_seconds"` // 文件保持不变的秒数
+	WatchProcessed string               `json:"watch_processed_dir"`  // 执行后移动文件的目录
+	OnFailureJobId int                  `json:"on_failure_job_id"`    // 执行失败后触发的处理任务
+	OnSuccessJobId int                  `json:"on_success_job_id"`    // 执行成功后触发的处理任务
+
+	TargetGroup  string                `json:"target_group"`     // 节点选择器的分组，和标签都为空时固定在 node_id 上执行
+	TargetLabels map[string]string     `json:"target_labels"`    // 节点选择器的标签
+	Strategy     model.BalanceStrategy `json:"balance_strategy"` // 选择节点的策略，1轮询，2随机，3最少执行，4一致性哈希
+
+	TargetMode model.TargetMode `json:"target_mode"` // 执行方式，1选择一个节点，2广播，3分片
+	MinSuccess int              `json:"min_success"` // 广播和分片时至少成功的节点数，0表示全部成功
+
+	FailoverEnabled  bool `json:"failover_enabled"`       // 节点离线后是否转移到其他节点
+	FailoverGrace    int  `json:"failover_grace_seconds"` // 节点离线多少秒后转移
+	FailoverFailback bool `json:"failover_failback"`      // 原节点恢复后是否转回
 }
 
 type ReqJobList struct {
@@ -75,4 +158,44 @@ type RespJob struct {
 	NotifyStrategy model.NotifyStrategy `json:"notify_strategy"` // 通知策略
 	NotifyMark     string               `json:"notify_mark"`     // 通知方式的具体内容，可能是邮箱地址，可能是外链。
 	UserId         int                  `json:"user_id"`
+	MisfirePolicy  model.MisfirePolicy  `json:"misfire_policy"`
+	MisfireMax     int                  `json:"misfire_max_count"`
+	JitterMax      int                  `json:"jitter_max_seconds"`
+	JitterHash     bool                 `json:"jitter_hash"`
+	Priority       int                  `json:"priority"`
+	ScheduleType   model.ScheduleType   `json:"schedule_type"`
+	WatchPath      string               `json:"watch_path"`
+	WatchStable    int                  `json:"watch_stable_seconds"`
+	WatchProcessed string               `json:"watch_processed_dir"`
+	OnFailureJobId int                  `json:"on_failure_job_id"`
+	OnSuccessJobId int                  `json:"on_success_job_id"`
+	Dispatched     bool                 `json:"dispatched"` // 由 master 调度
+
+	TargetGroup  string                `json:"target_group"`
+	TargetLabels map[string]string     `json:"target_labels"`
+	Strategy     model.BalanceStrategy `json:"balance_strategy"`
+	TargetMode   model.TargetMode      `json:"target_mode"`
+	MinSuccess   int                   `json:"min_success"`
+
+	FailoverEnabled  bool `json:"failover_enabled"`
+	FailoverGrace    int  `json:"failover_grace_seconds"`
+	FailoverFailback bool `json:"failover_failback"`
+	FailoverOrigin   int  `json:"failover_origin_node_id"` // 转移前的节点，为0表示未转移
+
+	LastNextExecTime int64 `json:"last_next_exec_time,omitempty"` // 最近一次执行记录中的下一次执行时间，仅节点同步时返回
+}
+
+type ReqCronPreview struct {
+	CronExpr string `json:"cron_expr" form:"cron_expr" binding:"required"`
+	TimeZone string `json:"time_zone" form:"time_zone"` // 时区，如 Asia/Shanghai，为空时使用服务器时区
+	Count    int    `json:"count" form:"count"`         // 预览的执行次数，默认5次
+}
+
+type RespCronPreview struct {
+	CronExpr      string          `json:"cron_expr"`
+	TimeZone      string          `json:"time_zone"`
+	NextTimes     []time.Time     `json:"next_times"`
+	DescriptionZh string          `json:"description_zh"`
+	DescriptionEn string          `json:"description_en"`
+	Warnings      []cronx.Warning `json:"warnings"`
 }
@@ -7,3 +7,66 @@ type ReqNodeRef struct {
 	Type    model.NodeInstallRefType `json:"type"`
 	PkgName string                   `json:"pkg_name"`
 }
+
+// ReqNodeDrain 等待节点上正在执行的任务结束
+type ReqNodeDrain struct {
+	TimeoutSeconds int `json:"timeout_seconds"` // 最长等待秒数，为0时使用默认值
+}
+
+// ReqNodeRegister 节点启动时自注册，按名称匹配已有的节点
+type ReqNodeRegister struct {
+	Name         string            `json:"name" binding:"required"`
+	Hostname     string            `json:"hostname"`
+	Address      string            `json:"address" binding:"required"` // master 访问节点的地址
+	Version      string            `json:"version"`
+	Capabilities []string          `json:"capabilities"`
+	Group        string            `json:"group"`  // 为空时保留节点原有的分组
+	Labels       map[string]string `json:"labels"` // 为空时保留节点原有的标签
+}
+
+type RespNodeRegister struct {
+	NodeId            int    `json:"node_id"`
+	HeartbeatInterval int    `json:"heartbeat_interval"` // 上报心跳的间隔秒数
+	Secret            string `json:"secret"`             // master 请求节点时签名的密钥
+	TokenSecret       string `json:"token_secret"`       // 节点生成访问 master 的 token 的密钥
+}
+
+// RespNodeSecret 节点的两个密钥，重置时只返回重置的密钥
+type RespNodeSecret struct {
+	Secret      string `json:"secret,omitempty"`       // master 请求节点时签名的密钥
+	TokenSecret string `json:"token_secret,omitempty"` // 节点生成访问 master 的 token 的密钥
+}
+
+// ReqNodeCert 节点申请证书，证书的 CN 和域名由 master 按节点id和地址决定
+type ReqNodeCert struct {
+	Csr string `json:"csr" binding:"required"` // PEM 格式的证书请求
+}
+
+// RespNodeCert master 签发的证书和 CA 证书，都为 PEM 格式
+type RespNodeCert struct {
+	Cert       string `json:"cert"`
+	CA         string `json:"ca"`
+	ExpireTime int64  `json:"expire_time"`
+}
+
+// ReqNodeHeartbeat 节点定期上报心跳
+type ReqNodeHeartbeat struct {
+	NodeId   int                 `json:"node_id" binding:"required"`
+	Resource *model.NodeResource `json:"resource"` // 采集失败时为空
+}
+
+// ReqNodeResource 查询节点一段时间内的资源使用情况
+type ReqNodeResource struct {
+	Begin int64 `form:"begin"` // 为0时返回所有保留的数据
+	End   int64 `form:"end"`   // 为0时到当前时间
+}
+
+type RespNodeResource struct {
+	Node    model.Node           `json:"node"`
+	History []model.NodeResource `json:"history"`
+}
+
+type RespNodeDrain struct {
+	Drained bool  `json:"drained"` // 是否所有执行都已结束
+	Running int64 `json:"running"` // 超时后仍在执行和排队中的数量
+}
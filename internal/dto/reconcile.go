@@ -0,0 +1,47 @@
+package dto
+
+import "time"
+
+// ReconcileDiffType 节点上的任务与数据库不一致的类型
+type ReconcileDiffType string
+
+const (
+	ReconcileMissing    ReconcileDiffType = "missing"    // 节点上缺少任务
+	ReconcileExtra      ReconcileDiffType = "extra"      // 节点上有数据库中不存在或不应该在该节点上的任务
+	ReconcileDefinition ReconcileDiffType = "definition" // 任务定义不一致
+	ReconcileActive     ReconcileDiffType = "active"     // 启用状态不一致
+	ReconcileFile       ReconcileDiffType = "file"       // 任务文件不一致
+)
+
+// ReqReconcile 手动对账
+type ReqReconcile struct {
+	DryRun bool `json:"dry_run" form:"dry_run"` // 只对比不修复
+}
+
+// RespReconcileDiff 一个节点上一个任务的差异
+type RespReconcileDiff struct {
+	NodeId   int                 `json:"node_id"`
+	NodeName string              `json:"node_name"`
+	JobId    int                 `json:"job_id"`
+	JobName  string              `json:"job_name"`
+	Types    []ReconcileDiffType `json:"types"`
+	Repaired bool                `json:"repaired"` // 是否已修复
+	Error    string              `json:"error"`    // 修复失败的原因
+}
+
+// RespReconcileNodeError 无法对账的节点
+type RespReconcileNodeError struct {
+	NodeId   int    `json:"node_id"`
+	NodeName string `json:"node_name"`
+	Error    string `json:"error"`
+}
+
+// RespReconcileReport 一次对账的结果
+type RespReconcileReport struct {
+	StartTime  time.Time                `json:"start_time"`
+	EndTime    time.Time                `json:"end_time"`
+	DryRun     bool                     `json:"dry_run"` // 只对比不修复
+	NodeCount  int                      `json:"node_count"`
+	Diffs      []RespReconcileDiff      `json:"diffs"`
+	NodeErrors []RespReconcileNodeError `json:"node_errors"`
+}
@@ -0,0 +1,20 @@
+package dto
+
+import "go-job/internal/model"
+
+type ReqWebhook struct {
+	JobId       int                      `json:"job_id" binding:"required"`
+	PayloadMode model.WebhookPayloadMode `json:"payload_mode"` // 默认通过标准输入传递
+	WithSecret  bool                     `json:"with_secret"`  // 是否生成HMAC签名密钥
+	UserId      int                      `json:"-"`
+}
+
+type ReqWebhookList struct {
+	JobId int `form:"job_id" binding:"required"`
+}
+
+// RespWebhook 创建webhook后返回，secret 只在创建时返回一次
+type RespWebhook struct {
+	model.JobWebhook
+	Secret string `json:"secret,omitempty"`
+}
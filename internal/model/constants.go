@@ -1,5 +1,7 @@
 package model
 
+import "strconv"
+
 /*
 todo 后续需要把这些常量定义迁移到具体的model文件中
 */
@@ -12,6 +14,19 @@ const (
 	ExecTypeFile
 )
 
+func (t ExecType) String() string {
+	switch t {
+	case ExecTypeShell:
+		return "shell"
+	case ExecTypeHttp:
+		return "http"
+	case ExecTypeFile:
+		return "file"
+	default:
+		return strconv.Itoa(int(t))
+	}
+}
+
 type NotifyStrategy uint8
 
 const (
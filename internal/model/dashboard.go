@@ -5,6 +5,7 @@ type DashboardKey string
 const (
 	DashboardKeyDayStatus DashboardKey = "day"
 	DashboardKeyJobStatus DashboardKey = "job"
+	DashboardKeyNodeLoad  DashboardKey = "node_load" // 各节点最近上报的负载
 )
 
 type ReqDashboardChart struct {
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// FailoverEventType 故障转移事件类型
+type FailoverEventType uint8
+
+const (
+	FailoverMoved    FailoverEventType = iota + 1 // 节点离线，任务转移到其他节点
+	FailoverDisabled                              // 原节点恢复，停用原节点上的任务
+	FailoverBack                                  // 原节点恢复，任务转回原节点
+	FailoverFailed                                // 转移失败，如没有可用的节点
+)
+
+// JobFailoverEvent 任务故障转移和转回的记录
+type JobFailoverEvent struct {
+	Id          int               `json:"id" gorm:"primary_key"`
+	JobId       int               `json:"job_id" gorm:"column:job_id"`
+	Type        FailoverEventType `json:"type" gorm:"column:type"`
+	FromNodeId  int               `json:"from_node_id" gorm:"column:from_node_id"`
+	ToNodeId    int               `json:"to_node_id" gorm:"column:to_node_id"` // 停用原节点和转移失败时为0
+	Message     string            `json:"message" gorm:"column:message"`
+	CreatedTime time.Time         `json:"created_time" gorm:"column:created_time;autoCreateTime"`
+}
+
+func (JobFailoverEvent) TableName() string {
+	return "job_failover_event"
+}
@@ -13,8 +13,13 @@ const (
 	Running
 	Success
 	Failed
+	Dropped // 排队超时等原因未执行
+	Skipped // 节点维护中等原因跳过执行
 )
 
+// JobStatusNum 执行状态的数量，用于按状态统计
+const JobStatusNum = int(Skipped) + 1
+
 func (s JobStatus) String() string {
 	switch s {
 	case Pending:
@@ -25,6 +30,10 @@ func (s JobStatus) String() string {
 		return "成功"
 	case Failed:
 		return "失败"
+	case Dropped:
+		return "已丢弃"
+	case Skipped:
+		return "已跳过"
 	default:
 		return strconv.Itoa(int(s))
 	}
@@ -37,11 +46,47 @@ const (
 	JobStop
 )
 
+// MisfirePolicy 错过执行时间(如节点宕机)后的补偿策略
+type MisfirePolicy uint8
+
+const (
+	MisfireIgnore MisfirePolicy = iota + 1 // 忽略错过的执行
+	MisfireOnce                            // 只补执行一次
+	MisfireAll                             // 补执行所有错过的，最多 MaxCount 次
+)
+
+// ScheduleType 任务的调度方式
+type ScheduleType uint8
+
+const (
+	ScheduleCron ScheduleType = iota + 1 // crontab 定时调度
+	ScheduleFile                         // 监听到文件后触发
+)
+
+// BalanceStrategy 通过节点选择器执行时，从匹配的节点中选择执行节点的策略
+type BalanceStrategy uint8
+
+const (
+	BalanceRoundRobin   BalanceStrategy = iota + 1 // 轮询
+	BalanceRandom                                  // 随机
+	BalanceLeastRunning                            // 正在执行数最少
+	BalanceHash                                    // 按任务id一致性哈希，节点不变时总是同一个节点
+)
+
+// TargetMode 节点选择器的执行方式
+type TargetMode uint8
+
+const (
+	TargetOne       TargetMode = iota + 1 // 按策略选择一个节点执行
+	TargetBroadcast                       // 在所有匹配的节点上执行
+	TargetShard                           // 在所有匹配的节点上分片执行，节点通过 SHARD_INDEX/SHARD_TOTAL 获取分片
+)
+
 type Job struct {
 	Id           int           `json:"id" gorm:"primary_key"`
 	Name         string        `json:"name" binding:"required"`                              // 任务名称
 	ExecType     ExecType      `json:"exec_type" gorm:"column:exec_type" binding:"required"` // 任务类型
-	CronExpr     string        `json:"cron_expr" gorm:"column:cron_expr" binding:"required"` // crontab 表达式
+	CronExpr     string        `json:"cron_expr" gorm:"column:cron_expr"`                    // crontab 表达式
 	CreatedTime  time.Time     `json:"created_time" gorm:"column:created_time;autoCreateTime"`
 	UpdatedTime  time.Time     `json:"updated_time" gorm:"column:updated_time;autoUpdateTime"`
 	Active       JobActiveType `json:"active" gorm:"column:active;default:1"`
@@ -60,6 +105,84 @@ func (Job) TableName() string {
 type JobInternal struct {
 	FileMeta upload.FileMeta `json:"file_meta"`
 	Notify   JobNotify       `json:"notify"`
+	Misfire  JobMisfire      `json:"misfire"`
+	Jitter   JobJitter       `json:"jitter"`
+	Priority int             `json:"priority"` // 节点执行池中的优先级，0~9，值越大越先执行
+
+	ScheduleType ScheduleType `json:"schedule_type"` // 调度方式，为空时等同于定时调度
+	FileWatch    JobFileWatch `json:"file_watch"`    // 文件触发的监听配置
+	Handler      JobHandler   `json:"handler"`       // 执行结束后触发的处理任务
+	Target       JobTarget    `json:"target"`        // 节点选择器，设置后不再固定在 NodeID 上执行
+	Failover     JobFailover  `json:"failover"`      // 节点离线后的故障转移
+}
+
+// JobFailover 节点离线超过宽限时间后，master 将任务文件和配置发送到其他可用节点，NodeID 改为新节点
+// 原节点恢复后停用其上的任务，避免同一个任务在两个节点上执行
+type JobFailover struct {
+	Enabled      bool  `json:"enabled"`
+	GraceSeconds int   `json:"grace_seconds"`  // 节点离线多少秒后转移
+	Failback     bool  `json:"failback"`       // 原节点恢复后转回原节点，否则继续在新节点上执行
+	OriginNodeId int   `json:"origin_node_id"` // 第一次转移前的节点，转回或停用后清空
+	StaleNodeIds []int `json:"stale_node_ids"` // 转移前的节点上仍有该任务，恢复后需要停用
+}
+
+// JobTarget 节点选择器，每次触发由 master 从匹配的健康节点中按策略选择一个节点执行
+// 广播和分片时在所有匹配的健康节点上执行，每个节点一条子记录，全部结束后汇总到父记录
+type JobTarget struct {
+	Group    string            `json:"group"`    // 节点分组，为空时不限制
+	Labels   map[string]string `json:"labels"`   // 节点需要包含所有标签
+	Strategy BalanceStrategy   `json:"strategy"` // 选择节点的策略
+
+	Mode       TargetMode `json:"mode"`        // 执行方式，为空时等同于选择一个节点
+	MinSuccess int        `json:"min_success"` // 广播和分片时至少成功的节点数，0表示全部成功
+}
+
+// MultiNode 是否在多个节点上执行
+func (t JobTarget) MultiNode() bool {
+	return t.Mode == TargetBroadcast || t.Mode == TargetShard
+}
+
+// Enabled 是否设置了节点选择器
+func (t JobTarget) Enabled() bool {
+	return t.Group != "" || len(t.Labels) > 0
+}
+
+// Match 节点是否匹配选择器
+func (t JobTarget) Match(node Node) bool {
+	if t.Group != "" && t.Group != node.Group {
+		return false
+	}
+	for k, v := range t.Labels {
+		if val, ok := node.Labels[k]; !ok || val != v {
+			return false
+		}
+	}
+	return true
+}
+
+// JobHandler 执行失败或成功后由 master 触发的处理任务，如清理、回滚脚本，0表示不触发
+// 处理任务可以是停用状态的任务，由处理任务触发的执行不会再触发其他处理任务
+type JobHandler struct {
+	OnFailure int `json:"on_failure"` // 执行失败(包括被丢弃)后触发的任务id
+	OnSuccess int `json:"on_success"` // 执行成功后触发的任务id
+}
+
+type JobMisfire struct {
+	Policy   MisfirePolicy `json:"policy"`    // 补偿策略，为空时等同于忽略
+	MaxCount int           `json:"max_count"` // MisfireAll 策略下最多补执行的次数
+}
+
+// JobJitter 执行前的随机延迟，用于错开同一时间触发的任务
+type JobJitter struct {
+	MaxSeconds int  `json:"max_seconds"` // 最大延迟秒数，0表示不延迟
+	Hash       bool `json:"hash"`        // 根据任务id计算固定的延迟，类似 Jenkins 的 H
+}
+
+// JobFileWatch 文件触发，匹配的文件出现并且稳定一段时间后执行，文件路径通过环境变量 GO_JOB_FILE 传给脚本
+type JobFileWatch struct {
+	Path          string `json:"path"`           // 监听的文件路径，支持 glob，如 /data/in/*.csv
+	StableSeconds int    `json:"stable_seconds"` // 文件大小和修改时间保持不变的秒数
+	ProcessedDir  string `json:"processed_dir"`  // 执行后将文件移动到该目录，为空时不移动
 }
 
 type JobNotify struct {
@@ -2,6 +2,17 @@ package model
 
 import "time"
 
+// TriggerType 任务的触发方式
+type TriggerType uint8
+
+const (
+	TriggerCron    TriggerType = iota + 1 // crontab 定时触发
+	TriggerMisfire                        // 节点恢复后补偿触发
+	TriggerWebhook                        // 外部系统通过webhook触发
+	TriggerFile                           // 监听到文件后触发
+	TriggerHandler                        // 作为其他任务的失败/成功处理任务触发
+)
+
 type JobExecResult struct {
 	StartTime int64     `json:"start_time"`
 	EndTime   int64     `json:"end_time"`
@@ -9,6 +20,13 @@ type JobExecResult struct {
 	Status    JobStatus `json:"status"`
 	Output    string    `json:"output"`
 	Error     string    `json:"error"`
+
+	TriggerType   TriggerType `json:"trigger_type"`
+	ScheduledTime int64       `json:"scheduled_time"` // 计划执行时间，补偿执行时为错过的时间点
+	JitterDelay   float64     `json:"jitter_delay"`   // 执行前的随机延迟，单位秒
+	RunId         int         `json:"run_id"`         // master 调度时预先创建的执行记录id，节点调度时为0
+	NodeId        int         `json:"node_id"`        // master 触发时选择的执行节点，节点调度时为0
+	ExecutionId   string      `json:"execution_id"`   // 节点生成的执行id，重试上报时 master 按执行id去重
 }
 
 type CallbackJobResult struct {
@@ -27,6 +45,13 @@ type JobRecord struct {
 	Status       JobStatus `json:"status"`
 	Output       string    `json:"output"`
 	Error        string    `json:"error"`
+
+	TriggerType   TriggerType `json:"trigger_type"`
+	ScheduledTime time.Time   `json:"scheduled_time"`
+	JitterDelay   float64     `json:"jitter_delay"`
+	NodeId        int         `json:"node_id"`                          // 执行节点，为0时是任务固定的节点
+	ParentId      int         `json:"parent_id"`                        // 广播和分片执行时的父记录，为0表示没有父记录
+	ExecutionId   string      `json:"execution_id" gorm:"default:null"` // 节点上报的执行id，master 创建的记录和旧版本节点为空
 }
 
 type JobLastRecord struct {
@@ -49,6 +74,17 @@ type JobRecordSummary struct {
 	NextExecTime time.Time `json:"next_exec_time"`
 	Duration     float64   `json:"duration"`
 	Status       JobStatus `json:"status"`
+
+	TriggerType   TriggerType `json:"trigger_type"`
+	ScheduledTime time.Time   `json:"scheduled_time"`
+	JitterDelay   float64     `json:"jitter_delay"`
+	NodeId        int         `json:"node_id"`
+	ParentId      int         `json:"parent_id"`
+}
+
+type JobLastNextExecTime struct {
+	JobId        int       `json:"job_id" gorm:"column:job_id"`
+	NextExecTime time.Time `json:"next_exec_time" gorm:"column:next_exec_time"`
 }
 
 type JobRecordDayStatusCount struct {
@@ -1,6 +1,9 @@
 package model
 
-import "time"
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"time"
+)
 
 type NodeInstallRefType string
 
@@ -15,8 +18,68 @@ type Node struct {
 	Address     string    `json:"address" binding:"required"`
 	CreatedTime time.Time `json:"created_at" gorm:"column:created_time;autoCreateTime"`
 	UpdatedTime time.Time `json:"updated_at" gorm:"column:updated_time;autoUpdateTime"`
+	Maintenance bool      `json:"maintenance" gorm:"column:maintenance"` // 维护中，节点上的触发都跳过执行
+	Secret      string    `json:"-" gorm:"column:secret"`                // master 请求节点时签名的密钥
+	TokenSecret string    `json:"-" gorm:"column:token_secret"`          // 节点生成访问 master 的 token 的密钥
+	UserId      int       `json:"user_id" gorm:"column:user_id"`         // 添加节点的用户，自注册的节点为0
 	Online      bool      `json:"online" gorm:"-"`
 	CheckTime   time.Time `json:"check_time" gorm:"-"`
+	OfflineTime time.Time `json:"offline_time" gorm:"-"` // 检测到离线的时间，在线时为零值
+
+	Group  string            `json:"group" gorm:"column:group_name"`              // 节点分组
+	Labels map[string]string `json:"labels" gorm:"serializer:json;column:labels"` // 节点标签，如 env=prod，python=3.11
+
+	// 节点自注册时上报的信息，自注册的节点通过心跳判断是否在线
+	Hostname      string    `json:"hostname" gorm:"column:hostname"`
+	Version       string    `json:"version" gorm:"column:version"`
+	Capabilities  []string  `json:"capabilities" gorm:"serializer:json;column:capabilities"` // 支持的执行类型，如 file，python
+	Heartbeat     bool      `json:"heartbeat" gorm:"column:heartbeat"`
+	HeartbeatTime time.Time `json:"heartbeat_time" gorm:"-"` // 最近一次收到心跳的时间
+
+	// 开启 TLS 时 master 给节点签发的当前证书，其他序列号的证书都视为已吊销
+	CertSerial     string `json:"cert_serial" gorm:"column:cert_serial"`
+	CertExpireTime int64  `json:"cert_expire_time" gorm:"column:cert_expire_time"` // 证书过期时间戳
+
+	Resource *NodeResource `json:"resource,omitempty" gorm:"-"` // 最近一次上报的资源使用情况
+	Tunnel   bool          `json:"tunnel" gorm:"-"`             // 是否通过节点建立的反向连接访问节点
+}
+
+// NodeClaims 节点使用 master 下发的 token 密钥生成的 token，只能访问节点自己的数据
+type NodeClaims struct {
+	jwt.RegisteredClaims
+	NodeId int `json:"node_id"`
+}
+
+// NodeResource 节点随心跳上报的资源使用情况，内存和磁盘单位为字节
+type NodeResource struct {
+	Time       int64   `json:"time"`        // 采集时间戳
+	CPUPercent float64 `json:"cpu_percent"` // 与上一次采集之间的CPU使用率
+	Load1      float64 `json:"load1"`
+	Load5      float64 `json:"load5"`
+	Load15     float64 `json:"load15"`
+	MemTotal   uint64  `json:"mem_total"`
+	MemUsed    uint64  `json:"mem_used"`
+	DiskTotal  uint64  `json:"disk_total"` // upload_job_dir 所在的磁盘
+	DiskUsed   uint64  `json:"disk_used"`
+	Running    int     `json:"running"` // 正在执行的数量
+	Queued     int     `json:"queued"`  // 排队中的数量
+}
+
+// MemPercent 内存使用率
+func (r NodeResource) MemPercent() float64 {
+	return percent(r.MemUsed, r.MemTotal)
+}
+
+// DiskPercent 磁盘使用率
+func (r NodeResource) DiskPercent() float64 {
+	return percent(r.DiskUsed, r.DiskTotal)
+}
+
+func percent(used, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(used) * 100 / float64(total)
 }
 
 func (Node) TableName() string {
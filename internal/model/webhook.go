@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// WebhookPayloadMode webhook 请求体传给脚本的方式
+type WebhookPayloadMode uint8
+
+const (
+	WebhookPayloadStdin WebhookPayloadMode = iota + 1 // 通过标准输入传递
+	WebhookPayloadEnv                                 // 通过环境变量 GO_JOB_PAYLOAD 传递
+)
+
+type WebhookStatus uint8
+
+const (
+	WebhookEnabled WebhookStatus = iota + 1
+	WebhookRevoked
+)
+
+type JobWebhook struct {
+	Id          int                `json:"id" gorm:"primary_key"`
+	JobId       int                `json:"job_id" gorm:"column:job_id"`
+	UserId      int                `json:"user_id" gorm:"column:user_id"`
+	Token       string             `json:"token" gorm:"column:token"`               // 触发地址中的token
+	Secret      string             `json:"-" gorm:"column:secret"`                  // HMAC签名密钥，为空时不校验签名
+	PayloadMode WebhookPayloadMode `json:"payload_mode" gorm:"column:payload_mode"` // 请求体传给脚本的方式
+	Status      WebhookStatus      `json:"status" gorm:"column:status;default:1"`
+	CreatedTime time.Time          `json:"created_time" gorm:"column:created_time;autoCreateTime"`
+	UpdatedTime time.Time          `json:"updated_time" gorm:"column:updated_time;autoUpdateTime"`
+}
+
+func (JobWebhook) TableName() string {
+	return "job_webhook"
+}
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/google/uuid"
+	"go-job/internal/pkg/consts"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultSignWindow 签名时间与当前时间相差超过该时间时拒绝，nonce 也只在该时间内记录
+const DefaultSignWindow = 5 * time.Minute
+
+var (
+	ErrSignMissing = errors.New("请求缺少签名")
+	ErrSignExpired = errors.New("签名已过期")
+	ErrSignInvalid = errors.New("签名校验失败")
+	ErrSignReplay  = errors.New("重复的请求")
+)
+
+// NewSecret 生成随机的签名密钥
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Sign 对请求方法、path 和 query、时间戳、nonce、请求体的 sha256 计算 HMAC-SHA256
+func Sign(secret, method, uri string, timestamp int64, nonce string, body []byte) string {
+	bodySum := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + uri + "\n" + strconv.FormatInt(timestamp, 10) + "\n" +
+		nonce + "\n" + hex.EncodeToString(bodySum[:])))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignRequest 给请求添加签名请求头，会读取请求体并重新设置
+func SignRequest(req *http.Request, secret string) error {
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now().Unix()
+	nonce := uuid.NewString()
+	req.Header.Set(consts.SignTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(consts.SignNonceHeader, nonce)
+	req.Header.Set(consts.SignatureHeader, Sign(secret, req.Method, req.URL.RequestURI(), timestamp, nonce, body))
+	return nil
+}
+
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, nil
+}
+
+// SignTransport 按请求的目标地址获取密钥给请求签名，没有密钥时不签名
+type SignTransport struct {
+	Base   http.RoundTripper
+	Secret func(address string) string
+}
+
+func (t *SignTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	secret := t.Secret(req.URL.Host)
+	if secret == "" {
+		return base.RoundTrip(req)
+	}
+	// RoundTripper 不能修改原请求
+	req = req.Clone(req.Context())
+	if err := SignRequest(req, secret); err != nil {
+		return nil, err
+	}
+	return base.RoundTrip(req)
+}
+
+// Verifier 校验请求签名，有效时间内同一个 nonce 只能使用一次
+type Verifier struct {
+	window time.Duration
+	now    func() time.Time
+
+	mux    sync.Mutex
+	nonces map[string]time.Time
+}
+
+func NewVerifier(window time.Duration) *Verifier {
+	if window <= 0 {
+		window = DefaultSignWindow
+	}
+	return &Verifier{
+		window: window,
+		now:    time.Now,
+		nonces: make(map[string]time.Time),
+	}
+}
+
+// Verify 校验请求头中的签名，body 为已经读取的请求体
+func (v *Verifier) Verify(secret string, req *http.Request, body []byte) error {
+	tsStr := req.Header.Get(consts.SignTimestampHeader)
+	nonce := req.Header.Get(consts.SignNonceHeader)
+	signature := req.Header.Get(consts.SignatureHeader)
+	if tsStr == "" || nonce == "" || signature == "" {
+		return ErrSignMissing
+	}
+	timestamp, err := strconv.ParseInt(tsStr, 10, 64)
+	if err != nil {
+		return ErrSignInvalid
+	}
+	now := v.now()
+	signTime := time.Unix(timestamp, 0)
+	if signTime.Before(now.Add(-v.window)) || signTime.After(now.Add(v.window)) {
+		return ErrSignExpired
+	}
+	want := Sign(secret, req.Method, req.URL.RequestURI(), timestamp, nonce, body)
+	if !hmac.Equal([]byte(want), []byte(signature)) {
+		return ErrSignInvalid
+	}
+
+	v.mux.Lock()
+	defer v.mux.Unlock()
+	for n, expire := range v.nonces {
+		if now.After(expire) {
+			delete(v.nonces, n)
+		}
+	}
+	if _, ok := v.nonces[nonce]; ok {
+		return ErrSignReplay
+	}
+	// 超过有效时间后时间戳校验会失败，不需要再记录
+	v.nonces[nonce] = signTime.Add(v.window)
+	return nil
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-job/internal/pkg/consts"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifier(t *testing.T) {
+	const secret = "secret"
+	now := time.Now()
+	testCases := []struct {
+		name   string
+		modify func(req *http.Request) []byte // 修改签名后的请求，返回节点收到的请求体
+		secret string
+		want   error
+	}{
+		{
+			name:   "valid",
+			secret: secret,
+		}, {
+			name:   "wrong secret",
+			secret: "other",
+			want:   ErrSignInvalid,
+		}, {
+			name:   "body changed",
+			secret: secret,
+			modify: func(req *http.Request) []byte { return []byte(`{"pkg_name":"evil"}`) },
+			want:   ErrSignInvalid,
+		}, {
+			name:   "path changed",
+			secret: secret,
+			modify: func(req *http.Request) []byte {
+				req.URL.Path = "/api/go-job/node/jobs/2"
+				return nil
+			},
+			want: ErrSignInvalid,
+		}, {
+			name:   "expired",
+			secret: secret,
+			modify: func(req *http.Request) []byte {
+				ts := now.Add(-DefaultSignWindow - time.Minute).Unix()
+				req.Header.Set(consts.SignTimestampHeader, strconv.FormatInt(ts, 10))
+				return nil
+			},
+			want: ErrSignExpired,
+		}, {
+			name:   "missing signature",
+			secret: secret,
+			modify: func(req *http.Request) []byte {
+				req.Header.Del(consts.SignatureHeader)
+				return nil
+			},
+			want: ErrSignMissing,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body := `{"type":"py","pkg_name":"requests"}`
+			req := httptest.NewRequest(http.MethodPost, "http://node:8081/api/go-job/node/install_ref?id=1",
+				strings.NewReader(body))
+			require.NoError(t, SignRequest(req, secret))
+			received, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			require.Equal(t, body, string(received))
+			if tc.modify != nil {
+				if b := tc.modify(req); b != nil {
+					received = b
+				}
+			}
+
+			v := NewVerifier(0)
+			v.now = func() time.Time { return now }
+			assert.ErrorIs(t, v.Verify(tc.secret, req, received), tc.want)
+		})
+	}
+}
+
+func TestVerifierReplay(t *testing.T) {
+	v := NewVerifier(time.Minute)
+	req := httptest.NewRequest(http.MethodGet, "http://node:8081/api/go-job/node/info", nil)
+	require.NoError(t, SignRequest(req, "secret"))
+
+	require.NoError(t, v.Verify("secret", req, nil))
+	assert.ErrorIs(t, v.Verify("secret", req, nil), ErrSignReplay)
+
+	// 超过有效时间后时间戳校验失败
+	v.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	assert.ErrorIs(t, v.Verify("secret", req, nil), ErrSignExpired)
+}
+
+func TestSignTransport(t *testing.T) {
+	var got *http.Request
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	client := &http.Client{Transport: &SignTransport{Secret: func(address string) string {
+		if address == host {
+			return "secret"
+		}
+		return ""
+	}}}
+	resp, err := client.Post(srv.URL+"/api/go-job/node/jobs/add", "application/json", strings.NewReader(`{"id":1}`))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	require.NotNil(t, got)
+	assert.NoError(t, NewVerifier(0).Verify("secret", got, gotBody))
+}
@@ -6,3 +6,16 @@ const (
 	DefaultLoginJwtExpireTime     = time.Hour * 2
 	DefaultAuthStateJwtExpireTime = time.Minute * 10
 )
+
+// BootstrapTokenHeader 节点自注册时携带 bootstrap token 的请求头
+const BootstrapTokenHeader = "X-Bootstrap-Token"
+
+// NodeAddressHeader 节点建立反向连接时携带 master 访问节点的地址
+const NodeAddressHeader = "X-Node-Address"
+
+// master 请求节点时的签名请求头，签名内容见 auth.Sign
+const (
+	SignTimestampHeader = "X-Go-Job-Timestamp"
+	SignNonceHeader     = "X-Go-Job-Nonce"
+	SignatureHeader     = "X-Go-Job-Signature"
+)
@@ -0,0 +1,80 @@
+package cronx
+
+import (
+	"github.com/robfig/cron/v3"
+	"strings"
+	"time"
+)
+
+// parser 与节点调度器保持一致，支持秒级表达式
+var parser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Parse 解析crontab表达式
+func Parse(expr string) (cron.Schedule, error) {
+	return parser.Parse(expr)
+}
+
+// MissedTimes 计算 [from, to] 区间内的所有触发时间，只保留最近的 limit 个，按时间先后返回
+// from 需要是一个有效的触发时间，如上一次记录的下一次执行时间
+func MissedTimes(schedule cron.Schedule, from, to time.Time, limit int) []time.Time {
+	if limit <= 0 || from.IsZero() || from.After(to) {
+		return nil
+	}
+	times := make([]time.Time, 0, limit)
+	for t := from; !t.IsZero() && !t.After(to); t = schedule.Next(t) {
+		if len(times) == limit {
+			times = append(times[1:], t)
+			continue
+		}
+		times = append(times, t)
+	}
+	return times
+}
+
+// NextTimes 计算 from 之后的 n 个触发时间
+func NextTimes(schedule cron.Schedule, from time.Time, n int) []time.Time {
+	times := make([]time.Time, 0, n)
+	for t := schedule.Next(from); !t.IsZero() && len(times) < n; t = schedule.Next(t) {
+		times = append(times, t)
+	}
+	return times
+}
+
+// Warning 表达式可能存在的问题
+type Warning struct {
+	Code string `json:"code"`
+	Zh   string `json:"zh"`
+	En   string `json:"en"`
+}
+
+var (
+	WarnEverySecond = Warning{Code: "every_second", Zh: "每秒都会执行", En: "fires every second"}
+	WarnNeverFires  = Warning{Code: "never_fires", Zh: "未来一年内不会执行", En: "never fires in the next year"}
+	WarnDomAndDow   = Warning{Code: "dom_and_dow", Zh: "同时限制了日期和星期，满足任意一个就会执行",
+		En: "both day-of-month and day-of-week are restricted, the job fires when either matches"}
+)
+
+// Check 检查表达式中容易出错的地方
+func Check(expr string, schedule cron.Schedule, now time.Time) []Warning {
+	var warnings []Warning
+	next := schedule.Next(now)
+	if next.IsZero() || next.After(now.AddDate(1, 0, 0)) {
+		warnings = append(warnings, WarnNeverFires)
+	} else if schedule.Next(next).Sub(next) <= time.Second {
+		warnings = append(warnings, WarnEverySecond)
+	}
+
+	expr = stripTimeZone(expr)
+	if spec, ok := descriptors[expr]; ok {
+		expr = spec
+	}
+	if fields := strings.Fields(expr); len(fields) == 6 &&
+		!isUnrestricted(fields[fieldDom]) && !isUnrestricted(fields[fieldDow]) {
+		warnings = append(warnings, WarnDomAndDow)
+	}
+	return warnings
+}
+
+func isUnrestricted(field string) bool {
+	return field == "*" || field == "?"
+}
@@ -0,0 +1,141 @@
+package cronx
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestMissedTimes(t *testing.T) {
+	base := time.Date(2025, 7, 1, 10, 0, 0, 0, time.Local)
+	testCases := []struct {
+		name  string
+		expr  string
+		from  time.Time
+		to    time.Time
+		limit int
+		want  []time.Time
+	}{
+		{
+			name:  "nothing missed",
+			expr:  "0 */5 * * * *",
+			from:  base.Add(5 * time.Minute),
+			to:    base,
+			limit: 10,
+			want:  nil,
+		}, {
+			name:  "zero from",
+			expr:  "0 */5 * * * *",
+			to:    base,
+			limit: 10,
+			want:  nil,
+		}, {
+			name:  "run once keeps the latest",
+			expr:  "0 */5 * * * *",
+			from:  base,
+			to:    base.Add(17 * time.Minute),
+			limit: 1,
+			want:  []time.Time{base.Add(15 * time.Minute)},
+		}, {
+			name:  "run all within limit",
+			expr:  "0 */5 * * * *",
+			from:  base,
+			to:    base.Add(12 * time.Minute),
+			limit: 10,
+			want:  []time.Time{base, base.Add(5 * time.Minute), base.Add(10 * time.Minute)},
+		}, {
+			name:  "run all over limit",
+			expr:  "0 */5 * * * *",
+			from:  base,
+			to:    base.Add(30 * time.Minute),
+			limit: 2,
+			want:  []time.Time{base.Add(25 * time.Minute), base.Add(30 * time.Minute)},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			schedule, err := Parse(tc.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, MissedTimes(schedule, tc.from, tc.to, tc.limit))
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	testCases := []struct {
+		expr string
+		zh   string
+		en   string
+	}{
+		{expr: "* * * * * *", zh: "每秒", en: "Every second"},
+		{expr: "*/10 * * * * *", zh: "每10秒", en: "Every 10 seconds"},
+		{expr: "0 * * * * *", zh: "每分钟", en: "Every minute"},
+		{expr: "0 */5 * * * *", zh: "每5分钟", en: "Every 5 minutes"},
+		{expr: "30 */5 * * * *", zh: "每5分钟，第30秒", en: "At second 30, every 5 minutes"},
+		{expr: "0 0 9 * * *", zh: "每天 09:00:00", en: "At 09:00:00"},
+		{expr: "0 30 9 * * MON-FRI", zh: "星期一到星期五 09:30:00", en: "At 09:30:00, Monday through Friday"},
+		{expr: "0 0 0 1,15 * *", zh: "每月1、15号 00:00:00", en: "At 00:00:00, on days 1 and 15 of the month"},
+		{expr: "0 0 9-17/2 * * *", zh: "9点到17点内每2小时", en: "Every 2 hours, hours 9 through 17"},
+		{expr: "0 0,30 8 * 1 *", zh: "1月，8点，第0、30分钟", en: "At minutes 0 and 30, at hour 8, in January"},
+		{expr: "@daily", zh: "每天 00:00:00", en: "At 00:00:00"},
+		{expr: "@every 1h30m", zh: "每1h30m0s", en: "Every 1h30m0s"},
+		{expr: "CRON_TZ=Asia/Shanghai 0 0 8 * * *", zh: "每天 08:00:00", en: "At 08:00:00"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			_, err := Parse(tc.expr)
+			require.NoError(t, err)
+			zh, err := Describe(tc.expr, LangZh)
+			require.NoError(t, err)
+			assert.Equal(t, tc.zh, zh)
+			en, err := Describe(tc.expr, LangEn)
+			require.NoError(t, err)
+			assert.Equal(t, tc.en, en)
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2025, 7, 1, 10, 0, 0, 0, time.Local)
+	testCases := []struct {
+		expr string
+		want []Warning
+	}{
+		{expr: "0 */5 * * * *", want: nil},
+		{expr: "* * * * * *", want: []Warning{WarnEverySecond}},
+		{expr: "0 0 0 30 2 *", want: []Warning{WarnNeverFires}},
+		{expr: "0 0 0 1 * MON", want: []Warning{WarnDomAndDow}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			schedule, err := Parse(tc.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, Check(tc.expr, schedule, now))
+		})
+	}
+}
+
+func TestJitterDelay(t *testing.T) {
+	testCases := []struct {
+		name string
+		key  int
+		max  time.Duration
+		hash bool
+	}{
+		{name: "disabled", key: 1, max: 0},
+		{name: "random", key: 1, max: 30 * time.Second},
+		{name: "hash", key: 1, max: 30 * time.Second, hash: true},
+		{name: "hash other key", key: 2, max: 30 * time.Second, hash: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			delay := JitterDelay(tc.key, tc.max, tc.hash)
+			assert.GreaterOrEqual(t, delay, time.Duration(0))
+			assert.LessOrEqual(t, delay, tc.max)
+			if tc.hash {
+				assert.Equal(t, delay, JitterDelay(tc.key, tc.max, tc.hash))
+			}
+		})
+	}
+}
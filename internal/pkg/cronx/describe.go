@@ -0,0 +1,298 @@
+package cronx
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Lang string
+
+const (
+	LangZh Lang = "zh"
+	LangEn Lang = "en"
+)
+
+var ErrFieldCount = errors.New("表达式需要6个字段：秒 分 时 日 月 星期")
+
+// descriptors 预定义表达式对应的6字段表达式
+var descriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+const (
+	fieldSecond = iota
+	fieldMinute
+	fieldHour
+	fieldDom
+	fieldMonth
+	fieldDow
+)
+
+var (
+	monthNames = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+	dowNames = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+)
+
+// unitText 单个字段在某种语言下的描述模板
+type unitText struct {
+	every   string   // 每个单位
+	everyN  string   // 每 n 个单位
+	at      string   // 单个值
+	atList  string   // 多个值
+	between string   // 范围
+	names   []string // 值的显示名称，为空时显示数字
+}
+
+var zhUnits = [6]unitText{
+	{every: "每秒", everyN: "每%d秒", at: "第%s秒", atList: "第%s秒", between: "第%s到%s秒"},
+	{every: "每分钟", everyN: "每%d分钟", at: "第%s分钟", atList: "第%s分钟", between: "第%s到%s分钟"},
+	{every: "每小时", everyN: "每%d小时", at: "%s点", atList: "%s点", between: "%s点到%s点"},
+	{every: "每天", everyN: "每%d天", at: "每月%s号", atList: "每月%s号", between: "每月%s号到%s号"},
+	{every: "每月", everyN: "每%d个月", at: "%s", atList: "%s", between: "%s到%s",
+		names: []string{"", "1月", "2月", "3月", "4月", "5月", "6月", "7月", "8月", "9月", "10月", "11月", "12月"}},
+	{every: "每天", everyN: "每%d天", at: "%s", atList: "%s", between: "%s到%s",
+		names: []string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"}},
+}
+
+var enUnits = [6]unitText{
+	{every: "every second", everyN: "every %d seconds", at: "at second %s", atList: "at seconds %s", between: "seconds %s through %s"},
+	{every: "every minute", everyN: "every %d minutes", at: "at minute %s", atList: "at minutes %s", between: "minutes %s through %s"},
+	{every: "every hour", everyN: "every %d hours", at: "at hour %s", atList: "at hours %s", between: "hours %s through %s"},
+	{every: "every day", everyN: "every %d days", at: "on day %s of the month", atList: "on days %s of the month", between: "on days %s through %s of the month"},
+	{every: "every month", everyN: "every %d months", at: "in %s", atList: "in %s", between: "%s through %s",
+		names: []string{"", "January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}},
+	{every: "every day", everyN: "every %d days of the week", at: "on %s", atList: "on %s", between: "%s through %s",
+		names: []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}},
+}
+
+// fieldItem 字段中以逗号分隔的一项
+type fieldItem struct {
+	all        bool // * 或 ?
+	start, end int  // end 为 -1 表示到字段最大值
+	step       int
+}
+
+func (i fieldItem) single() bool {
+	return !i.all && i.step == 0 && i.start == i.end
+}
+
+// Describe 将crontab表达式转换为自然语言描述，表达式需要先通过 Parse 校验
+func Describe(expr string, lang Lang) (string, error) {
+	expr = stripTimeZone(expr)
+	if strings.HasPrefix(expr, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(expr, "@every ")))
+		if err != nil {
+			return "", err
+		}
+		if lang == LangEn {
+			return "Every " + d.String(), nil
+		}
+		return "每" + d.String(), nil
+	}
+	if spec, ok := descriptors[expr]; ok {
+		expr = spec
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 6 {
+		return "", ErrFieldCount
+	}
+	items := make([][]fieldItem, 6)
+	for i, field := range fields {
+		var names map[string]int
+		switch i {
+		case fieldMonth:
+			names = monthNames
+		case fieldDow:
+			names = dowNames
+		}
+		fieldItems, err := parseField(field, names)
+		if err != nil {
+			return "", err
+		}
+		items[i] = fieldItems
+	}
+
+	units := zhUnits
+	if lang == LangEn {
+		units = enUnits
+	}
+
+	// 日期部分，未限制的字段省略
+	var dateParts []string
+	for _, i := range []int{fieldDom, fieldMonth, fieldDow} {
+		if len(items[i]) == 1 && items[i][0].all && items[i][0].step == 0 {
+			continue
+		}
+		dateParts = append(dateParts, describeField(items[i], units[i], lang))
+	}
+
+	// 时分秒都是单个值时直接描述为时间点
+	if items[fieldSecond][0].single() && items[fieldMinute][0].single() && items[fieldHour][0].single() &&
+		len(items[fieldSecond])+len(items[fieldMinute])+len(items[fieldHour]) == 3 {
+		clock := fmt.Sprintf("%02d:%02d:%02d", items[fieldHour][0].start,
+			items[fieldMinute][0].start, items[fieldSecond][0].start)
+		if lang == LangEn {
+			return strings.Join(append([]string{"At " + clock}, dateParts...), ", "), nil
+		}
+		if len(dateParts) == 0 {
+			return "每天 " + clock, nil
+		}
+		return strings.Join(dateParts, "，") + " " + clock, nil
+	}
+
+	// 从秒开始，前导的 0 省略，第一个 * 描述为"每x"，之后的 * 省略
+	var timeParts []string
+	leading := true
+	for _, i := range []int{fieldSecond, fieldMinute, fieldHour} {
+		fieldItems := items[i]
+		isZero := len(fieldItems) == 1 && fieldItems[0].single() && fieldItems[0].start == 0
+		isAll := len(fieldItems) == 1 && fieldItems[0].all && fieldItems[0].step == 0
+		switch {
+		case leading && isZero:
+			continue
+		case isAll:
+			if leading {
+				timeParts = append(timeParts, units[i].every)
+			}
+		default:
+			timeParts = append(timeParts, describeField(fieldItems, units[i], lang))
+		}
+		leading = false
+	}
+
+	if lang == LangEn {
+		desc := strings.Join(append(timeParts, dateParts...), ", ")
+		return strings.ToUpper(desc[:1]) + desc[1:], nil
+	}
+	for l, r := 0, len(timeParts)-1; l < r; l, r = l+1, r-1 {
+		timeParts[l], timeParts[r] = timeParts[r], timeParts[l]
+	}
+	return strings.Join(append(dateParts, timeParts...), "，"), nil
+}
+
+// stripTimeZone 去掉表达式中的时区前缀
+func stripTimeZone(expr string) string {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "CRON_TZ=") || strings.HasPrefix(expr, "TZ=") {
+		if i := strings.IndexByte(expr, ' '); i > 0 {
+			return strings.TrimSpace(expr[i:])
+		}
+	}
+	return expr
+}
+
+func parseField(field string, names map[string]int) ([]fieldItem, error) {
+	var items []fieldItem
+	for _, part := range strings.Split(field, ",") {
+		var (
+			item     fieldItem
+			rangeStr = part
+		)
+		if before, after, ok := strings.Cut(part, "/"); ok {
+			step, err := strconv.Atoi(after)
+			if err != nil {
+				return nil, err
+			}
+			item.step = step
+			rangeStr = before
+		}
+		if rangeStr == "*" || rangeStr == "?" {
+			item.all = true
+			items = append(items, item)
+			continue
+		}
+		startStr, endStr, isRange := strings.Cut(rangeStr, "-")
+		start, err := parseValue(startStr, names)
+		if err != nil {
+			return nil, err
+		}
+		item.start, item.end = start, start
+		if isRange {
+			if item.end, err = parseValue(endStr, names); err != nil {
+				return nil, err
+			}
+		} else if item.step > 0 {
+			item.end = -1
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func parseValue(s string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	return strconv.Atoi(s)
+}
+
+func describeField(items []fieldItem, unit unitText, lang Lang) string {
+	listSep, lastSep, itemSep := "、", "、", "，"
+	if lang == LangEn {
+		listSep, lastSep, itemSep = ", ", " and ", " and "
+	}
+
+	allSingle := true
+	for _, item := range items {
+		allSingle = allSingle && item.single()
+	}
+	if allSingle && len(items) > 1 {
+		values := make([]string, 0, len(items))
+		for _, item := range items {
+			values = append(values, unit.value(item.start))
+		}
+		last := len(values) - 1
+		return fmt.Sprintf(unit.atList, strings.Join(values[:last], listSep)+lastSep+values[last])
+	}
+
+	descs := make([]string, 0, len(items))
+	for _, item := range items {
+		descs = append(descs, describeItem(item, unit, lang))
+	}
+	return strings.Join(descs, itemSep)
+}
+
+func describeItem(item fieldItem, unit unitText, lang Lang) string {
+	switch {
+	case item.all && item.step == 0:
+		return unit.every
+	case item.all:
+		return fmt.Sprintf(unit.everyN, item.step)
+	case item.single():
+		return fmt.Sprintf(unit.at, unit.value(item.start))
+	case item.step == 0:
+		return fmt.Sprintf(unit.between, unit.value(item.start), unit.value(item.end))
+	}
+
+	everyN := fmt.Sprintf(unit.everyN, item.step)
+	if item.end == -1 {
+		at := fmt.Sprintf(unit.at, unit.value(item.start))
+		if lang == LangEn {
+			return everyN + " starting " + at
+		}
+		return "从" + at + "开始" + everyN
+	}
+	between := fmt.Sprintf(unit.between, unit.value(item.start), unit.value(item.end))
+	if lang == LangEn {
+		return everyN + ", " + between
+	}
+	return between + "内" + everyN
+}
+
+func (u unitText) value(v int) string {
+	if v >= 0 && v < len(u.names) {
+		return u.names[v]
+	}
+	return strconv.Itoa(v)
+}
@@ -0,0 +1,23 @@
+package cronx
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"strconv"
+	"time"
+)
+
+// JitterDelay 计算任务执行前的延迟，精度为毫秒
+// hash 为 true 时根据 key 计算固定的偏移(类似 Jenkins 的 H)，同一个任务每次延迟相同；否则在 [0, max] 内随机
+func JitterDelay(key int, max time.Duration, hash bool) time.Duration {
+	maxMs := max.Milliseconds()
+	if maxMs <= 0 {
+		return 0
+	}
+	if !hash {
+		return time.Duration(rand.Int64N(maxMs+1)) * time.Millisecond
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(strconv.Itoa(key)))
+	return time.Duration(h.Sum64()%uint64(maxMs+1)) * time.Millisecond
+}
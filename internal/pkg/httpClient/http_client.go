@@ -2,10 +2,12 @@ package httpClient
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"go-job/internal/dto"
+	"net/http"
 	"os"
 	"path/filepath"
 	"resty.dev/v3"
@@ -17,6 +19,42 @@ var (
 	DefaultTimeout     = time.Second * 3
 )
 
+var (
+	scheme    = "http"
+	tlsConfig *tls.Config
+	transport *http.Transport // 包装之前的传输层，证书续期后关闭其中的空闲连接
+)
+
+// EnableTLS 默认客户端使用 https 访问 master 和节点，需要在 WrapTransport 之前调用
+func EnableTLS(cfg *tls.Config) {
+	defaultRestyClient.SetTLSClientConfig(cfg)
+	transport, _ = defaultRestyClient.HTTPTransport()
+	tlsConfig = cfg
+	scheme = "https"
+}
+
+// CloseIdleConnections 关闭空闲连接，之后的请求重新握手，使用续期后的证书
+func CloseIdleConnections() {
+	if transport != nil {
+		transport.CloseIdleConnections()
+	}
+}
+
+// TLSConfig 开启 TLS 时的客户端配置，未开启时为 nil
+func TLSConfig() *tls.Config {
+	return tlsConfig
+}
+
+// URL 按是否开启 TLS 拼接访问 address 的地址
+func URL(address, path string) string {
+	return scheme + "://" + address + path
+}
+
+// WrapTransport 包装默认客户端的传输层，需要在发送请求之前调用
+func WrapTransport(wrap func(base http.RoundTripper) http.RoundTripper) {
+	defaultRestyClient.SetTransport(wrap(defaultRestyClient.Transport()))
+}
+
 func GetJson(ctx context.Context, url string, headers map[string]string, params map[string]string, timeout time.Duration) (*resty.Response, error) {
 	jsonHeader(headers)
 	return defaultRestyClient.SetTimeout(timeout).R().
@@ -3,13 +3,16 @@ package paths
 import "fmt"
 
 type JobAPI struct {
-	BasePath   string
-	Create     string
-	Update     string
-	GetAll     string
-	Upload     string
-	GetOneById func(id int) string
-	DeleteById func(id int) string
+	BasePath    string
+	Create      string
+	Update      string
+	GetAll      string
+	Upload      string
+	States      string
+	List        string
+	GetOneById  func(id int) string
+	DeleteById  func(id int) string
+	TriggerById func(id int) string
 }
 
 /*const (
@@ -26,15 +29,34 @@ var NodeJobAPI = &JobAPI{
 	Update:   "",
 	GetAll:   "",
 	Upload:   "/upload",
+	States:   "/states",
+	List:     "/list",
 	GetOneById: func(id int) string {
 		return fmt.Sprintf("/%d", id)
 	},
 	DeleteById: func(id int) string {
 		return fmt.Sprintf("/%d", id)
 	},
+	TriggerById: func(id int) string {
+		return fmt.Sprintf("/%d/trigger", id)
+	},
 }
 
 var (
 	JobRecordCreateAPI = "/api/go-job/job_records/add"
 	JobListAPI         = "/api/go-job/jobs"
+
+	NodeRegisterAPI  = "/api/go-job/nodes/register"
+	NodeHeartbeatAPI = "/api/go-job/nodes/heartbeat"
+	NodeConnectAPI   = "/api/go-job/nodes/connect"
+	NodeCertAPI      = "/api/go-job/nodes/cert"
+)
+
+var (
+	NodeSchedulerPauseAPI  = "/api/go-job/node/scheduler/pause"
+	NodeSchedulerResumeAPI = "/api/go-job/node/scheduler/resume"
+
+	NodeMaintenanceEnterAPI = "/api/go-job/node/maintenance/enter"
+	NodeMaintenanceExitAPI  = "/api/go-job/node/maintenance/exit"
+	NodeMaintenanceDrainAPI = "/api/go-job/node/maintenance/drain"
 )
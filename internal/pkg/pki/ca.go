@@ -0,0 +1,156 @@
+package pki
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"os"
+	"time"
+)
+
+// caValidity 生成的 CA 证书有效期
+const caValidity = 10 * 365 * 24 * time.Hour
+
+// CA master 内置的证书颁发机构，给 master 和节点签发同时用于服务端和客户端的证书
+type CA struct {
+	cert    *x509.Certificate
+	certPEM []byte
+	key     crypto.Signer
+	pool    *x509.CertPool
+}
+
+// LoadOrCreateCA 读取 CA 证书和私钥，都不存在时生成并保存，多个 master 需要使用同一个 CA
+func LoadOrCreateCA(certFile, keyFile string) (*CA, error) {
+	certPEM, certErr := os.ReadFile(certFile)
+	keyPEM, keyErr := os.ReadFile(keyFile)
+	if os.IsNotExist(certErr) && os.IsNotExist(keyErr) {
+		return createCA(certFile, keyFile)
+	}
+	if err := errors.Join(certErr, keyErr); err != nil {
+		return nil, err
+	}
+	cert, err := ParseCert(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParseKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return newCA(cert, certPEM, key), nil
+}
+
+func createCA(certFile, keyFile string) (*CA, error) {
+	key, err := NewKey()
+	if err != nil {
+		return nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject("go-job CA"),
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := EncodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	certPEM := EncodeCert(der)
+	if err = WriteFile(keyFile, keyPEM, 0600); err != nil {
+		return nil, err
+	}
+	if err = WriteFile(certFile, certPEM, 0644); err != nil {
+		return nil, err
+	}
+	return newCA(cert, certPEM, key), nil
+}
+
+func newCA(cert *x509.Certificate, certPEM []byte, key crypto.Signer) *CA {
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &CA{cert: cert, certPEM: certPEM, key: key, pool: pool}
+}
+
+// CertPEM CA 证书，节点使用它校验 master 的证书
+func (ca *CA) CertPEM() []byte {
+	return ca.certPEM
+}
+
+func (ca *CA) Pool() *x509.CertPool {
+	return ca.pool
+}
+
+// Issue 给公钥签发证书，hosts 为证书中的域名或IP
+func (ca *CA) Issue(pub crypto.PublicKey, commonName string, hosts []string, ttl time.Duration) (*x509.Certificate, []byte, error) {
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      subject(commonName),
+		NotBefore:    now.Add(-5 * time.Minute), // 允许少量的时钟偏差
+		NotAfter:     now.Add(ttl),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else if h != "" {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, pub, ca.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, EncodeCert(der), nil
+}
+
+// SignCSR 使用证书请求中的公钥签发证书，CN 和域名使用 master 指定的，不使用请求中的
+func (ca *CA) SignCSR(csrPEM []byte, commonName string, hosts []string, ttl time.Duration) (*x509.Certificate, []byte, error) {
+	csr, err := ParseCSR(csrPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ca.Issue(csr.PublicKey, commonName, hosts, ttl)
+}
+
+// IssueKeyPair 生成私钥并签发证书，用于 master 自己的证书
+func (ca *CA) IssueKeyPair(commonName string, hosts []string, ttl time.Duration) (tls.Certificate, error) {
+	key, err := NewKey()
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	cert, _, err := ca.Issue(key.Public(), commonName, hosts, ttl)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key, Leaf: cert}, nil
+}
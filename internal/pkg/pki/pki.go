@@ -0,0 +1,173 @@
+package pki
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MasterCommonName master 证书的 CN，节点只接受该 CN 的客户端证书
+	MasterCommonName = "go-job-master"
+	nodeCommonPrefix = "go-job-node-"
+)
+
+var (
+	ErrInvalidPEM = errors.New("PEM 格式无效")
+	ErrInvalidCSR = errors.New("证书请求签名无效")
+)
+
+// NodeCommonName 节点证书的 CN，master 通过 CN 确认证书属于哪个节点
+func NodeCommonName(nodeId int) string {
+	return nodeCommonPrefix + strconv.Itoa(nodeId)
+}
+
+// NodeIdFromCert 从节点证书的 CN 中解析节点id，不是节点证书时返回 false
+func NodeIdFromCert(cert *x509.Certificate) (int, bool) {
+	idStr, ok := strings.CutPrefix(cert.Subject.CommonName, nodeCommonPrefix)
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// Serial 证书序列号的十六进制
+func Serial(cert *x509.Certificate) string {
+	return hex.EncodeToString(cert.SerialNumber.Bytes())
+}
+
+// NewKey 生成 P-256 私钥
+func NewKey() (crypto.Signer, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+func EncodeKey(key crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+func ParseKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPEM
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("不支持的私钥类型")
+	}
+	return signer, nil
+}
+
+// LoadOrCreateKey 读取私钥文件，不存在时生成并保存
+func LoadOrCreateKey(file string) (crypto.Signer, error) {
+	data, err := os.ReadFile(file)
+	if err == nil {
+		return ParseKey(data)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	key, err := NewKey()
+	if err != nil {
+		return nil, err
+	}
+	data, err = EncodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err = WriteFile(file, data, 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func EncodeCert(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func ParseCert(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, ErrInvalidPEM
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// NewCSR 生成证书请求，CN 和域名由 master 签发时决定，请求只用于证明持有私钥
+func NewCSR(key crypto.Signer) ([]byte, error) {
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), nil
+}
+
+func ParseCSR(data []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, ErrInvalidPEM
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if err = csr.CheckSignature(); err != nil {
+		return nil, ErrInvalidCSR
+	}
+	return csr, nil
+}
+
+// NewCertPool 读取 CA 证书文件
+func NewCertPool(caFile string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, ErrInvalidPEM
+	}
+	return pool, nil
+}
+
+// WriteFile 先写临时文件再重命名，避免写入一半时进程退出留下损坏的证书
+func WriteFile(file string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+func subject(commonName string) pkix.Name {
+	return pkix.Name{Organization: []string{"go-job"}, CommonName: commonName}
+}
@@ -0,0 +1,136 @@
+package pki
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadOrCreateCA(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "ca.crt"), filepath.Join(dir, "ca.key")
+	ca, err := LoadOrCreateCA(certFile, keyFile)
+	require.NoError(t, err)
+
+	// 再次加载使用同一个 CA
+	loaded, err := LoadOrCreateCA(certFile, keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, ca.CertPEM(), loaded.CertPEM())
+}
+
+func TestSignCSR(t *testing.T) {
+	ca, err := LoadOrCreateCA(filepath.Join(t.TempDir(), "ca.crt"), filepath.Join(t.TempDir(), "ca.key"))
+	require.NoError(t, err)
+	key, err := NewKey()
+	require.NoError(t, err)
+	csr, err := NewCSR(key)
+	require.NoError(t, err)
+
+	cert, certPEM, err := ca.SignCSR(csr, NodeCommonName(3), []string{"10.0.0.3", "node3"}, time.Hour)
+	require.NoError(t, err)
+	parsed, err := ParseCert(certPEM)
+	require.NoError(t, err)
+	assert.Equal(t, Serial(cert), Serial(parsed))
+
+	id, ok := NodeIdFromCert(cert)
+	assert.True(t, ok)
+	assert.Equal(t, 3, id)
+	_, err = cert.Verify(x509.VerifyOptions{Roots: ca.Pool(), DNSName: "10.0.0.3"})
+	assert.NoError(t, err)
+	_, err = cert.Verify(x509.VerifyOptions{Roots: ca.Pool(), DNSName: "10.0.0.4"})
+	assert.Error(t, err)
+
+	_, err = KeyPair(certPEM, key)
+	assert.NoError(t, err)
+	other, err := NewKey()
+	require.NoError(t, err)
+	_, err = KeyPair(certPEM, other)
+	assert.ErrorIs(t, err, ErrKeyMismatch)
+
+	_, _, err = ca.SignCSR([]byte("invalid"), NodeCommonName(3), nil, time.Hour)
+	assert.ErrorIs(t, err, ErrInvalidPEM)
+}
+
+func TestHolderNeedRenew(t *testing.T) {
+	ca, err := LoadOrCreateCA(filepath.Join(t.TempDir(), "ca.crt"), filepath.Join(t.TempDir(), "ca.key"))
+	require.NoError(t, err)
+	h := &Holder{}
+	assert.True(t, h.NeedRenew(time.Now()))
+
+	cert, err := ca.IssueKeyPair(MasterCommonName, nil, 30*time.Hour)
+	require.NoError(t, err)
+	h.Set(cert)
+	testCases := []struct {
+		name  string
+		after time.Duration
+		want  bool
+	}{
+		{name: "new cert", after: 0, want: false},
+		{name: "before last third", after: 15 * time.Hour, want: false},
+		{name: "last third", after: 21 * time.Hour, want: true},
+		{name: "expired", after: 31 * time.Hour, want: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, h.NeedRenew(time.Now().Add(tc.after)))
+		})
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca, err := LoadOrCreateCA(filepath.Join(t.TempDir(), "ca.crt"), filepath.Join(t.TempDir(), "ca.key"))
+	require.NoError(t, err)
+	server := &Holder{}
+	serverCert, err := ca.IssueKeyPair(NodeCommonName(1), []string{"127.0.0.1"}, time.Hour)
+	require.NoError(t, err)
+	server.Set(serverCert)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	// 节点只接受 master 的客户端证书
+	srv := &http.Server{
+		Handler:   http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		TLSConfig: ServerConfig(server, ca.Pool(), tls.RequireAndVerifyClientCert, MasterCommonName),
+		ErrorLog:  log.New(io.Discard, "", 0),
+	}
+	go func() { _ = srv.ServeTLS(ln, "", "") }()
+	t.Cleanup(func() { _ = srv.Close() })
+	url := "https://" + ln.Addr().String()
+
+	testCases := []struct {
+		name       string
+		commonName string // 为空时不发送客户端证书
+		wantErr    bool
+	}{
+		{name: "master", commonName: MasterCommonName},
+		{name: "other node", commonName: NodeCommonName(2), wantErr: true},
+		{name: "no client cert", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &Holder{}
+			if tc.commonName != "" {
+				cert, err := ca.IssueKeyPair(tc.commonName, nil, time.Hour)
+				require.NoError(t, err)
+				client.Set(cert)
+			}
+			c := &http.Client{Transport: &http.Transport{TLSClientConfig: ClientConfig(client, ca.Pool())}}
+			resp, err := c.Get(url)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		})
+	}
+}
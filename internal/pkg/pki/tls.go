@@ -0,0 +1,119 @@
+package pki
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoCertificate  = errors.New("还没有证书")
+	ErrPeerCommonName = errors.New("对端证书的 CN 不允许访问")
+	ErrKeyMismatch    = errors.New("证书和私钥不匹配")
+)
+
+// Holder 保存当前使用的证书，续期后替换，新的连接使用新的证书
+type Holder struct {
+	mux  sync.RWMutex
+	cert *tls.Certificate
+}
+
+func (h *Holder) Set(cert tls.Certificate) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.cert = &cert
+}
+
+// Get 没有证书时返回 nil
+func (h *Holder) Get() *tls.Certificate {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+	return h.cert
+}
+
+// Leaf 当前证书，没有证书时返回 nil
+func (h *Holder) Leaf() *x509.Certificate {
+	cert := h.Get()
+	if cert == nil {
+		return nil
+	}
+	return cert.Leaf
+}
+
+// NeedRenew 没有证书或有效期剩余不到三分之一时需要续期
+func (h *Holder) NeedRenew(now time.Time) bool {
+	leaf := h.Leaf()
+	if leaf == nil {
+		return true
+	}
+	lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+	return leaf.NotAfter.Sub(now) < lifetime/3
+}
+
+func (h *Holder) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if cert := h.Get(); cert != nil {
+		return cert, nil
+	}
+	return nil, ErrNoCertificate
+}
+
+func (h *Holder) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	// 没有证书时不发送客户端证书，由服务端决定是否拒绝
+	if cert := h.Get(); cert != nil {
+		return cert, nil
+	}
+	return &tls.Certificate{}, nil
+}
+
+// KeyPair 组合证书和私钥，证书和私钥不匹配时返回错误
+func KeyPair(certPEM []byte, key crypto.Signer) (tls.Certificate, error) {
+	cert, err := ParseCert(certPEM)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	pub, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(cert.PublicKey) {
+		return tls.Certificate{}, ErrKeyMismatch
+	}
+	return tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key, Leaf: cert}, nil
+}
+
+// ServerConfig 服务端配置，clientAuth 决定是否要求客户端证书，commonNames 不为空时只接受这些 CN 的客户端证书
+func ServerConfig(h *Holder, pool *x509.CertPool, clientAuth tls.ClientAuthType, commonNames ...string) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: h.getCertificate,
+		ClientCAs:      pool,
+		ClientAuth:     clientAuth,
+	}
+	if len(commonNames) > 0 {
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return nil
+			}
+			return verifyCommonName(cs.PeerCertificates[0], commonNames)
+		}
+	}
+	return cfg
+}
+
+// ClientConfig 客户端配置，使用 pool 校验服务端证书，服务端要求时发送当前证书
+func ClientConfig(h *Holder, pool *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		MinVersion:           tls.VersionTLS12,
+		RootCAs:              pool,
+		GetClientCertificate: h.getClientCertificate,
+	}
+}
+
+func verifyCommonName(cert *x509.Certificate, commonNames []string) error {
+	for _, cn := range commonNames {
+		if cert.Subject.CommonName == cn {
+			return nil
+		}
+	}
+	return ErrPeerCommonName
+}
@@ -0,0 +1,269 @@
+package tunnel
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"golang.org/x/net/websocket"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// maxPayloadBytes 单个帧的最大长度，任务文件也通过帧发送
+	maxPayloadBytes = 64 << 20
+	writeTimeout    = 10 * time.Second
+	// missedKeepalive 连续多少个保活间隔没有收到任何帧时断开连接
+	missedKeepalive = 3
+)
+
+var (
+	ErrClosed  = errors.New("反向连接已断开")
+	ErrTimeout = errors.New("反向连接保活超时")
+)
+
+type FrameType string
+
+const (
+	FrameRequest  FrameType = "request"
+	FrameResponse FrameType = "response"
+	FramePing     FrameType = "ping"
+	FramePong     FrameType = "pong"
+)
+
+// Frame 连接上传输的一帧，请求和响应通过 Id 对应，两端都可以发送请求
+type Frame struct {
+	Id     uint64      `json:"id,omitempty"`
+	Type   FrameType   `json:"type"`
+	Method string      `json:"method,omitempty"`
+	URI    string      `json:"uri,omitempty"` // 请求的 path 和 query
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+	Status int         `json:"status,omitempty"`
+}
+
+// Conn 在一个 websocket 连接上双向发送 http 请求，收到的请求交给 handler 处理
+type Conn struct {
+	ws         *websocket.Conn
+	handler    http.Handler
+	remoteAddr string
+	tlsState   *tls.ConnectionState // 建立连接时的 TLS 状态，连接上收到的请求使用该状态中的客户端证书
+
+	writeMux sync.Mutex
+	mux      sync.Mutex
+	nextId   uint64
+	pending  map[uint64]chan Frame
+	lastRead atomic.Int64
+
+	closeOnce sync.Once
+	done      chan struct{}
+	err       error
+}
+
+func NewConn(ws *websocket.Conn, handler http.Handler) *Conn {
+	ws.MaxPayloadBytes = maxPayloadBytes
+	c := &Conn{
+		ws:      ws,
+		handler: handler,
+		pending: make(map[uint64]chan Frame),
+		done:    make(chan struct{}),
+	}
+	if req := ws.Request(); req != nil {
+		c.remoteAddr = req.RemoteAddr
+		c.tlsState = req.TLS
+	} else {
+		c.remoteAddr = ws.RemoteAddr().String()
+	}
+	c.lastRead.Store(time.Now().UnixNano())
+	return c
+}
+
+// Serve 读取并处理对端发送的帧，keepalive 大于0时定期发送 ping，连接断开或 ctx 结束时返回
+func (c *Conn) Serve(ctx context.Context, keepalive time.Duration) error {
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.close(ctx.Err())
+		case <-c.done:
+		}
+	}()
+	if keepalive > 0 {
+		go c.keepalive(keepalive)
+	}
+	for {
+		var frame Frame
+		if err := websocket.JSON.Receive(c.ws, &frame); err != nil {
+			c.close(err)
+			return c.Err()
+		}
+		c.lastRead.Store(time.Now().UnixNano())
+		switch frame.Type {
+		case FrameRequest:
+			go c.handle(frame)
+		case FrameResponse:
+			c.mux.Lock()
+			ch, ok := c.pending[frame.Id]
+			delete(c.pending, frame.Id)
+			c.mux.Unlock()
+			if ok {
+				ch <- frame
+			}
+		case FramePing:
+			if err := c.send(Frame{Type: FramePong}); err != nil {
+				c.close(err)
+			}
+		}
+	}
+}
+
+func (c *Conn) keepalive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+		if time.Since(time.Unix(0, c.lastRead.Load())) > missedKeepalive*interval {
+			c.close(ErrTimeout)
+			return
+		}
+		if err := c.send(Frame{Type: FramePing}); err != nil {
+			c.close(err)
+			return
+		}
+	}
+}
+
+// handle 把收到的请求交给 handler，处理结果作为响应帧发回
+func (c *Conn) handle(frame Frame) {
+	resp := Frame{Id: frame.Id, Type: FrameResponse}
+	req, err := http.NewRequest(frame.Method, frame.URI, bytes.NewReader(frame.Body))
+	if err != nil || c.handler == nil {
+		resp.Status = http.StatusBadRequest
+	} else {
+		req.Header = frame.Header
+		if req.Header == nil {
+			req.Header = make(http.Header)
+		}
+		req.RequestURI = frame.URI
+		req.RemoteAddr = c.remoteAddr
+		req.TLS = c.tlsState
+		rec := httptest.NewRecorder()
+		c.handler.ServeHTTP(rec, req)
+		resp.Status = rec.Code
+		resp.Header = rec.Header()
+		resp.Body = rec.Body.Bytes()
+	}
+	if err = c.send(resp); err != nil {
+		slog.Error("tunnel send response error", "uri", frame.URI, "err", err)
+		c.close(err)
+	}
+}
+
+// RoundTrip 通过连接发送请求并等待对端的响应，实现 http.RoundTripper
+func (c *Conn) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	ch := make(chan Frame, 1)
+	c.mux.Lock()
+	c.nextId++
+	id := c.nextId
+	c.pending[id] = ch
+	c.mux.Unlock()
+	defer func() {
+		c.mux.Lock()
+		delete(c.pending, id)
+		c.mux.Unlock()
+	}()
+
+	err := c.send(Frame{
+		Id:     id,
+		Type:   FrameRequest,
+		Method: req.Method,
+		URI:    req.URL.RequestURI(),
+		Header: req.Header,
+		Body:   body,
+	})
+	if err != nil {
+		c.close(err)
+		return nil, err
+	}
+	select {
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	case <-c.done:
+		return nil, ErrClosed
+	case frame := <-ch:
+		header := frame.Header
+		if header == nil {
+			header = make(http.Header)
+		}
+		return &http.Response{
+			Status:        http.StatusText(frame.Status),
+			StatusCode:    frame.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(frame.Body)),
+			ContentLength: int64(len(frame.Body)),
+			Request:       req,
+		}, nil
+	}
+}
+
+func (c *Conn) send(frame Frame) error {
+	c.writeMux.Lock()
+	defer c.writeMux.Unlock()
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+	_ = c.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return websocket.JSON.Send(c.ws, frame)
+}
+
+// Close 断开连接，等待中的请求返回 ErrClosed
+func (c *Conn) Close() error {
+	c.close(ErrClosed)
+	return nil
+}
+
+func (c *Conn) close(err error) {
+	c.closeOnce.Do(func() {
+		c.err = err
+		close(c.done)
+		_ = c.ws.Close()
+	})
+}
+
+// Done 连接断开后关闭
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err 连接断开的原因，连接未断开时为 nil
+func (c *Conn) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
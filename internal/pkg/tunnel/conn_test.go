@@ -0,0 +1,121 @@
+package tunnel
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoHandler 返回请求的方法、路径和请求体
+func echoHandler(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Handler", name)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.RequestURI() + " " + string(body)))
+	})
+}
+
+// newPair 建立一对连接，返回服务端和客户端的连接
+func newPair(t *testing.T, keepalive time.Duration) (*Conn, *Conn) {
+	serverCh := make(chan *Conn, 1)
+	srv := httptest.NewServer(websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			conn := NewConn(ws, echoHandler("server"))
+			serverCh <- conn
+			_ = conn.Serve(context.Background(), keepalive)
+		},
+	})
+	t.Cleanup(srv.Close)
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	ws, err := websocket.Dial(url, "", "http://localhost")
+	require.NoError(t, err)
+	client := NewConn(ws, echoHandler("client"))
+	go func() { _ = client.Serve(context.Background(), keepalive) }()
+	t.Cleanup(func() { _ = client.Close() })
+
+	select {
+	case server := <-serverCh:
+		return server, client
+	case <-time.After(time.Second):
+		t.Fatal("server conn not accepted")
+		return nil, nil
+	}
+}
+
+func TestConnRoundTrip(t *testing.T) {
+	server, client := newPair(t, 0)
+	testCases := []struct {
+		name    string
+		conn    *Conn
+		handler string
+	}{
+		{name: "client to server", conn: client, handler: "server"},
+		{name: "server to client", conn: server, handler: "client"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://node:8081/api/jobs?id=1", strings.NewReader("hello"))
+			resp, err := tc.conn.RoundTrip(req)
+			require.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusCreated, resp.StatusCode)
+			assert.Equal(t, tc.handler, resp.Header.Get("X-Handler"))
+			assert.Equal(t, "POST /api/jobs?id=1 hello", string(body))
+		})
+	}
+}
+
+func TestConnClose(t *testing.T) {
+	server, client := newPair(t, 0)
+	require.NoError(t, client.Close())
+
+	select {
+	case <-server.Done():
+	case <-time.After(time.Second):
+		t.Fatal("server conn not closed")
+	}
+	req := httptest.NewRequest(http.MethodGet, "http://node:8081/api/jobs", nil)
+	_, err := client.RoundTrip(req)
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestTransport(t *testing.T) {
+	_, client := newPair(t, 0)
+	direct := httptest.NewServer(echoHandler("direct"))
+	t.Cleanup(direct.Close)
+
+	registry := NewRegistry()
+	registry.Add("master:8080", client)
+	httpClient := &http.Client{Transport: &Transport{Registry: registry}}
+
+	testCases := []struct {
+		name    string
+		url     string
+		handler string
+	}{
+		{name: "registered address", url: "http://master:8080/ping", handler: "server"},
+		{name: "other address", url: direct.URL + "/ping", handler: "direct"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := httpClient.Get(tc.url)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+			assert.Equal(t, tc.handler, resp.Header.Get("X-Handler"))
+		})
+	}
+
+	// 断开后不再使用连接
+	require.NoError(t, client.Close())
+	assert.Nil(t, registry.Get("master:8080"))
+}
@@ -0,0 +1,66 @@
+package tunnel
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Registry 按对端地址保存反向连接
+type Registry struct {
+	mux   sync.RWMutex
+	conns map[string]*Conn
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		conns: make(map[string]*Conn),
+	}
+}
+
+// Add 保存连接，同一地址已有连接时关闭旧的连接
+func (r *Registry) Add(address string, conn *Conn) {
+	r.mux.Lock()
+	old := r.conns[address]
+	r.conns[address] = conn
+	r.mux.Unlock()
+	if old != nil && old != conn {
+		_ = old.Close()
+	}
+}
+
+// Remove 删除连接，地址上已经是新的连接时不删除
+func (r *Registry) Remove(address string, conn *Conn) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.conns[address] == conn {
+		delete(r.conns, address)
+	}
+}
+
+// Get 地址上未断开的连接，没有时返回 nil
+func (r *Registry) Get(address string) *Conn {
+	r.mux.RLock()
+	conn := r.conns[address]
+	r.mux.RUnlock()
+	if conn == nil || conn.Err() != nil {
+		return nil
+	}
+	return conn
+}
+
+// Transport 目标地址有反向连接时通过连接发送请求，否则使用 Base 直接请求
+type Transport struct {
+	Base     http.RoundTripper
+	Registry *Registry
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if conn := t.Registry.Get(req.URL.Host); conn != nil {
+		return conn.RoundTrip(req)
+	}
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
@@ -9,6 +9,10 @@ import (
 
 var (
 	emailCompile = regexp2.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`, regexp2.None)
+	// pyRequirementCompile python 包名，可以带 extras 和一个版本约束，如 requests[socks]>=2.31
+	pyRequirementCompile = regexp2.MustCompile(
+		`^([A-Za-z0-9](?:[A-Za-z0-9._-]*[A-Za-z0-9])?)(\[[A-Za-z0-9._-]+(?:,[A-Za-z0-9._-]+)*\])?(?:(?:==|!=|>=|<=|~=|>|<)[A-Za-z0-9.*+!_-]+)?$`,
+		regexp2.None)
 )
 
 // IsValidIPv4Address 校验IPV4地址是否有效
@@ -49,3 +53,16 @@ func IsValidEmail(email string) bool {
 	}
 	return ok
 }
+
+// PyRequirementName 校验 pip 安装的包名，返回不含 extras 和版本约束的包名，
+// 不允许以 - 开头的参数、路径和 url，避免通过包名传入 pip 的其他参数
+func PyRequirementName(requirement string) (string, bool) {
+	if len(requirement) == 0 || len(requirement) > 255 {
+		return "", false
+	}
+	m, err := pyRequirementCompile.FindStringMatch(requirement)
+	if err != nil || m == nil {
+		return "", false
+	}
+	return m.GroupByNumber(1).String(), true
+}
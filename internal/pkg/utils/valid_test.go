@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPyRequirementName(t *testing.T) {
+	testCases := []struct {
+		name        string
+		requirement string
+		want        string
+		wantOk      bool
+	}{
+		{name: "name", requirement: "requests", want: "requests", wantOk: true},
+		{name: "version", requirement: "requests==2.31.0", want: "requests", wantOk: true},
+		{name: "extras and version", requirement: "requests[socks,security]>=2.31", want: "requests", wantOk: true},
+		{name: "dot and dash", requirement: "zope.interface-x", want: "zope.interface-x", wantOk: true},
+		{name: "empty", requirement: ""},
+		{name: "option", requirement: "--index-url=http://evil"},
+		{name: "short option", requirement: "-rrequirements.txt"},
+		{name: "url", requirement: "http://evil/pkg.tar.gz"},
+		{name: "path", requirement: "../pkg"},
+		{name: "space", requirement: "requests --user"},
+		{name: "quote", requirement: `requests")`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, ok := PyRequirementName(tc.requirement)
+			assert.Equal(t, tc.wantOk, ok)
+			assert.Equal(t, tc.want, name)
+		})
+	}
+}
@@ -0,0 +1,4 @@
+package version
+
+// Version 程序版本，编译时通过 -ldflags "-X go-job/internal/pkg/version.Version=v1.0.0" 设置
+var Version = "dev"
@@ -3,6 +3,7 @@ package upload
 import (
 	"errors"
 	"github.com/Ri0nGo/gokit/slice"
+	"log/slog"
 	"path/filepath"
 	"sync"
 	"time"
@@ -27,11 +28,19 @@ type ValidatorOptions func(fileMeta FileMeta) error
 // Option 修改defaultFileUpload的直接
 type Option func(*FileUpload)
 
+// MetaStore 上传后还未创建任务的文件信息，多个 master 时需要共享
+type MetaStore interface {
+	Set(uuid string, file FileMeta) error
+	Get(uuid string) (FileMeta, bool)
+	Delete(uuid string) error
+}
+
 type FileUpload struct {
 	mux   sync.RWMutex
 	files map[string]FileMeta
 	exts  []string
 	size  int
+	store MetaStore // 为空时存储在内存中
 }
 
 var defaultFu = &FileUpload{
@@ -44,6 +53,12 @@ var defaultFu = &FileUpload{
 
 // SetFileMeta 存储一个FileMeta
 func SetFileMeta(uuid string, file FileMeta) {
+	if defaultFu.store != nil {
+		if err := defaultFu.store.Set(uuid, file); err != nil {
+			slog.Error("set file meta error", "uuid", uuid, "err", err)
+		}
+		return
+	}
 	defaultFu.mux.Lock()
 	defer defaultFu.mux.Unlock()
 	defaultFu.files[uuid] = file
@@ -51,6 +66,9 @@ func SetFileMeta(uuid string, file FileMeta) {
 
 // GetFileMeta 获取一个FileMeta
 func GetFileMeta(uuid string) (FileMeta, bool) {
+	if defaultFu.store != nil {
+		return defaultFu.store.Get(uuid)
+	}
 	defaultFu.mux.RLock()
 	defer defaultFu.mux.RUnlock()
 	file, ok := defaultFu.files[uuid]
@@ -58,6 +76,12 @@ func GetFileMeta(uuid string) (FileMeta, bool) {
 }
 
 func DeleteFileMeta(uuid string) {
+	if defaultFu.store != nil {
+		if err := defaultFu.store.Delete(uuid); err != nil {
+			slog.Error("delete file meta error", "uuid", uuid, "err", err)
+		}
+		return
+	}
 	defaultFu.mux.Lock()
 	defer defaultFu.mux.Unlock()
 	delete(defaultFu.files, uuid)
@@ -75,6 +99,13 @@ func SizeOpt(size int) Option {
 	}
 }
 
+// StoreOpt 使用外部存储保存文件信息，如 redis
+func StoreOpt(store MetaStore) Option {
+	return func(f *FileUpload) {
+		f.store = store
+	}
+}
+
 func FileUploadOpts(opts ...Option) {
 	for _, opt := range opts {
 		opt(defaultFu)
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"go-job/internal/dto"
+	"go-job/master/service"
+	"log/slog"
+)
+
+type FailoverApi struct {
+	failoverSvc service.IFailoverService
+}
+
+func NewFailoverApi(failoverSvc service.IFailoverService) *FailoverApi {
+	return &FailoverApi{
+		failoverSvc: failoverSvc,
+	}
+}
+
+// RegisterRoutes 注册故障转移模块路由
+func (a *FailoverApi) RegisterRoutes(group *gin.RouterGroup) {
+	group.GET("/failover_events", a.GetEventList)
+}
+
+// GetEventList 查询任务的故障转移记录
+func (a *FailoverApi) GetEventList(ctx *gin.Context) {
+	var req dto.ReqFailoverEventList
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+	uc, err := GetUserClaim(ctx)
+	if err != nil {
+		slog.Error("get user claim err", "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.UnauthorizedError)
+		return
+	}
+
+	list, err := a.failoverSvc.GetEventList(uc.Uid, req)
+	if err != nil {
+		slog.Error("get failover event list err:", "err", err)
+		if service.IsRespErr(err) {
+			dto.NewJsonResp(ctx).FailWithMsg(dto.FailoverEventGetFailed, err.Error())
+		} else {
+			dto.NewJsonResp(ctx).Fail(dto.FailoverEventGetFailed)
+		}
+		return
+	}
+	dto.NewJsonResp(ctx).Success(list)
+}
@@ -41,6 +41,7 @@ func (a *JobApi) RegisterRoutes(group *gin.RouterGroup) {
 		jobGroup.DELETE("/:id", middleware.OperationLog(middleware.OperationDescDeleteJob), a.DeleteJob)
 		jobGroup.POST("/upload", middleware.OperationLog(middleware.OperationDescUploadFile), a.UploadFile)
 		jobGroup.GET("/download", middleware.OperationLog(middleware.OperationDescDownloadFile), a.DownloadFile)
+		jobGroup.GET("/cron/preview", a.PreviewCron)
 	}
 }
 
@@ -77,6 +78,17 @@ func (a *JobApi) GetJobList(ctx *gin.Context) {
 		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
 		return
 	}
+	// 节点同步任务时只返回该节点上的任务
+	if nc, ok := GetNodeClaim(ctx); ok {
+		list, err := a.JobService.GetNodeJobList(nc.NodeId, page)
+		if err != nil {
+			slog.Error("get node job list err:", "node id", nc.NodeId, "err", err)
+			dto.NewJsonResp(ctx).Fail(dto.JobGetFailed)
+			return
+		}
+		dto.NewJsonResp(ctx).Success(list)
+		return
+	}
 	uc, err := GetUserClaim(ctx)
 	if err != nil {
 		slog.Error("get user claim err", "err", err)
@@ -170,6 +182,27 @@ func (a *JobApi) UpdateJob(ctx *gin.Context) {
 	dto.NewJsonResp(ctx).Success()
 }
 
+// PreviewCron 预览crontab表达式，任务表单保存前调用
+func (a *JobApi) PreviewCron(ctx *gin.Context) {
+	var req dto.ReqCronPreview
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+
+	resp, err := a.JobService.PreviewCron(req)
+	if err != nil {
+		slog.Error("preview cron err:", "err", err)
+		if service.IsRespErr(err) {
+			dto.NewJsonResp(ctx).FailWithMsg(dto.JobCronPreview, err.Error())
+		} else {
+			dto.NewJsonResp(ctx).Fail(dto.JobCronPreview)
+		}
+		return
+	}
+	dto.NewJsonResp(ctx).Success(resp)
+}
+
 // UploadFile 保存上传的文件(master 用的)
 func (a *JobApi) UploadFile(ctx *gin.Context) {
 	file, err := ctx.FormFile("file")
@@ -62,6 +62,13 @@ func (a *JobRecordApi) AddJobRecord(ctx *gin.Context) {
 		return
 	}
 
+	if nc, ok := GetNodeClaim(ctx); ok {
+		if err := a.JobRecordService.CheckNodeResult(nc.NodeId, req); err != nil {
+			slog.Error("check node job result err", "node id", nc.NodeId, "job id", req.JobID, "err", err)
+			dto.NewJsonResp(ctx).FailWithMsg(dto.JobRecordAddFailed, err.Error())
+			return
+		}
+	}
 	err := a.JobRecordService.AddJobRecord(req)
 	if err != nil {
 		slog.Error("add job record err", "err", err)
@@ -1,14 +1,19 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"github.com/gin-gonic/gin"
 	"go-job/internal/dto"
 	"go-job/internal/model"
+	"go-job/internal/pkg/consts"
 	"go-job/master/pkg/middleware"
+	"go-job/master/pkg/nodeconn"
 	"go-job/master/service"
+	"golang.org/x/net/websocket"
 	"gorm.io/gorm"
 	"log/slog"
+	"net/http"
 	"strconv"
 )
 
@@ -27,12 +32,28 @@ func (a *NodeApi) RegisterRoutes(group *gin.RouterGroup) {
 	nodeGroup := group.Group("/nodes")
 	{
 		nodeGroup.GET("", a.GetNodeList)
+		// 节点调用，注册通过 bootstrap token 校验，心跳通过jwt校验，注册会重新生成密钥，记录操作日志
+		nodeGroup.POST("/register", middleware.SecretOperationLog(middleware.OperationDescRegisterNode), a.Register)
+		nodeGroup.POST("/heartbeat", a.Heartbeat)
+		nodeGroup.GET("/connect", a.Connect)
+		nodeGroup.POST("/cert", a.IssueCert)
 		nodeGroup.GET("/:id", a.GetNode)
 		nodeGroup.POST("/add", middleware.OperationLog(middleware.OperationDescAddNode), a.AddNode)
 		nodeGroup.PUT("/update", middleware.OperationLog(middleware.OperationDescUpdateNode), a.UpdateNode)
 		nodeGroup.DELETE("/:id", middleware.OperationLog(middleware.OperationDescDeleteNode), a.DeleteNode)
 		nodeGroup.POST("/install_ref", middleware.OperationLog(middleware.OperationDescNodeInstallRef), a.InstallRef)
 		nodeGroup.GET("/:id/info", a.NodeInfo)
+		nodeGroup.GET("/:id/resources", a.GetNodeResource)
+		nodeGroup.GET("/:id/jobs", a.GetNodeJobs)
+		nodeGroup.GET("/:id/secret", middleware.SecretOperationLog(middleware.OperationDescGetNodeSecret), a.GetNodeSecret)
+		nodeGroup.POST("/:id/secret/reset", middleware.SecretOperationLog(middleware.OperationDescResetNodeSecret), a.ResetNodeSecret)
+		nodeGroup.POST("/:id/token_secret/reset", middleware.SecretOperationLog(middleware.OperationDescResetNodeToken), a.ResetNodeTokenSecret)
+		nodeGroup.POST("/:id/cert/revoke", middleware.OperationLog(middleware.OperationDescRevokeNodeCert), a.RevokeCert)
+		nodeGroup.POST("/:id/scheduler/pause", middleware.OperationLog(middleware.OperationDescPauseScheduler), a.PauseScheduler)
+		nodeGroup.POST("/:id/scheduler/resume", middleware.OperationLog(middleware.OperationDescResumeScheduler), a.ResumeScheduler)
+		nodeGroup.POST("/:id/maintenance/enter", middleware.OperationLog(middleware.OperationDescEnterMaintenance), a.EnterMaintenance)
+		nodeGroup.POST("/:id/maintenance/exit", middleware.OperationLog(middleware.OperationDescExitMaintenance), a.ExitMaintenance)
+		nodeGroup.POST("/:id/maintenance/drain", middleware.OperationLog(middleware.OperationDescDrainNode), a.DrainNode)
 	}
 }
 
@@ -81,6 +102,13 @@ func (a *NodeApi) AddNode(ctx *gin.Context) {
 		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
 		return
 	}
+	uc, err := GetUserClaim(ctx)
+	if err != nil {
+		slog.Error("get user claim err", "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.UnauthorizedError)
+		return
+	}
+	req.UserId = uc.Uid
 
 	if err := a.NodeService.AddNode(req); err != nil {
 		slog.Error("add node err:", "err", err)
@@ -129,7 +157,11 @@ func (a *NodeApi) UpdateNode(ctx *gin.Context) {
 	}
 	if err := a.NodeService.UpdateNode(req); err != nil {
 		slog.Error("update node err:", "err", err)
-		dto.NewJsonResp(ctx).Fail(dto.NodeUpdateFailed)
+		if service.IsRespErr(err) {
+			dto.NewJsonResp(ctx).FailWithMsg(dto.NodeUpdateFailed, err.Error())
+		} else {
+			dto.NewJsonResp(ctx).Fail(dto.NodeUpdateFailed)
+		}
 		return
 	}
 	dto.NewJsonResp(ctx).Success()
@@ -145,6 +177,10 @@ func (a *NodeApi) InstallRef(ctx *gin.Context) {
 	data, err := a.NodeService.InstallRef(req)
 	if err != nil {
 		slog.Error("install ref err:", "err", err)
+		if service.IsRespErr(err) {
+			dto.NewJsonResp(ctx).FailWithMsg(dto.NodeInstallRefFailed, err.Error())
+			return
+		}
 		dto.NewJsonResp(ctx).Fail(dto.NodeInstallRefFailed)
 		return
 	}
@@ -167,3 +203,292 @@ func (a *NodeApi) NodeInfo(ctx *gin.Context) {
 	}
 	dto.NewJsonResp(ctx).Success(data)
 }
+
+// GetNodeJobs 节点上实际持有的任务及正在执行的进程
+func (a *NodeApi) GetNodeJobs(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+	data, err := a.NodeService.GetNodeJobs(id)
+	if err != nil {
+		slog.Error("get node jobs err:", "id", id, "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.NodeJobsFailed)
+		return
+	}
+	dto.NewJsonResp(ctx).Success(data)
+}
+
+// Register 节点自注册
+func (a *NodeApi) Register(ctx *gin.Context) {
+	var req dto.ReqNodeRegister
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		slog.Error("register node params err", "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+	data, err := a.NodeService.Register(ctx.GetHeader(consts.BootstrapTokenHeader), req)
+	if err != nil {
+		slog.Error("register node err:", "name", req.Name, "address", req.Address, "err", err)
+		if service.IsRespErr(err) {
+			dto.NewJsonResp(ctx).FailWithMsg(dto.NodeRegisterFailed, err.Error())
+		} else {
+			dto.NewJsonResp(ctx).Fail(dto.NodeRegisterFailed)
+		}
+		return
+	}
+	dto.NewJsonResp(ctx).Success(data)
+}
+
+// Heartbeat 节点上报心跳
+func (a *NodeApi) Heartbeat(ctx *gin.Context) {
+	var req dto.ReqNodeHeartbeat
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+	if nc, ok := GetNodeClaim(ctx); ok && nc.NodeId != req.NodeId {
+		slog.Error("node heartbeat for other node", "node id", nc.NodeId, "req node id", req.NodeId)
+		dto.NewJsonResp(ctx).FailWithMsg(dto.NodeHeartbeatFailed, "节点不能上报其他节点的心跳")
+		return
+	}
+	if err := a.NodeService.Heartbeat(req); err != nil {
+		slog.Error("node heartbeat err:", "node id", req.NodeId, "err", err)
+		dto.NewJsonResp(ctx).FailWithMsg(dto.NodeHeartbeatFailed, err.Error())
+		return
+	}
+	dto.NewJsonResp(ctx).Success()
+}
+
+// Connect 节点建立反向连接，之后 master 和节点之间的请求都通过该连接发送
+func (a *NodeApi) Connect(ctx *gin.Context) {
+	// 只允许节点的 token，升级期间兼容旧版本节点使用的内部用户 token，其他用户的 token 返回403
+	var nodeId int
+	if nc, ok := GetNodeClaim(ctx); ok {
+		nodeId = nc.NodeId
+	} else if uc, err := GetUserClaim(ctx); err != nil || uc.Uid != model.InternalDefaultUser {
+		slog.Warn("node connect with user token forbidden", "ip", ctx.ClientIP())
+		ctx.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	address := ctx.GetHeader(consts.NodeAddressHeader)
+	node, err := a.NodeService.ConnectNode(nodeId, address)
+	if err != nil {
+		slog.Error("node connect err:", "address", address, "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.NodeNotExist)
+		return
+	}
+	websocket.Server{
+		// 已经通过 jwt 校验，不校验 Origin
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			nodeconn.Serve(context.Background(), node, ws)
+		},
+	}.ServeHTTP(ctx.Writer, ctx.Request)
+}
+
+// GetNodeSecret 查询 master 请求节点时签名的密钥和节点 token 的密钥
+func (a *NodeApi) GetNodeSecret(ctx *gin.Context) {
+	a.nodeSecret(ctx, a.NodeService.GetNodeSecret)
+}
+
+// ResetNodeSecret 重新生成 master 请求节点时签名的密钥
+func (a *NodeApi) ResetNodeSecret(ctx *gin.Context) {
+	a.nodeSecret(ctx, a.NodeService.ResetNodeSecret)
+}
+
+// ResetNodeTokenSecret 重新生成节点 token 的密钥
+func (a *NodeApi) ResetNodeTokenSecret(ctx *gin.Context) {
+	a.nodeSecret(ctx, a.NodeService.ResetNodeTokenSecret)
+}
+
+func (a *NodeApi) nodeSecret(ctx *gin.Context, fn func(uid, id int) (dto.RespNodeSecret, error)) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+	uc, err := GetUserClaim(ctx)
+	if err != nil {
+		slog.Error("get user claim err", "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.UnauthorizedError)
+		return
+	}
+	data, err := fn(uc.Uid, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		dto.NewJsonResp(ctx).Fail(dto.NodeNotExist)
+		return
+	}
+	if err != nil {
+		slog.Error("node secret err:", "node id", id, "uid", uc.Uid, "err", err)
+		if service.IsRespErr(err) {
+			dto.NewJsonResp(ctx).FailWithMsg(dto.NodeSecretFailed, err.Error())
+			return
+		}
+		dto.NewJsonResp(ctx).Fail(dto.NodeSecretFailed)
+		return
+	}
+	dto.NewJsonResp(ctx).Success(data)
+}
+
+// IssueCert 节点使用自己的 token 申请证书，首次申请和续期都调用
+func (a *NodeApi) IssueCert(ctx *gin.Context) {
+	nc, ok := GetNodeClaim(ctx)
+	if !ok {
+		dto.NewJsonResp(ctx).Fail(dto.UnauthorizedError)
+		return
+	}
+	var req dto.ReqNodeCert
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+	data, err := a.NodeService.IssueNodeCert(nc.NodeId, req)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		dto.NewJsonResp(ctx).Fail(dto.NodeNotExist)
+		return
+	}
+	if err != nil {
+		slog.Error("issue node cert err:", "node id", nc.NodeId, "err", err)
+		if service.IsRespErr(err) {
+			dto.NewJsonResp(ctx).FailWithMsg(dto.NodeCertFailed, err.Error())
+			return
+		}
+		dto.NewJsonResp(ctx).Fail(dto.NodeCertFailed)
+		return
+	}
+	dto.NewJsonResp(ctx).Success(data)
+}
+
+// RevokeCert 吊销节点当前的证书
+func (a *NodeApi) RevokeCert(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+	err = a.NodeService.RevokeNodeCert(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		dto.NewJsonResp(ctx).Fail(dto.NodeNotExist)
+		return
+	}
+	if err != nil {
+		slog.Error("revoke node cert err:", "node id", id, "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.NodeCertRevokeFailed)
+		return
+	}
+	dto.NewJsonResp(ctx).Success()
+}
+
+// GetNodeResource 查询节点的资源使用情况
+func (a *NodeApi) GetNodeResource(ctx *gin.Context) {
+	idStr := ctx.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+	var req dto.ReqNodeResource
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+	data, err := a.NodeService.GetNodeResource(id, req)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		dto.NewJsonResp(ctx).Fail(dto.NodeNotExist)
+		return
+	}
+	if err != nil {
+		slog.Error("get node resource err:", "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.NodeResourceFailed)
+		return
+	}
+	dto.NewJsonResp(ctx).Success(data)
+}
+
+// PauseScheduler 暂停节点调度
+func (a *NodeApi) PauseScheduler(ctx *gin.Context) {
+	idStr := ctx.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+	if err := a.NodeService.PauseScheduler(id); err != nil {
+		slog.Error("pause node scheduler err:", "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.NodeSchedulerFailed)
+		return
+	}
+	dto.NewJsonResp(ctx).Success()
+}
+
+// ResumeScheduler 恢复节点调度
+func (a *NodeApi) ResumeScheduler(ctx *gin.Context) {
+	idStr := ctx.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+	if err := a.NodeService.ResumeScheduler(id); err != nil {
+		slog.Error("resume node scheduler err:", "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.NodeSchedulerFailed)
+		return
+	}
+	dto.NewJsonResp(ctx).Success()
+}
+
+// EnterMaintenance 节点进入维护状态
+func (a *NodeApi) EnterMaintenance(ctx *gin.Context) {
+	idStr := ctx.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+	if err := a.NodeService.EnterMaintenance(id); err != nil {
+		slog.Error("node enter maintenance err:", "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.NodeMaintenanceFailed)
+		return
+	}
+	dto.NewJsonResp(ctx).Success()
+}
+
+// ExitMaintenance 节点退出维护状态
+func (a *NodeApi) ExitMaintenance(ctx *gin.Context) {
+	idStr := ctx.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+	if err := a.NodeService.ExitMaintenance(id); err != nil {
+		slog.Error("node exit maintenance err:", "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.NodeMaintenanceFailed)
+		return
+	}
+	dto.NewJsonResp(ctx).Success()
+}
+
+// DrainNode 节点进入维护状态，并等待正在执行的任务结束
+func (a *NodeApi) DrainNode(ctx *gin.Context) {
+	idStr := ctx.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+	var req dto.ReqNodeDrain
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+	result, err := a.NodeService.DrainNode(id, req)
+	if err != nil {
+		slog.Error("drain node err:", "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.NodeDrainFailed)
+		return
+	}
+	dto.NewJsonResp(ctx).Success(result)
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"go-job/internal/dto"
+	"go-job/master/service"
+	"log/slog"
+)
+
+type ReconcileApi struct {
+	reconcileSvc service.IReconcileService
+}
+
+func NewReconcileApi(reconcileSvc service.IReconcileService) *ReconcileApi {
+	return &ReconcileApi{
+		reconcileSvc: reconcileSvc,
+	}
+}
+
+// RegisterRoutes 注册对账模块路由
+func (a *ReconcileApi) RegisterRoutes(group *gin.RouterGroup) {
+	rg := group.Group("/reconcile")
+	rg.GET("/report", a.GetReport)
+	rg.POST("/run", a.Run)
+}
+
+// GetReport 查询最近一次对账的结果
+func (a *ReconcileApi) GetReport(ctx *gin.Context) {
+	report, ok := a.reconcileSvc.GetReport()
+	if !ok {
+		dto.NewJsonResp(ctx).Fail(dto.ReconcileReportFailed)
+		return
+	}
+	dto.NewJsonResp(ctx).Success(report)
+}
+
+// Run 立即对账一次，返回本次对账的结果
+func (a *ReconcileApi) Run(ctx *gin.Context) {
+	var req dto.ReqReconcile
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+	report, err := a.reconcileSvc.Reconcile(req.DryRun)
+	if err != nil {
+		slog.Error("reconcile jobs err:", "err", err)
+		if service.IsRespErr(err) {
+			dto.NewJsonResp(ctx).FailWithMsg(dto.ReconcileRunFailed, err.Error())
+		} else {
+			dto.NewJsonResp(ctx).Fail(dto.ReconcileRunFailed)
+		}
+		return
+	}
+	dto.NewJsonResp(ctx).Success(report)
+}
@@ -436,6 +436,16 @@ func GetUserClaim(ctx *gin.Context) (*model.UserClaims, error) {
 	return uc, nil
 }
 
+// GetNodeClaim 节点使用自己的 token 访问时的节点信息
+func GetNodeClaim(ctx *gin.Context) (*model.NodeClaims, bool) {
+	value, exists := ctx.Get("node")
+	if !exists {
+		return nil, false
+	}
+	nc, ok := value.(*model.NodeClaims)
+	return nc, ok
+}
+
 func UserDomainToClaim(user model.DomainUser) model.UserClaims {
 	uc := model.UserClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
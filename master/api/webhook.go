@@ -0,0 +1,152 @@
+package api
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go-job/internal/dto"
+	"go-job/internal/pkg/ratelimit"
+	"go-job/master/pkg/middleware"
+	"go-job/master/pkg/middleware/ratelimit/iplimiter"
+	"go-job/master/service"
+	"gorm.io/gorm"
+	"io"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+const (
+	webhookLimitInterval = time.Minute
+	webhookLimitRate     = 60
+)
+
+type WebhookApi struct {
+	webhookSvc service.IWebhookService
+	limiter    gin.HandlerFunc
+}
+
+func NewWebhookApi(webhookSvc service.IWebhookService, cmd redis.Cmdable) *WebhookApi {
+	redisLimiter := ratelimit.NewRedisSlidingWindowLimiter(cmd, webhookLimitInterval, webhookLimitRate)
+	return &WebhookApi{
+		webhookSvc: webhookSvc,
+		limiter:    iplimiter.NewIpLimiter(redisLimiter).SetPrefix("webhook-limiter").Builder(),
+	}
+}
+
+// RegisterRoutes 注册webhook模块路由
+func (a *WebhookApi) RegisterRoutes(group *gin.RouterGroup) {
+	// 外部系统调用，不校验jwt，通过token和签名校验
+	group.POST("/hooks/:token", a.limiter, a.Trigger)
+
+	webhookGroup := group.Group("/webhooks")
+	{
+		webhookGroup.GET("", a.GetWebhookList)
+		webhookGroup.POST("/add", middleware.OperationLog(middleware.OperationDescAddWebhook), a.AddWebhook)
+		webhookGroup.PUT("/:id/revoke", middleware.OperationLog(middleware.OperationDescRevokeWebhook), a.RevokeWebhook)
+	}
+}
+
+func (a *WebhookApi) GetWebhookList(ctx *gin.Context) {
+	var req dto.ReqWebhookList
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+	uc, err := GetUserClaim(ctx)
+	if err != nil {
+		slog.Error("get user claim err", "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.UnauthorizedError)
+		return
+	}
+
+	list, err := a.webhookSvc.GetWebhookList(uc.Uid, req.JobId)
+	if err != nil {
+		slog.Error("get webhook list err:", "err", err)
+		if service.IsRespErr(err) {
+			dto.NewJsonResp(ctx).FailWithMsg(dto.WebhookGetFailed, err.Error())
+		} else {
+			dto.NewJsonResp(ctx).Fail(dto.WebhookGetFailed)
+		}
+		return
+	}
+	dto.NewJsonResp(ctx).Success(list)
+}
+
+func (a *WebhookApi) AddWebhook(ctx *gin.Context) {
+	var req dto.ReqWebhook
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		slog.Error("add webhook param err", "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+	uc, err := GetUserClaim(ctx)
+	if err != nil {
+		slog.Error("get user claim err", "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.UnauthorizedError)
+		return
+	}
+	req.UserId = uc.Uid
+
+	resp, err := a.webhookSvc.AddWebhook(req)
+	if err != nil {
+		slog.Error("add webhook error", "err", err)
+		if service.IsRespErr(err) {
+			dto.NewJsonResp(ctx).FailWithMsg(dto.WebhookAddFailed, err.Error())
+		} else {
+			dto.NewJsonResp(ctx).Fail(dto.WebhookAddFailed)
+		}
+		return
+	}
+	dto.NewJsonResp(ctx).Success(resp)
+}
+
+func (a *WebhookApi) RevokeWebhook(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+	uc, err := GetUserClaim(ctx)
+	if err != nil {
+		slog.Error("get user claim err", "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.UnauthorizedError)
+		return
+	}
+
+	if err = a.webhookSvc.RevokeWebhook(uc.Uid, id); err != nil {
+		slog.Error("revoke webhook err:", "err", err)
+		if service.IsRespErr(err) {
+			dto.NewJsonResp(ctx).FailWithMsg(dto.WebhookRevokeFailed, err.Error())
+		} else {
+			dto.NewJsonResp(ctx).Fail(dto.WebhookRevokeFailed)
+		}
+		return
+	}
+	dto.NewJsonResp(ctx).Success()
+}
+
+// Trigger 外部系统通过webhook触发任务执行
+func (a *WebhookApi) Trigger(ctx *gin.Context) {
+	// 多读一个字节用于判断是否超过上限
+	payload, err := io.ReadAll(io.LimitReader(ctx.Request.Body, service.MaxWebhookPayload+1))
+	if err != nil {
+		slog.Error("read webhook payload err", "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+
+	err = a.webhookSvc.Trigger(ctx.Param("token"), ctx.GetHeader(service.WebhookSignatureHeader), payload)
+	switch {
+	case err == nil:
+		dto.NewJsonResp(ctx).Success()
+	case errors.Is(err, service.ErrWebhookNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		dto.NewJsonResp(ctx).Fail(dto.WebhookNotExist)
+	case service.IsRespErr(err):
+		slog.Warn("trigger webhook rejected", "ip", ctx.ClientIP(), "err", err)
+		dto.NewJsonResp(ctx).FailWithMsg(dto.WebhookTriggerFailed, err.Error())
+	default:
+		slog.Error("trigger webhook err", "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.WebhookTriggerFailed)
+	}
+}
@@ -0,0 +1,156 @@
+package balancer
+
+import (
+	"errors"
+	"go-job/internal/model"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// hashReplicas 一致性哈希中每个节点的虚拟节点数量
+const hashReplicas = 100
+
+// acquireTTL 触发后超过该时间还没有上报结果的执行不再计数，避免节点崩溃或结果丢失后执行数一直不释放
+const acquireTTL = time.Hour
+
+var (
+	ErrNoNode   = errors.New("没有可用的节点")
+	ErrStrategy = errors.New("不支持的负载均衡策略")
+)
+
+// Balancer 从匹配的节点中选择执行节点，并记录通过 master 触发还未结束的执行数
+type Balancer struct {
+	mux     sync.Mutex
+	next    map[int]uint64      // 每个job轮询的位置
+	running map[int][]time.Time // 每个节点正在执行的触发时间，按先后顺序
+	ttl     time.Duration
+	now     func() time.Time
+	rand    *rand.Rand
+}
+
+func New() *Balancer {
+	return &Balancer{
+		next:    make(map[int]uint64),
+		running: make(map[int][]time.Time),
+		ttl:     acquireTTL,
+		now:     time.Now,
+		rand:    rand.New(rand.NewSource(rand.Int63())),
+	}
+}
+
+// Pick 按策略从 nodes 中选择一个节点，nodes 需要是已经过滤后的健康节点
+func (b *Balancer) Pick(strategy model.BalanceStrategy, jobId int, nodes []model.Node) (model.Node, error) {
+	if len(nodes) == 0 {
+		return model.Node{}, ErrNoNode
+	}
+	// 按id排序，保证节点列表的顺序不影响选择结果
+	sorted := make([]model.Node, len(nodes))
+	copy(sorted, nodes)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Id < sorted[j].Id })
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	switch strategy {
+	case 0, model.BalanceRoundRobin:
+		i := b.next[jobId] % uint64(len(sorted))
+		b.next[jobId]++
+		return sorted[i], nil
+	case model.BalanceRandom:
+		return sorted[b.rand.Intn(len(sorted))], nil
+	case model.BalanceLeastRunning:
+		best := sorted[0]
+		for _, node := range sorted[1:] {
+			if b.count(node.Id) < b.count(best.Id) {
+				best = node
+			}
+		}
+		return best, nil
+	case model.BalanceHash:
+		return hashPick(jobId, sorted), nil
+	default:
+		return model.Node{}, ErrStrategy
+	}
+}
+
+// Acquire 触发成功后增加节点的执行数，超过 acquireTTL 没有释放时自动过期
+func (b *Balancer) Acquire(nodeId int) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.running[nodeId] = append(b.running[nodeId], b.now())
+}
+
+// Release 执行结束后减少节点的执行数，先释放最早的一次
+func (b *Balancer) Release(nodeId int) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.count(nodeId) <= 1 {
+		delete(b.running, nodeId)
+		return
+	}
+	b.running[nodeId] = b.running[nodeId][1:]
+}
+
+// Running 节点上通过 master 触发还未结束的执行数
+func (b *Balancer) Running(nodeId int) int {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.count(nodeId)
+}
+
+// count 清理过期的执行后返回执行数，需要持有锁
+func (b *Balancer) count(nodeId int) int {
+	times := b.running[nodeId]
+	expire := b.now().Add(-b.ttl)
+	i := 0
+	for i < len(times) && times[i].Before(expire) {
+		i++
+	}
+	if i == len(times) {
+		delete(b.running, nodeId)
+		return 0
+	}
+	b.running[nodeId] = times[i:]
+	return len(times) - i
+}
+
+// hashPick 一致性哈希，节点增减时只有少部分job会换到其他节点
+func hashPick(jobId int, nodes []model.Node) model.Node {
+	var (
+		ring   = make([]uint32, 0, len(nodes)*hashReplicas)
+		owners = make(map[uint32]model.Node, len(nodes)*hashReplicas)
+	)
+	for _, node := range nodes {
+		for i := 0; i < hashReplicas; i++ {
+			h := hashKey(strconv.Itoa(node.Id) + "#" + strconv.Itoa(i))
+			ring = append(ring, h)
+			owners[h] = node
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i] < ring[j] })
+
+	h := hashKey(strconv.Itoa(jobId))
+	i := sort.Search(len(ring), func(i int) bool { return ring[i] >= h })
+	if i == len(ring) {
+		i = 0
+	}
+	return owners[ring[i]]
+}
+
+func hashKey(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()
+}
+
+// ============= 全局负载均衡器 ============= //
+
+var defaultBalancer = New()
+
+// GetBalancer 获取全局负载均衡器
+func GetBalancer() *Balancer {
+	return defaultBalancer
+}
@@ -0,0 +1,123 @@
+package balancer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-job/internal/model"
+	"testing"
+	"time"
+)
+
+func testNodes(ids ...int) []model.Node {
+	nodes := make([]model.Node, 0, len(ids))
+	for _, id := range ids {
+		nodes = append(nodes, model.Node{Id: id})
+	}
+	return nodes
+}
+
+func TestBalancerPick(t *testing.T) {
+	testCases := []struct {
+		name     string
+		strategy model.BalanceStrategy
+		nodes    []model.Node
+		running  map[int]int
+		want     []int
+		wantErr  error
+	}{
+		{
+			name:    "no node",
+			nodes:   nil,
+			wantErr: ErrNoNode,
+		},
+		{
+			name:     "round robin",
+			strategy: model.BalanceRoundRobin,
+			nodes:    testNodes(3, 1, 2),
+			want:     []int{1, 2, 3, 1},
+		},
+		{
+			name:     "least running",
+			strategy: model.BalanceLeastRunning,
+			nodes:    testNodes(1, 2, 3),
+			running:  map[int]int{1: 2, 2: 0, 3: 1},
+			want:     []int{2, 2},
+		},
+		{
+			name:     "unknown strategy",
+			strategy: 99,
+			nodes:    testNodes(1),
+			wantErr:  ErrStrategy,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := New()
+			for id, n := range tc.running {
+				for i := 0; i < n; i++ {
+					b.Acquire(id)
+				}
+			}
+			if tc.wantErr != nil {
+				_, err := b.Pick(tc.strategy, 1, tc.nodes)
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			for _, want := range tc.want {
+				node, err := b.Pick(tc.strategy, 1, tc.nodes)
+				require.NoError(t, err)
+				assert.Equal(t, want, node.Id)
+			}
+		})
+	}
+}
+
+func TestBalancerHash(t *testing.T) {
+	b := New()
+	nodes := testNodes(1, 2, 3, 4)
+	moved := 0
+	for jobId := 1; jobId <= 200; jobId++ {
+		first, err := b.Pick(model.BalanceHash, jobId, nodes)
+		require.NoError(t, err)
+		// 节点不变时总是同一个节点
+		again, _ := b.Pick(model.BalanceHash, jobId, testNodes(4, 3, 2, 1))
+		assert.Equal(t, first.Id, again.Id)
+
+		// 移除一个节点后，只有原来在该节点上的job换到其他节点
+		after, _ := b.Pick(model.BalanceHash, jobId, testNodes(1, 2, 3))
+		if first.Id != 4 {
+			assert.Equal(t, first.Id, after.Id)
+		} else {
+			moved++
+		}
+	}
+	assert.Less(t, moved, 200)
+}
+
+func TestBalancerRelease(t *testing.T) {
+	b := New()
+	b.Acquire(1)
+	b.Acquire(1)
+	b.Release(1)
+	assert.Equal(t, 1, b.Running(1))
+	b.Release(1)
+	b.Release(1)
+	assert.Equal(t, 0, b.Running(1))
+}
+
+func TestBalancerAcquireExpire(t *testing.T) {
+	b := New()
+	now := time.Now()
+	b.now = func() time.Time { return now }
+	b.Acquire(1)
+	now = now.Add(acquireTTL / 2)
+	b.Acquire(1)
+	assert.Equal(t, 2, b.Running(1))
+
+	// 没有上报结果的执行超过 acquireTTL 后不再计数
+	now = now.Add(acquireTTL/2 + time.Second)
+	assert.Equal(t, 1, b.Running(1))
+	now = now.Add(acquireTTL)
+	assert.Equal(t, 0, b.Running(1))
+	assert.Empty(t, b.running)
+}
@@ -0,0 +1,79 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/redis/go-redis/v9"
+	"go-job/internal/model"
+	"log/slog"
+)
+
+const eventChannel = "go-job:master:events"
+
+type EventType string
+
+const (
+	EventJobChanged    EventType = "job_changed"    // 任务新增、更新、删除，重新加载调度和通知配置
+	EventNodeChanged   EventType = "node_changed"   // 节点新增、更新、删除、维护状态变化，重新加载节点
+	EventNodeHeartbeat EventType = "node_heartbeat" // 节点心跳，心跳可能发送到任意一个 master
+	EventNodeStatus    EventType = "node_status"    // leader 检测的节点在线状态
+)
+
+// Event 在 master 之间同步的事件，各个 master 根据事件更新内存中的数据
+type Event struct {
+	Type     EventType           `json:"type"`
+	Origin   string              `json:"origin"` // 发送事件的 master，收到自己发送的事件时忽略
+	JobId    int                 `json:"job_id,omitempty"`
+	NodeId   int                 `json:"node_id,omitempty"`
+	Resource *model.NodeResource `json:"resource,omitempty"`
+	Online   map[int]bool        `json:"online,omitempty"`
+}
+
+// Bus 通过 redis 发布订阅在 master 之间广播事件，订阅断开期间的事件会丢失，
+// 各个 master 在成为 leader 时从数据库重新加载
+type Bus struct {
+	client redis.UniversalClient
+	id     string
+}
+
+func NewBus(client redis.UniversalClient, id string) *Bus {
+	return &Bus{
+		client: client,
+		id:     id,
+	}
+}
+
+func (b *Bus) Publish(ctx context.Context, event Event) error {
+	event.Origin = b.id
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, eventChannel, data).Err()
+}
+
+// Subscribe 接收其他 master 发送的事件，直到 ctx 结束
+func (b *Bus) Subscribe(ctx context.Context, handler func(Event)) {
+	ps := b.client.Subscribe(ctx, eventChannel)
+	defer ps.Close()
+	ch := ps.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			var event Event
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				slog.Error("unmarshal cluster event error", "payload", msg.Payload, "err", err)
+				continue
+			}
+			if event.Origin == b.id {
+				continue
+			}
+			handler(event)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go-job/master/pkg/config"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// defaultLeaseSeconds leader 租约的默认时长
+const defaultLeaseSeconds = 15
+
+var ErrRedisClient = errors.New("redis 客户端不支持发布订阅")
+
+// Cluster 多个 master 同时运行时的 leader 选举和事件同步，未开启时只有一个 master，总是 leader
+type Cluster struct {
+	enabled bool
+	id      string
+	elector *Elector
+	bus     *Bus
+}
+
+func (c *Cluster) IsLeader() bool {
+	return !c.enabled || c.elector.IsLeader()
+}
+
+// ============= 全局集群 ============= //
+
+var defaultCluster = &Cluster{}
+
+// Init 开启集群时先竞选一次，启动时就能确定是否为 leader
+func Init(cmd redis.Cmdable, cfg config.Cluster) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	client, ok := cmd.(redis.UniversalClient)
+	if !ok {
+		return ErrRedisClient
+	}
+	hostname, _ := os.Hostname()
+	id := fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.NewString()[:8])
+	lease := time.Duration(cfg.LeaseSeconds) * time.Second
+	if cfg.LeaseSeconds <= 0 {
+		lease = defaultLeaseSeconds * time.Second
+	}
+	defaultCluster = &Cluster{
+		enabled: true,
+		id:      id,
+		elector: NewElector(cmd, id, lease),
+		bus:     NewBus(client, id),
+	}
+	leader := defaultCluster.elector.Campaign(context.Background())
+	slog.Info("cluster enabled", "id", id, "leader", leader, "lease", lease)
+	return nil
+}
+
+// Enabled 是否开启了集群
+func Enabled() bool {
+	return defaultCluster.enabled
+}
+
+// IsLeader 当前 master 是否负责调度、故障转移、节点检测
+func IsLeader() bool {
+	return defaultCluster.IsLeader()
+}
+
+// OnLeaderChange 注册 leader 状态变化的回调，未开启集群时不会调用
+func OnLeaderChange(fn func(leader bool)) {
+	if defaultCluster.enabled {
+		defaultCluster.elector.OnChange(fn)
+	}
+}
+
+// Run 定期续期或竞选 leader，直到 ctx 结束
+func Run(ctx context.Context) {
+	if defaultCluster.enabled {
+		defaultCluster.elector.Run(ctx)
+	}
+}
+
+// Publish 通知其他 master，未开启集群时忽略
+func Publish(event Event) {
+	if !defaultCluster.enabled {
+		return
+	}
+	if err := defaultCluster.bus.Publish(context.Background(), event); err != nil {
+		slog.Error("publish cluster event error", "type", event.Type, "err", err)
+	}
+}
+
+// Subscribe 处理其他 master 发送的事件，直到 ctx 结束
+func Subscribe(ctx context.Context, handler func(Event)) {
+	if defaultCluster.enabled {
+		defaultCluster.bus.Subscribe(ctx, handler)
+	}
+}
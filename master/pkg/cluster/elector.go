@@ -0,0 +1,118 @@
+package cluster
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const leaderKey = "go-job:master:leader"
+
+var (
+	//go:embed lua/renew_lease.lua
+	luaRenewLease string
+
+	//go:embed lua/release_lease.lua
+	luaReleaseLease string
+)
+
+// Elector 通过 redis 租约选举 leader，leader 负责调度、故障转移、节点检测等只能由一个 master 执行的工作
+type Elector struct {
+	cmd    redis.Cmdable
+	id     string
+	lease  time.Duration
+	leader atomic.Bool
+
+	mux      sync.Mutex
+	onChange []func(leader bool)
+}
+
+func NewElector(cmd redis.Cmdable, id string, lease time.Duration) *Elector {
+	return &Elector{
+		cmd:   cmd,
+		id:    id,
+		lease: lease,
+	}
+}
+
+// IsLeader 当前实例是否持有租约
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// OnChange 注册 leader 状态变化的回调，在选举的 goroutine 中调用
+func (e *Elector) OnChange(fn func(leader bool)) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.onChange = append(e.onChange, fn)
+}
+
+// Campaign 持有租约时续期，否则尝试获取租约，返回是否为 leader
+func (e *Elector) Campaign(ctx context.Context) bool {
+	leader, err := e.campaign(ctx)
+	if err != nil {
+		// redis 异常时无法确认租约是否还有效，放弃 leader 避免出现两个 leader
+		slog.Error("leader campaign error", "id", e.id, "err", err)
+		leader = false
+	}
+	e.setLeader(leader)
+	return leader
+}
+
+func (e *Elector) campaign(ctx context.Context) (bool, error) {
+	if e.IsLeader() {
+		n, err := e.cmd.Eval(ctx, luaRenewLease, []string{leaderKey}, e.id, e.lease.Milliseconds()).Int()
+		if err != nil {
+			return false, err
+		}
+		if n == 1 {
+			return true, nil
+		}
+	}
+	return e.cmd.SetNX(ctx, leaderKey, e.id, e.lease).Result()
+}
+
+// Run 定期续期或竞选，间隔为租约的三分之一，ctx 结束后释放租约
+func (e *Elector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			e.Resign(context.Background())
+			return
+		case <-ticker.C:
+			e.Campaign(ctx)
+		}
+	}
+}
+
+// Resign 释放租约，其他实例可以立即成为 leader
+func (e *Elector) Resign(ctx context.Context) {
+	if !e.IsLeader() {
+		return
+	}
+	err := e.cmd.Eval(ctx, luaReleaseLease, []string{leaderKey}, e.id).Err()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		slog.Error("leader resign error", "id", e.id, "err", err)
+	}
+	e.setLeader(false)
+}
+
+func (e *Elector) setLeader(leader bool) {
+	if e.leader.Swap(leader) == leader {
+		return
+	}
+	slog.Warn("leader changed", "id", e.id, "leader", leader)
+	e.mux.Lock()
+	fns := append([]func(bool){}, e.onChange...)
+	e.mux.Unlock()
+	for _, fn := range fns {
+		fn(leader)
+	}
+}
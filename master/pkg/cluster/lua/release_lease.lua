@@ -0,0 +1,5 @@
+-- 只有持有租约的实例才能释放
+if redis.call("get", KEYS[1]) == ARGV[1] then
+    return redis.call("del", KEYS[1])
+end
+return 0
@@ -0,0 +1,5 @@
+-- 只有持有租约的实例才能续期
+if redis.call("get", KEYS[1]) == ARGV[1] then
+    return redis.call("pexpire", KEYS[1], ARGV[2])
+end
+return 0
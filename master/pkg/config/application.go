@@ -1,23 +1,37 @@
 package config
 
+import "slices"
+
 var App *Application
 
 type Application struct {
-	Server  Server
-	Data    Data
-	MySQL   MySQL
-	Redis   Redis
-	SMTP    map[string]SMTPProvider   `mapstructure:"smtp"`
-	OAuth   OAuth                     `mapstructure:"oauth"`
-	OAuth2  map[string]OAuth2Provider `mapstructure:"oauth2"`
-	Metrics Metrics
+	Server    Server
+	Data      Data
+	MySQL     MySQL
+	Redis     Redis
+	SMTP      map[string]SMTPProvider   `mapstructure:"smtp"`
+	OAuth     OAuth                     `mapstructure:"oauth"`
+	OAuth2    map[string]OAuth2Provider `mapstructure:"oauth2"`
+	Metrics   Metrics
+	Scheduler Scheduler
+	Register  Register
+	Cluster   Cluster
+	Reconcile Reconcile
+	TLS       TLS `mapstructure:"tls"`
 }
 
 type Server struct {
-	Port uint16
-	Name string
-	Ip   string
-	Key  string
+	Port            uint16
+	Name            string
+	Ip              string
+	Key             string
+	ShutdownTimeout int   `mapstructure:"shutdown_timeout"` // 退出时等待处理中的请求和通知发送的最长秒数
+	AdminUids       []int `mapstructure:"admin_uids"`       // 管理员的用户id，可以管理所有节点的密钥
+}
+
+// IsAdmin 用户是否为管理员
+func (s Server) IsAdmin(uid int) bool {
+	return slices.Contains(s.AdminUids, uid)
 }
 
 type Data struct {
@@ -57,8 +71,54 @@ type Metrics struct {
 }
 
 type NodeMetric struct {
-	Interval int `mapstructure:"interval"`
-	Timeout  int `mapstructure:"timeout"`
+	Interval         int `mapstructure:"interval"`
+	Timeout          int `mapstructure:"timeout"`
+	HeartbeatTimeout int `mapstructure:"heartbeat_timeout"` // 自注册的节点超过该秒数没有心跳判定为离线
+	HistorySize      int `mapstructure:"history_size"`      // 每个节点保留的资源数据数量
+}
+
+// Cluster 多个 master 同时运行，通过 redis 选举 leader
+type Cluster struct {
+	Enabled      bool `mapstructure:"enabled"`
+	LeaseSeconds int  `mapstructure:"lease_seconds"` // leader 租约时长，超过该时间没有续期由其他 master 接替
+}
+
+// Reconcile 定期对比节点上的任务和数据库，修复两边的差异
+type Reconcile struct {
+	Interval int  `mapstructure:"interval"` // 对账间隔秒数，为0时使用默认值，小于0时不定期对账
+	DryRun   bool `mapstructure:"dry_run"`  // 只记录差异，不修复
+}
+
+// Register 节点自注册
+type Register struct {
+	BootstrapToken    string `mapstructure:"bootstrap_token"`    // 为空时不允许节点自注册
+	HeartbeatInterval int    `mapstructure:"heartbeat_interval"` // 节点上报心跳的间隔秒数
+	LegacyNodeKey     bool   `mapstructure:"legacy_node_key"`    // 允许节点使用 server.key 生成 token，默认关闭，只在升级期间开启
+}
+
+// TLS master 和节点之间使用双向 TLS，master 作为 CA 给自己和节点签发证书
+type TLS struct {
+	Enabled  bool     `mapstructure:"enabled"`
+	CACert   string   `mapstructure:"ca_cert"`   // CA 证书文件，和私钥都不存在时生成，多个 master 需要使用同一个 CA
+	CAKey    string   `mapstructure:"ca_key"`    // CA 私钥文件
+	Hosts    []string `mapstructure:"hosts"`     // master 证书中的域名和IP，节点配置的 master.address 需要在其中
+	CertDays int      `mapstructure:"cert_days"` // 签发的证书有效天数，剩余三分之一时续期
+}
+
+// 任务的调度方式
+const (
+	SchedulerModeNode   = "node"   // 节点各自调度
+	SchedulerModeMaster = "master" // master 统一调度，节点只执行下发的任务
+)
+
+type Scheduler struct {
+	Mode       string `mapstructure:"mode"`        // 为空时等同于 node
+	RunTimeout int    `mapstructure:"run_timeout"` // master 触发的执行超过该秒数没有结果时记录为失败，为0时使用默认值，小于0时不检查
+}
+
+// MasterMode 是否由 master 统一调度
+func (s Scheduler) MasterMode() bool {
+	return s.Mode == SchedulerModeMaster
 }
 
 type OAuth2Provider struct {
@@ -0,0 +1,99 @@
+package dispatcher
+
+import (
+	"context"
+	"github.com/robfig/cron/v3"
+	"go-job/internal/pkg/cronx"
+	"sync"
+	"time"
+)
+
+// DispatchFunc 到达执行时间后调用，由调用方创建执行记录并下发到节点
+type DispatchFunc func(jobId int, scheduledTime, nextExecTime time.Time)
+
+// Dispatcher master 的全局调度器，只负责计算执行时间，执行由节点完成
+type Dispatcher struct {
+	mux      sync.Mutex
+	cron     *cron.Cron
+	entries  map[int]cron.EntryID
+	dispatch DispatchFunc
+}
+
+func NewDispatcher(dispatch DispatchFunc) *Dispatcher {
+	return &Dispatcher{
+		cron:     cron.New(cron.WithSeconds()),
+		entries:  make(map[int]cron.EntryID),
+		dispatch: dispatch,
+	}
+}
+
+// Schedule 添加或更新job的调度
+func (d *Dispatcher) Schedule(jobId int, cronExpr string) error {
+	schedule, err := cronx.Parse(cronExpr)
+	if err != nil {
+		return err
+	}
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if id, ok := d.entries[jobId]; ok {
+		d.cron.Remove(id)
+	}
+	d.entries[jobId] = d.cron.Schedule(schedule, cron.FuncJob(func() {
+		now := time.Now()
+		d.dispatch(jobId, now.Truncate(time.Second), schedule.Next(now))
+	}))
+	return nil
+}
+
+// Remove 移除job的调度
+func (d *Dispatcher) Remove(jobId int) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if id, ok := d.entries[jobId]; ok {
+		d.cron.Remove(id)
+		delete(d.entries, jobId)
+	}
+}
+
+// Scheduled job是否在调度中
+func (d *Dispatcher) Scheduled(jobId int) bool {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	_, ok := d.entries[jobId]
+	return ok
+}
+
+// JobIds 调度中的所有job
+func (d *Dispatcher) JobIds() []int {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	ids := make([]int, 0, len(d.entries))
+	for id := range d.entries {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (d *Dispatcher) Start() {
+	d.cron.Start()
+}
+
+// Stop 停止调度，返回的 context 在正在下发的任务结束后完成
+func (d *Dispatcher) Stop() context.Context {
+	return d.cron.Stop()
+}
+
+// ============= 全局调度器 ============= //
+
+var defaultDispatcher *Dispatcher
+
+// InitDispatcher 初始化全局调度器
+func InitDispatcher(dispatch DispatchFunc) *Dispatcher {
+	defaultDispatcher = NewDispatcher(dispatch)
+	return defaultDispatcher
+}
+
+// GetDispatcher 获取全局调度器，未初始化时返回 nil
+func GetDispatcher() *Dispatcher {
+	return defaultDispatcher
+}
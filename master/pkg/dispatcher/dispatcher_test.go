@@ -0,0 +1,36 @@
+package dispatcher
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestDispatcher(t *testing.T) {
+	dispatched := make(chan int, 10)
+	d := NewDispatcher(func(jobId int, scheduledTime, nextExecTime time.Time) {
+		assert.True(t, nextExecTime.After(scheduledTime))
+		dispatched <- jobId
+	})
+	d.Start()
+	defer d.Stop()
+
+	assert.Error(t, d.Schedule(1, "invalid"))
+	require.NoError(t, d.Schedule(1, "* * * * * *"))
+	require.NoError(t, d.Schedule(2, "0 0 0 1 1 *"))
+	assert.True(t, d.Scheduled(1))
+	assert.ElementsMatch(t, []int{1, 2}, d.JobIds())
+
+	select {
+	case id := <-dispatched:
+		assert.Equal(t, 1, id)
+	case <-time.After(2 * time.Second):
+		t.Fatal("job not dispatched")
+	}
+
+	d.Remove(1)
+	assert.False(t, d.Scheduled(1))
+	assert.True(t, d.Scheduled(2))
+	assert.Equal(t, []int{2}, d.JobIds())
+}
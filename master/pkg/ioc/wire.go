@@ -6,6 +6,7 @@ package ioc
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
 	"go-job/internal/pkg/email"
 	"go-job/master/api"
 	"go-job/master/database"
@@ -19,10 +20,14 @@ import (
 )
 
 type WebContainer struct {
-	Engine      *gin.Engine
-	MysqlDB     *gorm.DB
-	JobSvc      service.IJobService
-	NotifyStore notify.INotifyStore
+	Engine       *gin.Engine
+	MysqlDB      *gorm.DB
+	Redis        redis.Cmdable
+	JobSvc       service.IJobService
+	DispatchSvc  service.IDispatchService
+	FailoverSvc  service.IFailoverService
+	ReconcileSvc service.IReconcileService
+	NotifyStore  notify.INotifyStore
 }
 
 func InitWebServer() *WebContainer {
@@ -39,6 +44,8 @@ func InitWebServer() *WebContainer {
 		repo.NewNodeRepo,
 		repo.NewUserRepo,
 		repo.NewEmailCodeRepo,
+		repo.NewWebhookRepo,
+		repo.NewFailoverRepo,
 
 		// service
 		email.InitEmailService,
@@ -50,6 +57,10 @@ func InitWebServer() *WebContainer {
 		service.NewUserService,
 		service.NewIAMOAuthService,
 		service.NewDashboardService,
+		service.NewWebhookService,
+		service.NewDispatchService,
+		service.NewFailoverService,
+		service.NewReconcileService,
 
 		// api
 		api.NewJobApi,
@@ -59,6 +70,9 @@ func InitWebServer() *WebContainer {
 		api.NewIAMOAuthApi,
 		api.NewDashboardApi,
 		api.NewOAuth2Api,
+		api.NewWebhookApi,
+		api.NewFailoverApi,
+		api.NewReconcileApi,
 
 		// web
 		middleware.NewGinMiddlewares,
@@ -8,6 +8,7 @@ package ioc
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	"go-job/internal/pkg/email"
 	"go-job/master/api"
 	"go-job/master/database"
@@ -29,16 +30,16 @@ func InitWebServer() *WebContainer {
 	iJobRepo := repo.NewJobRepo(db)
 	iNodeRepo := repo.NewNodeRepo(db)
 	iUserRepo := repo.NewUserRepo(db)
+	iJobRecordRepo := repo.NewJobRecordRepo(db)
 	iEmailService := email.InitEmailService(cmdable)
 	iNotifyStore := notify.InitMemoryNotifyStore(iEmailService)
-	iJobService := service.NewJobService(iJobRepo, iNodeRepo, iUserRepo, iNotifyStore)
+	iJobService := service.NewJobService(iJobRepo, iNodeRepo, iUserRepo, iJobRecordRepo, iNotifyStore)
 	ioAuth2Cache := cache.NewOAuth2StateCache(cmdable)
 	iUserService := service.NewUserService(iUserRepo, ioAuth2Cache)
 	jobApi := api.NewJobApi(iJobService, iUserService)
-	iJobRecordRepo := repo.NewJobRecordRepo(db)
-	iJobRecordService := service.NewJobRecordService(iJobRecordRepo, iNotifyStore)
+	iJobRecordService := service.NewJobRecordService(iJobRecordRepo, iNotifyStore, iJobRepo, iJobService)
 	jobRecordApi := api.NewJobRecordApi(iJobRecordService)
-	iNodeService := service.NewNodeService(iNodeRepo, iJobRepo)
+	iNodeService := service.NewNodeService(iNodeRepo, iJobRepo, iJobService)
 	nodeApi := api.NewNodeApi(iNodeService)
 	iEmailCodeCache := cache.NewEmailCodeCache(cmdable)
 	iEmailCodeRepo := repo.NewEmailCodeRepo(iEmailCodeCache)
@@ -49,12 +50,25 @@ func InitWebServer() *WebContainer {
 	iDashboardService := service.NewDashboardService(iJobRepo, iJobRecordRepo)
 	dashboardApi := api.NewDashboardApi(iDashboardService)
 	oAuth2Api := api.NewOAuth2Api(iUserService)
-	engine := router.NewWebRouter(v, jobApi, jobRecordApi, nodeApi, userApi, dashboardApi, iamOAuthApi, oAuth2Api)
+	iWebhookRepo := repo.NewWebhookRepo(db)
+	iWebhookService := service.NewWebhookService(iWebhookRepo, iJobRepo, iJobRecordService)
+	webhookApi := api.NewWebhookApi(iWebhookService, cmdable)
+	iFailoverRepo := repo.NewFailoverRepo(db)
+	iFailoverService := service.NewFailoverService(iJobRepo, iFailoverRepo, iJobService)
+	failoverApi := api.NewFailoverApi(iFailoverService)
+	iReconcileService := service.NewReconcileService(iJobRepo, iJobService)
+	reconcileApi := api.NewReconcileApi(iReconcileService)
+	engine := router.NewWebRouter(v, jobApi, jobRecordApi, nodeApi, userApi, dashboardApi, iamOAuthApi, oAuth2Api, webhookApi, failoverApi, reconcileApi)
+	iDispatchService := service.NewDispatchService(iJobRepo, iJobRecordRepo, iJobService, iJobRecordService)
 	webContainer := &WebContainer{
-		Engine:      engine,
-		MysqlDB:     db,
-		JobSvc:      iJobService,
-		NotifyStore: iNotifyStore,
+		Engine:       engine,
+		MysqlDB:      db,
+		Redis:        cmdable,
+		JobSvc:       iJobService,
+		DispatchSvc:  iDispatchService,
+		FailoverSvc:  iFailoverService,
+		ReconcileSvc: iReconcileService,
+		NotifyStore:  iNotifyStore,
 	}
 	return webContainer
 }
@@ -62,8 +76,12 @@ func InitWebServer() *WebContainer {
 // wire.go:
 
 type WebContainer struct {
-	Engine      *gin.Engine
-	MysqlDB     *gorm.DB
-	JobSvc      service.IJobService
-	NotifyStore notify.INotifyStore
+	Engine       *gin.Engine
+	MysqlDB      *gorm.DB
+	Redis        redis.Cmdable
+	JobSvc       service.IJobService
+	DispatchSvc  service.IDispatchService
+	FailoverSvc  service.IFailoverService
+	ReconcileSvc service.IReconcileService
+	NotifyStore  notify.INotifyStore
 }
@@ -0,0 +1,63 @@
+package job
+
+import (
+	"errors"
+	"go-job/internal/model"
+	"go-job/master/pkg/cluster"
+	"go-job/master/pkg/dispatcher"
+	"go-job/master/pkg/metrics"
+	"go-job/master/service"
+	"gorm.io/gorm"
+	"log/slog"
+)
+
+// handleClusterEvent 根据其他 master 发送的事件更新内存中的调度、通知配置和节点状态
+func handleClusterEvent(db *gorm.DB, jobSvc service.IJobService) func(cluster.Event) {
+	return func(event cluster.Event) {
+		switch event.Type {
+		case cluster.EventJobChanged:
+			jobSvc.ReloadJob(event.JobId)
+		case cluster.EventNodeChanged:
+			reloadNode(db, event.NodeId)
+		case cluster.EventNodeHeartbeat:
+			metrics.GetNodeMetrics().Heartbeat(event.NodeId, event.Resource)
+		case cluster.EventNodeStatus:
+			metrics.GetNodeMetrics().ApplyOnline(event.Online)
+		}
+	}
+}
+
+func reloadNode(db *gorm.DB, id int) {
+	var node model.Node
+	err := db.First(&node, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		metrics.GetNodeMetrics().Remove(id)
+		return
+	}
+	if err != nil {
+		slog.Error("reload node error", "node id", id, "err", err)
+		return
+	}
+	metrics.GetNodeMetrics().Set(id, node)
+}
+
+// resyncDispatcher 成为 leader 时从数据库重新加载调度，避免订阅断开期间丢失的事件导致调度不一致
+func resyncDispatcher(db *gorm.DB, jobSvc service.IJobService) {
+	jobs, err := queryAllJobs(db)
+	if err != nil {
+		slog.Error("resync dispatcher query jobs error", "err", err)
+		return
+	}
+	exists := make(map[int]struct{}, len(jobs))
+	for _, job := range jobs {
+		exists[job.Id] = struct{}{}
+		jobSvc.ScheduleDispatch(job)
+	}
+	d := dispatcher.GetDispatcher()
+	for _, id := range d.JobIds() {
+		if _, ok := exists[id]; !ok {
+			d.Remove(id)
+		}
+	}
+	slog.Info("resync dispatcher", "jobs", len(jobs))
+}
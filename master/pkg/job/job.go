@@ -3,7 +3,9 @@ package job
 import (
 	"context"
 	"go-job/internal/model"
+	"go-job/master/pkg/cluster"
 	"go-job/master/pkg/config"
+	"go-job/master/pkg/dispatcher"
 	"go-job/master/pkg/metrics"
 	"go-job/master/pkg/notify"
 	"go-job/master/service"
@@ -11,8 +13,9 @@ import (
 	"log/slog"
 )
 
-func InitGlobalData(mysqlDB *gorm.DB, jobSvc service.IJobService,
-	notifyStore notify.INotifyStore) error {
+// InitGlobalData 加载任务和节点并启动调度，后台的集群同步、故障转移和对账在 ctx 结束后停止
+func InitGlobalData(ctx context.Context, mysqlDB *gorm.DB, jobSvc service.IJobService, dispatchSvc service.IDispatchService,
+	failoverSvc service.IFailoverService, reconcileSvc service.IReconcileService, notifyStore notify.INotifyStore) error {
 	// 查询所有的job
 	jobs, err := queryAllJobs(mysqlDB)
 	if err != nil {
@@ -24,24 +27,49 @@ func InitGlobalData(mysqlDB *gorm.DB, jobSvc service.IJobService,
 		panic(err)
 	}
 
+	// 先创建调度器，同步任务时由 master 调度的任务加入调度
+	d := dispatcher.InitDispatcher(dispatchSvc.Dispatch)
+
 	initJobData(nodeM, jobs, jobSvc, notifyStore)
 
 	initNodeMetrics(nodeM)
 
+	// 多个 master 时通过事件同步内存中的数据，leader 变化时重新加载调度
+	cluster.OnLeaderChange(func(leader bool) {
+		if leader {
+			resyncDispatcher(mysqlDB, jobSvc)
+		}
+	})
+	go cluster.Subscribe(ctx, handleClusterEvent(mysqlDB, jobSvc))
+	go cluster.Run(ctx)
+
+	// 下发任务时需要节点指标选择节点和判断是否维护中，需要在初始化指标后启动
+	d.Start()
+	slog.Info("master scheduler started", "master mode", config.App.Scheduler.MasterMode())
+
+	// 故障转移依赖节点指标中的离线时间
+	go failoverSvc.Run(ctx)
+
+	// 定期对比节点上的任务，修复下发失败或节点重启导致的差异
+	go reconcileSvc.Run(ctx)
+
+	// 节点崩溃或结果丢失时，master 触发的执行超时后记录为失败
+	go dispatchSvc.Run(ctx)
+
 	return nil
 }
 
 func initJobData(nodeM map[int]model.Node, jobs []model.Job,
 	jobSvc service.IJobService, notifyStore notify.INotifyStore) {
 
+	// 多个 master 时只由 leader 发送，避免每个 master 启动时重复发送
+	leader := cluster.IsLeader()
 	for _, job := range jobs {
-		// 发送job到node
-		node := nodeM[job.NodeID]
-		err := jobSvc.SendJobToNode(job, node, service.SendJobByCreate)
-		if err != nil {
-			slog.Error("init job to node error", "job name",
-				job.Name, "job id", job.Id, "err", err)
+		// 发送job到node，设置了节点选择器时发送到所有匹配的节点
+		if leader {
+			sendJobToNodes(jobSvc, job, jobNodes(nodeM, job))
 		}
+		jobSvc.ScheduleDispatch(job)
 
 		// 初始化任务通知数据
 		if job.Internal.Notify.NotifyStatus == model.NotifyStatusEnabled {
@@ -50,10 +78,35 @@ func initJobData(nodeM map[int]model.Node, jobs []model.Job,
 	}
 }
 
+func sendJobToNodes(jobSvc service.IJobService, job model.Job, nodes []model.Node) {
+	for _, node := range nodes {
+		err := jobSvc.SendJobToNode(job, node, service.SendJobByCreate)
+		if err != nil {
+			slog.Error("init job to node error", "job name",
+				job.Name, "job id", job.Id, "node id", node.Id, "err", err)
+		}
+	}
+}
+
+func jobNodes(nodeM map[int]model.Node, job model.Job) []model.Node {
+	if !job.Internal.Target.Enabled() {
+		return []model.Node{nodeM[job.NodeID]}
+	}
+	var nodes []model.Node
+	for _, node := range nodeM {
+		if job.Internal.Target.Match(node) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
 func initNodeMetrics(nodeM map[int]model.Node) {
 	metrics.InitNodeMetrics(context.Background(), nodeM,
 		metrics.WithNodeTimeout(config.App.Metrics.Node.Timeout),
-		metrics.WithNodeInterval(config.App.Metrics.Node.Interval))
+		metrics.WithNodeInterval(config.App.Metrics.Node.Interval),
+		metrics.WithHeartbeatTimeout(config.App.Metrics.Node.HeartbeatTimeout),
+		metrics.WithHistorySize(config.App.Metrics.Node.HistorySize))
 	go metrics.GetNodeMetrics().Monitor()
 }
 
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"go-job/internal/model"
+	"go-job/master/pkg/cluster"
+	"go-job/master/pkg/nodeconn"
 	"log/slog"
 	"net"
 	"sync"
@@ -21,6 +23,7 @@ var (
 	onceNode                   sync.Once
 	defaultCheckoutNodeTimeout = 2 * time.Second
 	defaultInterval            = 30 * time.Second
+	defaultHeartbeatTimeout    = 30 * time.Second
 )
 
 type NodeOption func(m *NodeMetrics)
@@ -47,33 +50,59 @@ func WithNodeInterval(t int) NodeOption {
 	}
 }
 
+func WithHeartbeatTimeout(t int) NodeOption {
+	return func(m *NodeMetrics) {
+		var timeout = time.Duration(t) * time.Second
+		if t == 0 {
+			timeout = defaultHeartbeatTimeout
+		}
+		m.heartbeatTimeout = timeout
+	}
+}
+
+func WithHistorySize(n int) NodeOption {
+	return func(m *NodeMetrics) {
+		if n <= 0 {
+			n = defaultHistorySize
+		}
+		m.historySize = n
+	}
+}
+
 type NodeMetrics struct {
 	mux      sync.RWMutex
 	ctx      context.Context
 	nodes    map[int]*NodeMetric
 	timeout  time.Duration // 检测存活超时时间
 	interval time.Duration // 检测节点间隔
+
+	heartbeatTimeout time.Duration // 自注册的节点超过该时间没有心跳判定为离线
+	startTime        time.Time     // master 重启后，自注册的节点从启动时间开始计算心跳超时
+	historySize      int           // 每个节点保留的资源数据数量
 }
 
 type NodeMetric struct {
 	model.Node
+
+	history *resourceHistory // 节点随心跳上报的资源使用情况
 }
 
+// Set 更新节点信息，保留已有的资源数据
 func (m *NodeMetrics) Set(nodeId int, node model.Node) {
 	m.mux.Lock()
 	defer m.mux.Unlock()
-	m.nodes[nodeId] = &NodeMetric{
-		Node: node,
-	}
+	m.nodes[nodeId] = m.newNodeMetric(nodeId, node)
 }
 
 func (m *NodeMetrics) SetAndCheck(nodeId int, node model.Node) {
 	m.mux.Lock()
 	defer m.mux.Unlock()
-	nm := &NodeMetric{
-		Node: node,
-	}
+	nm := m.newNodeMetric(nodeId, node)
 	m.nodes[nodeId] = nm
+	// 自注册的节点不主动检测，由心跳更新状态
+	if node.Heartbeat {
+		return
+	}
 
 	go func() {
 		updateNodeMetric(nm, isConnected(node.Address, m.timeout))
@@ -85,6 +114,71 @@ func updateNodeMetric(nm *NodeMetric, online bool) {
 	nm.Node.UpdatedTime = time.Now()
 }
 
+// SetMaintenance 更新节点的维护状态
+func (m *NodeMetrics) SetMaintenance(nodeId int, maintenance bool) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if nm, ok := m.nodes[nodeId]; ok {
+		nm.Maintenance = maintenance
+	}
+}
+
+// newNodeMetric 需要持有写锁
+func (m *NodeMetrics) newNodeMetric(nodeId int, node model.Node) *NodeMetric {
+	nm := &NodeMetric{Node: node}
+	if old, ok := m.nodes[nodeId]; ok {
+		// 更新节点信息时保留检测的状态，避免在下一次检测前被当作离线
+		nm.Online = old.Online
+		nm.CheckTime = old.CheckTime
+		nm.OfflineTime = old.OfflineTime
+		if nm.HeartbeatTime.IsZero() {
+			nm.HeartbeatTime = old.HeartbeatTime
+		}
+		nm.history = old.history
+		if nm.Resource == nil {
+			nm.Resource = old.Resource
+		}
+	}
+	if nm.history == nil {
+		nm.history = newResourceHistory(m.historySize)
+	}
+	return nm
+}
+
+// Heartbeat 收到节点心跳后标记为在线并记录资源使用情况，节点不存在时返回 false
+func (m *NodeMetrics) Heartbeat(nodeId int, res *model.NodeResource) bool {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	nm, ok := m.nodes[nodeId]
+	if !ok {
+		return false
+	}
+	now := time.Now()
+	nm.Heartbeat = true
+	nm.HeartbeatTime = now
+	nm.CheckTime = now
+	nm.Online = true
+	nm.OfflineTime = time.Time{}
+	if res != nil {
+		// 替换而不是修改指针指向的数据，Nodes 返回的副本中共享该指针
+		latest := *res
+		nm.Resource = &latest
+		nm.history.push(latest)
+	}
+	return true
+}
+
+// History 节点采集时间在 [begin, end] 内的资源数据，为0时不限制
+func (m *NodeMetrics) History(nodeId int, begin, end int64) ([]model.NodeResource, bool) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	nm, ok := m.nodes[nodeId]
+	if !ok {
+		return nil, false
+	}
+	return nm.history.list(begin, end), true
+}
+
 func (m *NodeMetrics) Get(nodeId int) (*NodeMetric, bool) {
 	m.mux.RLock()
 	defer m.mux.RUnlock()
@@ -102,6 +196,29 @@ func (m *NodeMetrics) All() []*NodeMetric {
 	return result
 }
 
+// Nodes 所有节点及其最近一次检测的状态
+func (m *NodeMetrics) Nodes() []model.Node {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	result := make([]model.Node, 0, len(m.nodes))
+	for _, nm := range m.nodes {
+		result = append(result, nm.Node)
+	}
+	return result
+}
+
+// SecretByAddress 地址对应节点的签名密钥，没有节点或没有密钥时为空
+func (m *NodeMetrics) SecretByAddress(address string) string {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	for _, nm := range m.nodes {
+		if nm.Address == address {
+			return nm.Secret
+		}
+	}
+	return ""
+}
+
 func (m *NodeMetrics) Remove(nodeId int) {
 	m.mux.Lock()
 	defer m.mux.Unlock()
@@ -114,9 +231,7 @@ func (m *NodeMetrics) BuildNodeMetric(nodes map[int]model.Node) *NodeMetrics {
 
 	for nodeId, node := range nodes {
 		fmt.Println("init node", nodeId, node.Name)
-		m.nodes[nodeId] = &NodeMetric{
-			Node: node,
-		}
+		m.nodes[nodeId] = m.newNodeMetric(nodeId, node)
 	}
 	return m
 }
@@ -132,28 +247,71 @@ func isConnected(addr string, timeout time.Duration) bool {
 }
 
 func (m *NodeMetrics) checkNodesMetric() {
-	m.mux.RLock()
+	now := time.Now()
+	results := make(map[int]bool)
 	addrs := make(map[int]string)
+	m.mux.RLock()
 	for id, metric := range m.nodes {
 		addrs[id] = metric.Address
+		if metric.Heartbeat {
+			last := metric.HeartbeatTime
+			if last.IsZero() {
+				last = m.startTime
+			}
+			results[id] = now.Sub(last) < m.heartbeatTimeout
+		}
 	}
 	m.mux.RUnlock()
 
-	results := make(map[int]bool)
-	for id, addr := range addrs {
-		results[id] = isConnected(addr, m.timeout)
+	// 手动添加的节点通过连接节点地址检测，多个 master 时只由 leader 检测，其他 master 通过事件同步结果
+	connected := make(map[int]bool)
+	if cluster.IsLeader() {
+		for id, addr := range addrs {
+			if _, ok := results[id]; !ok {
+				// 建立了反向连接的节点可能在私有网络中，master 无法直接连接
+				connected[id] = nodeconn.Connected(addr) || isConnected(addr, m.timeout)
+				results[id] = connected[id]
+			}
+		}
+		if len(connected) > 0 {
+			cluster.Publish(cluster.Event{Type: cluster.EventNodeStatus, Online: connected})
+		}
 	}
 
 	m.mux.Lock()
 	for id, status := range results {
-		if nm, ok := m.nodes[id]; ok {
-			nm.Online = status
-			nm.CheckTime = time.Now()
-		}
+		m.setOnline(id, status, now)
 	}
 	m.mux.Unlock()
 }
 
+// ApplyOnline 使用 leader 检测的节点在线状态
+func (m *NodeMetrics) ApplyOnline(online map[int]bool) {
+	now := time.Now()
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	for id, status := range online {
+		m.setOnline(id, status, now)
+	}
+}
+
+// setOnline 需要持有写锁
+func (m *NodeMetrics) setOnline(id int, status bool, now time.Time) {
+	nm, ok := m.nodes[id]
+	if !ok {
+		return
+	}
+	nm.Online = status
+	nm.CheckTime = now
+	// 记录开始离线的时间，用于判断是否超过故障转移的宽限时间
+	if status {
+		nm.OfflineTime = time.Time{}
+	} else if nm.OfflineTime.IsZero() {
+		nm.OfflineTime = now
+		slog.Warn("node offline", "node id", id, "addr", nm.Address)
+	}
+}
+
 func (m *NodeMetrics) Monitor() {
 	ticker := time.NewTicker(m.interval)
 	defer ticker.Stop()
@@ -176,15 +334,21 @@ func newNodeMetrics(ctx context.Context) *NodeMetrics {
 		nodes:    make(map[int]*NodeMetric),
 		timeout:  defaultCheckoutNodeTimeout,
 		interval: defaultInterval,
+
+		heartbeatTimeout: defaultHeartbeatTimeout,
+		startTime:        time.Now(),
+		historySize:      defaultHistorySize,
 	}
 }
 
 func InitNodeMetrics(ctx context.Context, nodes map[int]model.Node, opts ...NodeOption) {
 	onceNode.Do(func() {
-		nodeMetricsInstance = newNodeMetrics(ctx).BuildNodeMetric(nodes)
+		// 先应用配置，创建节点的资源数据时需要 historySize
+		nodeMetricsInstance = newNodeMetrics(ctx)
 		for _, opt := range opts {
 			opt(nodeMetricsInstance)
 		}
+		nodeMetricsInstance.BuildNodeMetric(nodes)
 	})
 }
 
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go-job/internal/model"
+	"testing"
+	"time"
+)
+
+func TestNodeMetricsHeartbeat(t *testing.T) {
+	testCases := []struct {
+		name       string
+		heartbeat  time.Duration // 距离上一次心跳的时间，为0时没有收到过心跳
+		started    time.Duration // master 已启动的时间
+		wantOnline bool
+	}{
+		{
+			name:       "heartbeat in time",
+			heartbeat:  5 * time.Second,
+			started:    time.Hour,
+			wantOnline: true,
+		},
+		{
+			name:       "heartbeat timeout",
+			heartbeat:  time.Minute,
+			started:    time.Hour,
+			wantOnline: false,
+		},
+		{
+			name:       "no heartbeat after master started",
+			started:    5 * time.Second,
+			wantOnline: true,
+		},
+		{
+			name:       "no heartbeat for a long time",
+			started:    time.Hour,
+			wantOnline: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Now()
+			m := newNodeMetrics(context.Background())
+			m.startTime = now.Add(-tc.started)
+			node := model.Node{Id: 1, Heartbeat: true}
+			if tc.heartbeat > 0 {
+				node.HeartbeatTime = now.Add(-tc.heartbeat)
+			}
+			m.Set(1, node)
+
+			m.checkNodesMetric()
+			nm, ok := m.Get(1)
+			assert.True(t, ok)
+			assert.Equal(t, tc.wantOnline, nm.Online)
+			assert.Equal(t, tc.wantOnline, nm.OfflineTime.IsZero())
+		})
+	}
+
+	m := newNodeMetrics(context.Background())
+	assert.False(t, m.Heartbeat(1, nil))
+	m.Set(1, model.Node{Id: 1, Heartbeat: true})
+	assert.True(t, m.Heartbeat(1, &model.NodeResource{Time: 1, Running: 2}))
+	nm, _ := m.Get(1)
+	assert.True(t, nm.Online)
+	assert.Equal(t, 2, nm.Resource.Running)
+
+	// 更新节点信息后保留资源数据
+	m.Set(1, model.Node{Id: 1, Heartbeat: true, Name: "node1"})
+	history, ok := m.History(1, 0, 0)
+	assert.True(t, ok)
+	assert.Len(t, history, 1)
+	nm, _ = m.Get(1)
+	assert.Equal(t, 2, nm.Resource.Running)
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import "go-job/internal/model"
+
+// defaultHistorySize 每个节点保留的资源数据数量，按10秒一次心跳约为1小时
+const defaultHistorySize = 360
+
+// resourceHistory 节点资源使用情况的环形缓冲区，超过容量后覆盖最早的数据
+type resourceHistory struct {
+	data  []model.NodeResource
+	start int // 最早的数据的位置
+	size  int
+}
+
+func newResourceHistory(capacity int) *resourceHistory {
+	return &resourceHistory{
+		data: make([]model.NodeResource, capacity),
+	}
+}
+
+func (h *resourceHistory) push(res model.NodeResource) {
+	if len(h.data) == 0 {
+		return
+	}
+	if h.size < len(h.data) {
+		h.data[(h.start+h.size)%len(h.data)] = res
+		h.size++
+		return
+	}
+	h.data[h.start] = res
+	h.start = (h.start + 1) % len(h.data)
+}
+
+// list 按时间顺序返回采集时间在 [begin, end] 内的数据，为0时不限制
+func (h *resourceHistory) list(begin, end int64) []model.NodeResource {
+	result := make([]model.NodeResource, 0, h.size)
+	for i := 0; i < h.size; i++ {
+		res := h.data[(h.start+i)%len(h.data)]
+		if begin > 0 && res.Time < begin {
+			continue
+		}
+		if end > 0 && res.Time > end {
+			continue
+		}
+		result = append(result, res)
+	}
+	return result
+}
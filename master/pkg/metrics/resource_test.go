@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go-job/internal/model"
+	"testing"
+)
+
+func TestResourceHistory(t *testing.T) {
+	testCases := []struct {
+		name     string
+		capacity int
+		push     []int64
+		begin    int64
+		end      int64
+		want     []int64
+	}{
+		{
+			name:     "not full",
+			capacity: 3,
+			push:     []int64{1, 2},
+			want:     []int64{1, 2},
+		},
+		{
+			name:     "overwrite oldest",
+			capacity: 3,
+			push:     []int64{1, 2, 3, 4, 5},
+			want:     []int64{3, 4, 5},
+		},
+		{
+			name:     "time range",
+			capacity: 5,
+			push:     []int64{1, 2, 3, 4, 5},
+			begin:    2,
+			end:      4,
+			want:     []int64{2, 3, 4},
+		},
+		{
+			name:     "zero capacity",
+			capacity: 0,
+			push:     []int64{1},
+			want:     []int64{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newResourceHistory(tc.capacity)
+			for _, ts := range tc.push {
+				h.push(model.NodeResource{Time: ts})
+			}
+			got := make([]int64, 0)
+			for _, res := range h.list(tc.begin, tc.end) {
+				got = append(got, res.Time)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
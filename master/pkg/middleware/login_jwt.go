@@ -9,14 +9,21 @@ import (
 	"go-job/internal/pkg/consts"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 )
 
 var defaultRefreshJwtTime = time.Minute * 5
 
 type LoginJwtMWBuilder struct {
-	key       string
-	skipPaths []string // 不需要校验jwt的path
+	key          string
+	skipPaths    []string // 不需要校验jwt的path
+	skipPrefixes []string // 不需要校验jwt的path前缀
+
+	nodeSecret    func(nodeId int) string                   // 节点 token 的签名密钥
+	nodePaths     []string                                  // 节点 token 可以访问的path
+	legacyNodeKey bool                                      // 是否允许节点使用 key 生成的内部用户 token
+	nodeCert      func(nodeId int, req *http.Request) error // 校验节点请求的客户端证书
 }
 
 func NewLoginJwtMWBuilder(key string) *LoginJwtMWBuilder {
@@ -30,8 +37,41 @@ func (b *LoginJwtMWBuilder) SkipPaths(paths []string) *LoginJwtMWBuilder {
 	return b
 }
 
+// SkipPathPrefixes 匹配前缀的path不校验jwt，用于path中带参数的接口
+func (b *LoginJwtMWBuilder) SkipPathPrefixes(prefixes []string) *LoginJwtMWBuilder {
+	b.skipPrefixes = prefixes
+	return b
+}
+
+// NodeToken 允许节点使用自己的密钥生成的 token 访问 paths，其他接口返回403
+func (b *LoginJwtMWBuilder) NodeToken(secret func(nodeId int) string, paths []string) *LoginJwtMWBuilder {
+	b.nodeSecret = secret
+	b.nodePaths = paths
+	return b
+}
+
+// NodeCert 节点 token 校验通过后再校验请求的客户端证书，失败时返回401
+func (b *LoginJwtMWBuilder) NodeCert(verify func(nodeId int, req *http.Request) error) *LoginJwtMWBuilder {
+	b.nodeCert = verify
+	return b
+}
+
+// LegacyNodeKey 允许未配置凭证的旧版本节点使用 key 生成内部用户的 token，只能访问 NodeToken 中的 paths
+func (b *LoginJwtMWBuilder) LegacyNodeKey(allow bool) *LoginJwtMWBuilder {
+	b.legacyNodeKey = allow
+	return b
+}
+
 func (b *LoginJwtMWBuilder) isSkipPaths(path string) bool {
-	return slice.Contains(b.skipPaths, path)
+	if slice.Contains(b.skipPaths, path) {
+		return true
+	}
+	for _, prefix := range b.skipPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 func (b *LoginJwtMWBuilder) Builder() gin.HandlerFunc {
@@ -53,11 +93,51 @@ func (b *LoginJwtMWBuilder) Builder() gin.HandlerFunc {
 		jwtBuilder := auth.NewJwtBuilder(b.key)
 		token, err := jwtBuilder.ParseToken(uc, tokenStr)
 		if err != nil {
+			// 不是用户的 token 时按节点的 token 校验
+			if nc, ok := b.parseNodeToken(tokenStr); ok {
+				if !slice.Contains(b.nodePaths, ctx.Request.URL.Path) {
+					slog.Warn("node token access forbidden", "node id", nc.NodeId, "path", ctx.Request.URL.Path)
+					ctx.AbortWithStatus(http.StatusForbidden)
+					return
+				}
+				if b.nodeCert != nil {
+					if err = b.nodeCert(nc.NodeId, ctx.Request); err != nil {
+						slog.Error("node client cert invalid", "node id", nc.NodeId, "path", ctx.Request.URL.Path, "err", err)
+						ctx.AbortWithStatus(http.StatusUnauthorized)
+						return
+					}
+				}
+				ctx.Set("node", nc)
+				return
+			}
 			slog.Error("jwt parse token error", "err", err,
 				"token", tokenStr)
 			ctx.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
+		// 节点持有 key 时可以生成任意用户的 token，只在升级期间兼容，和节点的 token 一样只能访问节点的接口
+		if uc.Uid == model.InternalDefaultUser {
+			if !b.legacyNodeKey {
+				slog.Error("legacy node token is disabled", "path", ctx.Request.URL.Path)
+				ctx.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+			if !slice.Contains(b.nodePaths, ctx.Request.URL.Path) {
+				slog.Warn("legacy node token access forbidden", "path", ctx.Request.URL.Path, "ip", ctx.ClientIP())
+				ctx.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			// 旧版本节点没有节点id，开启 TLS 后无法通过证书校验
+			if b.nodeCert != nil {
+				if err = b.nodeCert(uc.Uid, ctx.Request); err != nil {
+					slog.Error("legacy node client cert invalid", "path", ctx.Request.URL.Path, "err", err)
+					ctx.AbortWithStatus(http.StatusUnauthorized)
+					return
+				}
+			}
+			slog.Warn("legacy node token is deprecated, configure master.node_id and master.token_secret on the node",
+				"path", ctx.Request.URL.Path, "ip", ctx.ClientIP())
+		}
 
 		// 检测token是否需要刷新
 		if uc.ExpiresAt.Sub(time.Now()) < defaultRefreshJwtTime {
@@ -81,3 +161,23 @@ func (b *LoginJwtMWBuilder) updateJwtToken(expireTime time.Duration, token *jwt.
 	}
 	return newToken, nil
 }
+
+// parseNodeToken 按 token 中的节点id查找 token 密钥后校验，节点被删除或 token 密钥重置后 token 失效
+func (b *LoginJwtMWBuilder) parseNodeToken(tokenStr string) (*model.NodeClaims, bool) {
+	if b.nodeSecret == nil {
+		return nil, false
+	}
+	unverified := &model.NodeClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenStr, unverified); err != nil || unverified.NodeId <= 0 {
+		return nil, false
+	}
+	secret := b.nodeSecret(unverified.NodeId)
+	if secret == "" {
+		return nil, false
+	}
+	nc := &model.NodeClaims{}
+	if _, err := auth.NewJwtBuilder(secret).ParseToken(nc, tokenStr); err != nil || nc.NodeId != unverified.NodeId {
+		return nil, false
+	}
+	return nc, true
+}
@@ -3,9 +3,13 @@ package middleware
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
+	"go-job/internal/pkg/paths"
 	"go-job/internal/pkg/ratelimit"
 	"go-job/master/pkg/config"
+	"go-job/master/pkg/metrics"
 	"go-job/master/pkg/middleware/ratelimit/iplimiter"
+	"go-job/master/pkg/nodecert"
+	"net/http"
 	"time"
 )
 
@@ -22,13 +26,39 @@ func NewGinMiddlewares(cmd redis.Cmdable) []gin.HandlerFunc {
 			// "/api/go-job/users/login",
 			"/api/go-job/oauth/info",
 			"/api/go-job/oauth/login",
+			// 节点自注册通过 bootstrap token 校验
+			"/api/go-job/nodes/register",
 			// "/api/go-job/oauth2/github/authurl",
 			// "/api/go-job/oauth2/github/callback",
 			// "/api/go-job/oauth2/qq/authurl",
 			// "/api/go-job/oauth2/qq/callback",
 			// "/api/go-job/users/oauth2/code",
 			// "/api/go-job/users/oauth2/bind",
-		}).Builder(),
+		}).SkipPathPrefixes([]string{
+			"/api/go-job/hooks/",
+		}).NodeToken(nodeTokenSecret, []string{
+			paths.JobRecordCreateAPI,
+			paths.JobListAPI,
+			paths.NodeHeartbeatAPI,
+			paths.NodeConnectAPI,
+			paths.NodeCertAPI,
+		}).NodeCert(nodeCert).LegacyNodeKey(config.App.Register.LegacyNodeKey).Builder(),
 		iplimiter.NewIpLimiter(redisLimiter).Builder(),
 	}
 }
+
+// nodeCert 开启 TLS 时节点需要使用 master 签发给它的证书，申请证书时节点还没有证书
+func nodeCert(nodeId int, req *http.Request) error {
+	if req.URL.Path == paths.NodeCertAPI {
+		return nil
+	}
+	return nodecert.Verify(nodeId, req)
+}
+
+// nodeTokenSecret 节点 token 的签名密钥，和 master 请求节点时签名的密钥分开，泄露其中一个不能同时伪造两个方向的请求
+func nodeTokenSecret(nodeId int) string {
+	if m, ok := metrics.GetNodeMetrics().Get(nodeId); ok {
+		return m.TokenSecret
+	}
+	return ""
+}
@@ -25,8 +25,20 @@ const (
 	OperationDescDownloadFile        = "下载代码文件"
 	OperationDescAddNode             = "新增节点"
 	OperationDescDeleteNode          = "删除节点"
+	OperationDescRegisterNode        = "节点自注册"
 	OperationDescUpdateNode          = "更新节点"
 	OperationDescNodeInstallRef      = "新增依赖包"
+	OperationDescPauseScheduler      = "暂停节点调度"
+	OperationDescResumeScheduler     = "恢复节点调度"
+	OperationDescEnterMaintenance    = "节点进入维护"
+	OperationDescExitMaintenance     = "节点退出维护"
+	OperationDescDrainNode           = "节点排空"
+	OperationDescRevokeNodeCert      = "吊销节点证书"
+	OperationDescGetNodeSecret       = "查询节点密钥"
+	OperationDescResetNodeSecret     = "重置节点密钥"
+	OperationDescResetNodeToken      = "重置节点token密钥"
+	OperationDescAddWebhook          = "新增webhook"
+	OperationDescRevokeWebhook       = "撤销webhook"
 	OperationDescAddUser             = "新增用户"
 	OperationDescDeleteUser          = "删除用户"
 	OperationDescUpdateUser          = "更新用户"
@@ -41,6 +53,15 @@ const (
 )
 
 func OperationLog(optDesc string) gin.HandlerFunc {
+	return operationLog(optDesc, true)
+}
+
+// SecretOperationLog 响应中有密钥的操作，只记录请求，不记录响应
+func SecretOperationLog(optDesc string) gin.HandlerFunc {
+	return operationLog(optDesc, false)
+}
+
+func operationLog(optDesc string, recordResponse bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var (
 			body   []byte
@@ -107,7 +128,9 @@ func OperationLog(optDesc string) gin.HandlerFunc {
 			Response:   writer.body.String(),
 		}
 
-		if strings.Contains(c.Writer.Header().Get("Pragma"), "public") ||
+		if !recordResponse {
+			optLog.Response = "响应中有密钥，不记录"
+		} else if strings.Contains(c.Writer.Header().Get("Pragma"), "public") ||
 			strings.Contains(c.Writer.Header().Get("Expires"), "0") ||
 			strings.Contains(c.Writer.Header().Get("Cache-Control"), "must-revalidate, post-check=0, pre-check=0") ||
 			strings.Contains(c.Writer.Header().Get("Content-Type"), "application/force-download") ||
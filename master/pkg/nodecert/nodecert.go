@@ -0,0 +1,135 @@
+package nodecert
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"go-job/internal/model"
+	"go-job/internal/pkg/httpClient"
+	"go-job/internal/pkg/pki"
+	"go-job/master/pkg/config"
+	"go-job/master/pkg/metrics"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultCertDays = 30
+	// renewCheckInterval 检查 master 证书是否需要续期的间隔
+	renewCheckInterval = time.Hour
+)
+
+var (
+	ErrNoClientCert = errors.New("请求没有客户端证书")
+	ErrCertNode     = errors.New("客户端证书不属于该节点")
+	ErrCertRevoked  = errors.New("客户端证书已吊销")
+)
+
+// ============= master 内置的 CA，给 master 和节点签发证书 ============= //
+
+var (
+	ca      *pki.CA
+	holder  = &pki.Holder{}
+	hosts   []string
+	certTTL time.Duration
+)
+
+// Init 加载 CA 并签发 master 的证书，之后 master 和节点之间的请求都使用 https，
+// 需要在包装 httpClient 的传输层之前调用
+func Init(cfg config.TLS) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	var err error
+	if ca, err = pki.LoadOrCreateCA(cfg.CACert, cfg.CAKey); err != nil {
+		return err
+	}
+	days := cfg.CertDays
+	if days <= 0 {
+		days = defaultCertDays
+	}
+	certTTL = time.Duration(days) * 24 * time.Hour
+	hosts = append([]string{"localhost", "127.0.0.1"}, cfg.Hosts...)
+	if err = renewMasterCert(); err != nil {
+		return err
+	}
+	httpClient.EnableTLS(pki.ClientConfig(holder, ca.Pool()))
+	return nil
+}
+
+func Enabled() bool {
+	return ca != nil
+}
+
+// ServerTLSConfig 浏览器访问 master 时没有客户端证书，只在节点的接口中校验
+func ServerTLSConfig() *tls.Config {
+	return pki.ServerConfig(holder, ca.Pool(), tls.VerifyClientCertIfGiven)
+}
+
+// CACertPEM 节点使用 CA 证书校验 master 的证书
+func CACertPEM() []byte {
+	return ca.CertPEM()
+}
+
+// Issue 使用节点的证书请求签发证书，CN 为节点id，域名为节点地址中的主机
+func Issue(node model.Node, csrPEM []byte) (*x509.Certificate, []byte, error) {
+	host, _, err := net.SplitHostPort(node.Address)
+	if err != nil {
+		host = node.Address
+	}
+	return ca.SignCSR(csrPEM, pki.NodeCommonName(node.Id), []string{host}, certTTL)
+}
+
+// Verify 校验节点请求的客户端证书属于该节点，且是 master 签发给该节点的当前证书，未开启 TLS 时不校验
+func Verify(nodeId int, req *http.Request) error {
+	if !Enabled() {
+		return nil
+	}
+	if req.TLS == nil || len(req.TLS.PeerCertificates) == 0 {
+		return ErrNoClientCert
+	}
+	cert := req.TLS.PeerCertificates[0]
+	if id, ok := pki.NodeIdFromCert(cert); !ok || id != nodeId {
+		return ErrCertNode
+	}
+	m, ok := metrics.GetNodeMetrics().Get(nodeId)
+	if !ok || m.CertSerial == "" || m.CertSerial != pki.Serial(cert) {
+		return ErrCertRevoked
+	}
+	return nil
+}
+
+// RunRenew 定期检查 master 的证书，有效期剩余不到三分之一时重新签发
+func RunRenew(ctx context.Context) {
+	if !Enabled() {
+		return
+	}
+	ticker := time.NewTicker(renewCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !holder.NeedRenew(time.Now()) {
+			continue
+		}
+		if err := renewMasterCert(); err != nil {
+			slog.Error("renew master cert error", "err", err)
+		}
+	}
+}
+
+func renewMasterCert() error {
+	cert, err := ca.IssueKeyPair(pki.MasterCommonName, hosts, certTTL)
+	if err != nil {
+		return err
+	}
+	holder.Set(cert)
+	slog.Info("master cert issued", "serial", pki.Serial(cert.Leaf), "expire", cert.Leaf.NotAfter)
+	return nil
+}
@@ -0,0 +1,46 @@
+package nodeconn
+
+import (
+	"context"
+	"go-job/internal/model"
+	"go-job/internal/pkg/httpClient"
+	"go-job/internal/pkg/tunnel"
+	"golang.org/x/net/websocket"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// keepalive 反向连接的保活间隔
+const keepalive = 15 * time.Second
+
+// ============= 节点的反向连接 ============= //
+
+var (
+	registry = tunnel.NewRegistry()
+	handler  http.Handler
+)
+
+// Init 设置处理节点通过反向连接发送的请求的 handler，
+// 之后 master 发往已连接节点的请求都通过反向连接发送，节点不需要开放端口
+func Init(h http.Handler) {
+	handler = h
+	httpClient.WrapTransport(func(base http.RoundTripper) http.RoundTripper {
+		return &tunnel.Transport{Base: base, Registry: registry}
+	})
+}
+
+// Serve 保存节点的反向连接并处理连接上的请求，直到连接断开，同一节点重复连接时关闭旧的连接
+func Serve(ctx context.Context, node model.Node, ws *websocket.Conn) {
+	conn := tunnel.NewConn(ws, handler)
+	registry.Add(node.Address, conn)
+	defer registry.Remove(node.Address, conn)
+	slog.Info("node connected", "node id", node.Id, "address", node.Address, "remote", ws.Request().RemoteAddr)
+	err := conn.Serve(ctx, keepalive)
+	slog.Warn("node disconnected", "node id", node.Id, "address", node.Address, "err", err)
+}
+
+// Connected 节点是否有反向连接
+func Connected(address string) bool {
+	return registry.Get(address) != nil
+}
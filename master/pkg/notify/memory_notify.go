@@ -22,6 +22,8 @@ type MemoryNotifyStore struct {
 	notifyMap map[int]NotifyConfig // 任务启用通知列表
 	queue     chan NotifyUnit      // 需要的队列
 	workerNum int                  // worker数量
+	closed    bool                 // 关闭后不再接收通知
+	wg        sync.WaitGroup
 
 	emailSvc ifaceEmail.IEmailService // 邮件发送服务
 }
@@ -56,6 +58,13 @@ func (m *MemoryNotifyStore) Delete(ctx context.Context, jobId int) error {
 // PushNotifyUnit 推送通知单元到队列中
 func (m *MemoryNotifyStore) PushNotifyUnit(ctx context.Context, jobId int, unit NotifyUnit) error {
 	if m.needNotify(unit) {
+		// 持有读锁时 Close 不能关闭队列
+		m.mux.RLock()
+		defer m.mux.RUnlock()
+		if m.closed {
+			slog.Warn("memory notify store is closed", "jobId", jobId)
+			return nil
+		}
 		select {
 		case m.queue <- unit:
 		default:
@@ -67,15 +76,40 @@ func (m *MemoryNotifyStore) PushNotifyUnit(ctx context.Context, jobId int, unit
 	return nil
 }
 
+// Close 关闭队列，等待 worker 发送完队列中剩余的通知，ctx 结束时返回 ctx 的错误
+func (m *MemoryNotifyStore) Close(ctx context.Context) error {
+	m.mux.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.queue)
+	}
+	m.mux.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		slog.Error("memory notify queue not flushed", "remain", len(m.queue))
+		return ctx.Err()
+	}
+}
+
 // startWorker 启动worker
 func (m *MemoryNotifyStore) startWorker() {
+	m.wg.Add(m.workerNum)
 	for i := 0; i < m.workerNum; i++ {
 		go m.worker()
 	}
 }
 
-// worker 处理队列中的通知单元
+// worker 处理队列中的通知单元，队列关闭后发送完剩余的通知再退出
 func (m *MemoryNotifyStore) worker() {
+	defer m.wg.Done()
 	for unit := range m.queue {
 		if err := m.dispatch(unit); err != nil {
 			slog.Error("notification failed", "jobId", unit.JobID, "err", err)
@@ -89,7 +123,7 @@ func (m *MemoryNotifyStore) needNotify(unit NotifyUnit) bool {
 	case model.NotifyAfterSuccess:
 		return unit.Status == model.Success
 	case model.NotifyAfterFailed:
-		return unit.Status == model.Failed
+		return unit.Status == model.Failed || unit.Status == model.Dropped
 	case model.NotifyAlways:
 		return true
 	default:
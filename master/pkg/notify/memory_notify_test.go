@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go-job/internal/model"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowEmail 每次发送耗时 delay，记录收件人
+type slowEmail struct {
+	delay time.Duration
+	mux   sync.Mutex
+	sent  []string
+}
+
+func (e *slowEmail) Send(ctx context.Context, email []string, subject, content string) error {
+	time.Sleep(e.delay)
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.sent = append(e.sent, email...)
+	return nil
+}
+
+func (e *slowEmail) Name() string { return "slow" }
+
+func (e *slowEmail) count() int {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	return len(e.sent)
+}
+
+func TestMemoryNotifyStoreClose(t *testing.T) {
+	testCases := []struct {
+		name      string
+		delay     time.Duration
+		timeout   time.Duration
+		wantErr   error
+		wantCount int
+	}{
+		{name: "flush queue", delay: 10 * time.Millisecond, timeout: time.Second, wantCount: 10},
+		{name: "timeout", delay: time.Second, timeout: 20 * time.Millisecond, wantErr: context.DeadlineExceeded},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := &slowEmail{delay: tc.delay}
+			store := newMemoryNotifyStore(2, svc).(*MemoryNotifyStore)
+			store.startWorker()
+			unit := NotifyUnit{NotifyConfig: NotifyConfig{
+				NotifyStrategy: model.NotifyAlways,
+				NotifyType:     model.NotifyTypeEmail,
+				NotifyMark:     "a@example.com",
+			}}
+			for i := 0; i < 10; i++ {
+				assert.NoError(t, store.PushNotifyUnit(context.Background(), 1, unit))
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+			defer cancel()
+			assert.ErrorIs(t, store.Close(ctx), tc.wantErr)
+			assert.Equal(t, tc.wantCount, svc.count())
+
+			// 关闭后的通知直接丢弃
+			assert.NoError(t, store.PushNotifyUnit(context.Background(), 1, unit))
+		})
+	}
+}
@@ -20,6 +20,7 @@ type INotifyStore interface {
 	Delete(ctx context.Context, jobId int) error
 
 	PushNotifyUnit(ctx context.Context, jobId int, unit NotifyUnit) error
+	Close(ctx context.Context) error // 停止接收通知，发送队列中剩余的通知
 }
 
 type NotifyUnit struct {
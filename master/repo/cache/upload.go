@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"go-job/internal/upload"
+	"log/slog"
+	"time"
+)
+
+const (
+	defaultFileMetaPrefix = "upload_file:"
+	// defaultFileMetaTTL 上传后超过该时间没有创建任务，文件信息过期
+	defaultFileMetaTTL = time.Hour
+)
+
+// FileMetaCache 上传文件的信息存储在 redis 中，上传和创建任务可以由不同的 master 处理
+type FileMetaCache struct {
+	redisCache redis.Cmdable
+}
+
+func (c *FileMetaCache) Set(uuid string, file upload.FileMeta) error {
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	return c.redisCache.Set(context.Background(), c.key(uuid), data, defaultFileMetaTTL).Err()
+}
+
+func (c *FileMetaCache) Get(uuid string) (upload.FileMeta, bool) {
+	var file upload.FileMeta
+	data, err := c.redisCache.Get(context.Background(), c.key(uuid)).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			slog.Error("get file meta error", "uuid", uuid, "err", err)
+		}
+		return file, false
+	}
+	if err = json.Unmarshal(data, &file); err != nil {
+		slog.Error("unmarshal file meta error", "uuid", uuid, "err", err)
+		return file, false
+	}
+	return file, true
+}
+
+func (c *FileMetaCache) Delete(uuid string) error {
+	return c.redisCache.Del(context.Background(), c.key(uuid)).Err()
+}
+
+func (c *FileMetaCache) key(uuid string) string {
+	return defaultFileMetaPrefix + uuid
+}
+
+func NewFileMetaCache(cmd redis.Cmdable) upload.MetaStore {
+	return &FileMetaCache{
+		redisCache: cmd,
+	}
+}
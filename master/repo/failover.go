@@ -0,0 +1,32 @@
+package repo
+
+import (
+	"go-job/internal/model"
+	"go-job/internal/pkg/paginate"
+	"gorm.io/gorm"
+)
+
+type IFailoverRepo interface {
+	Insert(*model.JobFailoverEvent) error
+	QueryList(page model.Page, jobId int) (model.Page, error)
+}
+
+type FailoverRepo struct {
+	mysqlDB *gorm.DB
+}
+
+func (f *FailoverRepo) Insert(event *model.JobFailoverEvent) error {
+	return f.mysqlDB.Create(event).Error
+}
+
+func (f *FailoverRepo) QueryList(page model.Page, jobId int) (model.Page, error) {
+	return paginate.PaginateListV2[model.JobFailoverEvent](f.mysqlDB, page, func(db *gorm.DB) *gorm.DB {
+		return db.Where("job_id = ?", jobId)
+	})
+}
+
+func NewFailoverRepo(mysqlDB *gorm.DB) IFailoverRepo {
+	return &FailoverRepo{
+		mysqlDB: mysqlDB,
+	}
+}
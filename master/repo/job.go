@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"encoding/json"
 	"go-job/internal/model"
 	"go-job/internal/pkg/paginate"
 	"gorm.io/gorm"
@@ -10,15 +11,31 @@ type IJobRepo interface {
 	QueryById(id int) (model.Job, error)
 	QueryByIds(id []int) ([]model.Job, error)
 	QueryByNodeId(nodeId int) ([]model.Job, error)
+	QueryAll() ([]model.Job, error)
 	Insert(*model.Job) error
 	Inserts([]model.Job) error
 	Update(*model.Job) error
 	Delete(id int) error
 	QueryListByUID(uid int, page model.Page) (model.Page, error)
 	QueryListByActive(uid int, page model.Page, active model.JobActiveType) (model.Page, error)
+	QueryListByNode(node model.Node, page model.Page, active *model.JobActiveType) (model.Page, error)
 	QuerySummary(uid int) ([]model.JobStatusCount, error)
 }
 
+// 应该在节点上的任务，与 service 中的 jobOnNode 一致：设置了节点选择器(分组或标签)时按选择器匹配节点，否则按 node_id
+const (
+	jobTargetGroup  = "COALESCE(JSON_UNQUOTE(JSON_EXTRACT(internal, '$.target.group')), '')"
+	jobTargetLabels = "JSON_EXTRACT(internal, '$.target.labels')"
+	// 选择器的标签是不为空的对象，没有设置时为 NULL 或 JSON 的 null
+	jobHasTargetLabels = "COALESCE(JSON_TYPE(" + jobTargetLabels + ") = 'OBJECT' AND JSON_LENGTH(" + jobTargetLabels + ") > 0, FALSE)"
+	jobHasTarget       = "(" + jobTargetGroup + " <> '' OR " + jobHasTargetLabels + ")"
+	// 分组为空或和节点相同，节点的标签包含选择器的所有标签，参数为节点的分组和标签
+	jobTargetMatch = "(" + jobTargetGroup + " IN ('', ?) AND (NOT " + jobHasTargetLabels +
+		" OR JSON_CONTAINS(CAST(? AS JSON), " + jobTargetLabels + ")))"
+	// 参数为节点的分组、标签和id
+	jobOnNodeCond = "((" + jobHasTarget + " AND " + jobTargetMatch + ") OR (NOT " + jobHasTarget + " AND node_id = ?))"
+)
+
 type JobRepo struct {
 	mysqlDB *gorm.DB
 }
@@ -50,7 +67,14 @@ func (j *JobRepo) Update(job *model.Job) error {
 	if job.Id == 0 {
 		return ErrorIDIsZero
 	}
-	return j.mysqlDB.Updates(job).Error
+	if err := j.mysqlDB.Updates(job).Error; err != nil {
+		return err
+	}
+	// 使用节点选择器的任务不固定节点，零值需要单独更新
+	if job.NodeID == 0 {
+		return j.mysqlDB.Model(job).Update("node_id", 0).Error
+	}
+	return nil
 }
 
 func (j *JobRepo) Delete(id int) error {
@@ -62,6 +86,12 @@ func (j *JobRepo) Delete(id int) error {
 		if res.RowsAffected == 0 {
 			return nil
 		}
+		if err := tx.Where("job_id = ?", id).Delete(&model.JobWebhook{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("job_id = ?", id).Delete(&model.JobFailoverEvent{}).Error; err != nil {
+			return err
+		}
 		return tx.Where("job_id = ?", id).Delete(&model.JobRecord{}).Error
 	})
 }
@@ -72,6 +102,12 @@ func (j *JobRepo) QueryByNodeId(nodeId int) ([]model.Job, error) {
 	return jobs, err
 }
 
+func (j *JobRepo) QueryAll() ([]model.Job, error) {
+	var jobs []model.Job
+	err := j.mysqlDB.Find(&jobs).Error
+	return jobs, err
+}
+
 func (j *JobRepo) QueryListByUID(uid int, page model.Page) (model.Page, error) {
 	return paginate.PaginateListV2[model.Job](j.mysqlDB, page, func(db *gorm.DB) *gorm.DB {
 		return db.Where("user_id = ?", uid)
@@ -90,6 +126,25 @@ func (j *JobRepo) QueryListByActive(uid int, page model.Page, active model.JobAc
 	}
 }
 
+// QueryListByNode 分页查询应该在节点上的任务，在查询中按节点过滤后再分页，active 为空时不限制启用状态
+func (j *JobRepo) QueryListByNode(node model.Node, page model.Page, active *model.JobActiveType) (model.Page, error) {
+	labels, err := json.Marshal(node.Labels)
+	if err != nil {
+		return page, err
+	}
+	// 节点分页同步任务，需要固定的顺序
+	if page.Sort == "" {
+		page.Sort = "id"
+	}
+	return paginate.PaginateListV2[model.Job](j.mysqlDB, page, func(db *gorm.DB) *gorm.DB {
+		db = db.Where(jobOnNodeCond, node.Group, string(labels), node.Id)
+		if active != nil {
+			db = db.Where("active = ?", *active)
+		}
+		return db
+	})
+}
+
 func (j *JobRepo) QuerySummary(uid int) ([]model.JobStatusCount, error) {
 	var data []model.JobStatusCount
 	err := j.mysqlDB.Model(&model.Job{}).Select("active, COUNT(*) as count").
@@ -9,13 +9,19 @@ import (
 
 type IJobRecordRepo interface {
 	QueryById(id int) (model.JobRecord, error)
+	QueryByExecutionId(executionId string) (model.JobRecord, error)
 	Inserts([]model.JobRecord) error
 	Insert(*model.JobRecord) error
+	UpdateRun(*model.JobRecord) error
+	FinishParent(*model.JobRecord) (bool, error)
+	QueryByParentId(parentId int) ([]model.JobRecord, error)
+	QueryStalePending(before time.Time, limit int) ([]model.JobRecord, error)
 	Delete(id int) error
 	QueryList(page model.Page, jobId int) (model.Page, error)
 	QueryLastListByUid(page model.Page, uid int) (model.Page, error)
 	QueryDayStatusByUid(begin, end time.Time, uid int) ([]model.JobRecordDayStatusCount, error)
 	QueryJobStatusByUid(begin, end time.Time, uid int) ([]model.JobRecordJobStatusCount, error)
+	QueryLastNextExecTime(jobIds []int) ([]model.JobLastNextExecTime, error)
 }
 
 type JobRecordRepo struct {
@@ -28,6 +34,13 @@ func (j *JobRecordRepo) QueryById(id int) (model.JobRecord, error) {
 	return job, err
 }
 
+// QueryByExecutionId 按节点上报的执行id查询，用于判断结果是否重复上报
+func (j *JobRecordRepo) QueryByExecutionId(executionId string) (model.JobRecord, error) {
+	var job model.JobRecord
+	err := j.mysqlDB.Where("execution_id = ?", executionId).First(&job).Error
+	return job, err
+}
+
 func (j *JobRecordRepo) Inserts(jobs []model.JobRecord) error {
 	if len(jobs) == 0 {
 		return nil
@@ -39,8 +52,63 @@ func (j *JobRecordRepo) Insert(job *model.JobRecord) error {
 	return j.mysqlDB.Create(job).Error
 }
 
+// UpdateRun 节点执行结束后更新 master 预先创建的执行记录
+func (j *JobRecordRepo) UpdateRun(record *model.JobRecord) error {
+	if record.Id == 0 {
+		return ErrorIDIsZero
+	}
+	// 父记录由 master 创建时设置，节点上报的结果中没有
+	omits := []string{"id", "parent_id"}
+	// 旧版本节点不会上报执行节点，保留创建记录时选择的节点
+	if record.NodeId == 0 {
+		omits = append(omits, "node_id")
+	}
+	db := j.mysqlDB.Model(&model.JobRecord{}).Where("id = ? AND job_id = ?", record.Id, record.JobId)
+	if record.ExecutionId == "" {
+		omits = append(omits, "execution_id")
+	} else {
+		// 已经保存过执行结果的记录不再更新，重复上报时不影响任何行
+		db = db.Where("execution_id IS NULL")
+	}
+	result := db.Select("*").Omit(omits...).Updates(record)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FinishParent 子记录全部结束后更新父记录，父记录已经结束时返回 false，避免并发回调重复汇总
+func (j *JobRecordRepo) FinishParent(record *model.JobRecord) (bool, error) {
+	result := j.mysqlDB.Model(&model.JobRecord{}).
+		Where("id = ? AND status = ?", record.Id, model.Pending).
+		Select("status", "end_time", "duration", "output", "error").Updates(record)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (j *JobRecordRepo) QueryByParentId(parentId int) ([]model.JobRecord, error) {
+	var records []model.JobRecord
+	err := j.mysqlDB.Where("parent_id = ?", parentId).Find(&records).Error
+	return records, err
+}
+
+// QueryStalePending 开始时间早于 before 还没有结果的记录，有子记录的父记录由子记录汇总，不包括在内
+func (j *JobRecordRepo) QueryStalePending(before time.Time, limit int) ([]model.JobRecord, error) {
+	var records []model.JobRecord
+	err := j.mysqlDB.Where("status = ? AND start_time < ?", model.Pending, before).
+		Where("NOT EXISTS (SELECT 1 FROM job_record c WHERE c.parent_id = job_record.id)").
+		Order("id").Limit(limit).Find(&records).Error
+	return records, err
+}
+
 func (j *JobRecordRepo) Delete(id int) error {
-	return j.mysqlDB.Where("id = ?", id).Delete(&model.JobRecord{}).Error
+	// 删除父记录时一起删除子记录
+	return j.mysqlDB.Where("id = ? OR parent_id = ?", id, id).Delete(&model.JobRecord{}).Error
 }
 
 func (j *JobRecordRepo) QueryList(page model.Page, jobId int) (model.Page, error) {
@@ -54,7 +122,8 @@ func (j *JobRecordRepo) QueryDayStatusByUid(being, end time.Time, uid int) ([]mo
 	err := j.mysqlDB.Table("job_record AS t1").
 		Select("DATE(start_time) AS date, status, COUNT(*) as count").
 		Joins("JOIN job t2 ON t1.job_id = t2.id AND t2.user_id = ?", uid).
-		Where("start_time >= ? AND end_time <= ?", being, end).
+		// 广播和分片执行只统计父记录
+		Where("start_time >= ? AND end_time <= ? AND t1.parent_id = 0", being, end).
 		Group("date, status").
 		Order("date, status").
 		Find(&jobs).Error
@@ -69,7 +138,8 @@ func (j *JobRecordRepo) QueryJobStatusByUid(being, end time.Time, uid int) ([]mo
 	err := j.mysqlDB.Table("job_record AS t1").
 		Select("job_id, status, COUNT(*) as count").
 		Joins("JOIN job t2 ON t1.job_id = t2.id AND t2.user_id = ?", uid).
-		Where("start_time >= ? AND end_time <= ?", being, end).
+		// 广播和分片执行只统计父记录
+		Where("start_time >= ? AND end_time <= ? AND t1.parent_id = 0", being, end).
 		Group("job_id, status").
 		Order("job_id, status").
 		Find(&jobs).Error
@@ -84,8 +154,9 @@ func (j *JobRecordRepo) QueryLastListByUid(page model.Page, uid int) (model.Page
 	err := j.mysqlDB.Table("job_record AS r").
 		Select("r.id, j.id AS job_id, j.name AS job_name, n.id AS node_id, n.name AS node_name, r.start_time, r.end_time, r.status").
 		Joins("JOIN job j ON r.job_id = j.id").
-		Joins("JOIN node n ON n.id = j.node_id").
-		Where("j.user_id = ?", uid).
+		// 记录中没有执行节点时使用任务固定的节点
+		Joins("LEFT JOIN node n ON n.id = IF(r.node_id > 0, r.node_id, j.node_id)").
+		Where("j.user_id = ? AND r.parent_id = 0", uid).
 		Order("r.start_time DESC, r.id DESC").
 		Limit(page.PageSize).
 		Scan(&jobs).Error
@@ -97,6 +168,20 @@ func (j *JobRecordRepo) QueryLastListByUid(page model.Page, uid int) (model.Page
 	return page, nil
 }
 
+// QueryLastNextExecTime 查询每个job最近记录的下一次执行时间
+func (j *JobRecordRepo) QueryLastNextExecTime(jobIds []int) ([]model.JobLastNextExecTime, error) {
+	var times []model.JobLastNextExecTime
+	if len(jobIds) == 0 {
+		return times, nil
+	}
+	err := j.mysqlDB.Table("job_record").
+		Select("job_id, MAX(next_exec_time) AS next_exec_time").
+		Where("job_id IN ?", jobIds).
+		Group("job_id").
+		Find(&times).Error
+	return times, err
+}
+
 func NewJobRecordRepo(mysqlDB *gorm.DB) IJobRecordRepo {
 	return &JobRecordRepo{
 		mysqlDB: mysqlDB,
@@ -9,9 +9,16 @@ import (
 type INodeRepo interface {
 	QueryById(id int) (model.Node, error)
 	QueryByIds(ids []int) ([]model.Node, error)
+	QueryAll() ([]model.Node, error)
+	QueryByName(name string) (model.Node, error)
 	Inserts([]model.Node) error
 	Insert(*model.Node) error
 	Update(model.Node) error
+	UpdateMaintenance(id int, maintenance bool) error
+	UpdateRegister(model.Node) error
+	UpdateSecret(id int, secret string) error
+	UpdateTokenSecret(id int, secret string) error
+	UpdateCert(id int, serial string, expireTime int64) error
 	Delete(id int) error
 	QueryList(page model.Page) (model.Page, error)
 }
@@ -32,6 +39,18 @@ func (j *NodeRepo) QueryByIds(ids []int) ([]model.Node, error) {
 	return nodes, err
 }
 
+func (j *NodeRepo) QueryAll() ([]model.Node, error) {
+	var nodes []model.Node
+	err := j.mysqlDB.Find(&nodes).Error
+	return nodes, err
+}
+
+func (j *NodeRepo) QueryByName(name string) (model.Node, error) {
+	var node model.Node
+	err := j.mysqlDB.Where("name = ?", name).First(&node).Error
+	return node, err
+}
+
 func (j *NodeRepo) Inserts(nodes []model.Node) error {
 	if len(nodes) == 0 {
 		return nil
@@ -47,7 +66,49 @@ func (j *NodeRepo) Update(node model.Node) error {
 	if node.Id == 0 {
 		return ErrorIDIsZero
 	}
-	return j.mysqlDB.Updates(&node).Error
+	// 只更新页面上可以编辑的字段，维护状态、自注册上报的信息、密钥、证书和添加节点的用户都有单独的更新方法，
+	// 新增的字段默认不会被覆盖，分组和标签允许清空，需要更新零值
+	return j.mysqlDB.Select("name", "description", "address", "group_name", "labels", "updated_time").
+		Updates(&node).Error
+}
+
+// UpdateRegister 节点重新注册时更新上报的信息
+func (j *NodeRepo) UpdateRegister(node model.Node) error {
+	if node.Id == 0 {
+		return ErrorIDIsZero
+	}
+	return j.mysqlDB.Select("address", "hostname", "version", "capabilities", "heartbeat",
+		"group_name", "labels", "secret", "token_secret", "updated_time").Updates(&node).Error
+}
+
+func (j *NodeRepo) UpdateSecret(id int, secret string) error {
+	if id == 0 {
+		return ErrorIDIsZero
+	}
+	return j.mysqlDB.Model(&model.Node{}).Where("id = ?", id).Update("secret", secret).Error
+}
+
+func (j *NodeRepo) UpdateTokenSecret(id int, secret string) error {
+	if id == 0 {
+		return ErrorIDIsZero
+	}
+	return j.mysqlDB.Model(&model.Node{}).Where("id = ?", id).Update("token_secret", secret).Error
+}
+
+// UpdateCert 更新节点当前的证书，serial 为空时吊销节点的证书
+func (j *NodeRepo) UpdateCert(id int, serial string, expireTime int64) error {
+	if id == 0 {
+		return ErrorIDIsZero
+	}
+	return j.mysqlDB.Model(&model.Node{}).Where("id = ?", id).
+		Updates(map[string]any{"cert_serial": serial, "cert_expire_time": expireTime}).Error
+}
+
+func (j *NodeRepo) UpdateMaintenance(id int, maintenance bool) error {
+	if id == 0 {
+		return ErrorIDIsZero
+	}
+	return j.mysqlDB.Model(&model.Node{}).Where("id = ?", id).Update("maintenance", maintenance).Error
 }
 
 func (j *NodeRepo) Delete(id int) error {
@@ -0,0 +1,53 @@
+package repo
+
+import (
+	"go-job/internal/model"
+	"gorm.io/gorm"
+)
+
+type IWebhookRepo interface {
+	QueryById(id int) (model.JobWebhook, error)
+	QueryByToken(token string) (model.JobWebhook, error)
+	QueryByJobId(jobId int) ([]model.JobWebhook, error)
+	Insert(*model.JobWebhook) error
+	UpdateStatus(id int, status model.WebhookStatus) error
+}
+
+type WebhookRepo struct {
+	mysqlDB *gorm.DB
+}
+
+func (w *WebhookRepo) QueryById(id int) (model.JobWebhook, error) {
+	var webhook model.JobWebhook
+	err := w.mysqlDB.First(&webhook, id).Error
+	return webhook, err
+}
+
+func (w *WebhookRepo) QueryByToken(token string) (model.JobWebhook, error) {
+	var webhook model.JobWebhook
+	err := w.mysqlDB.Where("token = ?", token).First(&webhook).Error
+	return webhook, err
+}
+
+func (w *WebhookRepo) QueryByJobId(jobId int) ([]model.JobWebhook, error) {
+	var webhooks []model.JobWebhook
+	err := w.mysqlDB.Where("job_id = ?", jobId).Order("id DESC").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (w *WebhookRepo) Insert(webhook *model.JobWebhook) error {
+	return w.mysqlDB.Create(webhook).Error
+}
+
+func (w *WebhookRepo) UpdateStatus(id int, status model.WebhookStatus) error {
+	if id == 0 {
+		return ErrorIDIsZero
+	}
+	return w.mysqlDB.Model(&model.JobWebhook{}).Where("id = ?", id).Update("status", status).Error
+}
+
+func NewWebhookRepo(mysqlDB *gorm.DB) IWebhookRepo {
+	return &WebhookRepo{
+		mysqlDB: mysqlDB,
+	}
+}
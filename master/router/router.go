@@ -12,7 +12,10 @@ func NewWebRouter(mdls []gin.HandlerFunc,
 	userApi *api.UserApi,
 	dashboardApi *api.DashboardApi,
 	iamOAuthApi *api.IAMOAuthApi,
-	oauth2Api *api.OAuth2Api) *gin.Engine {
+	oauth2Api *api.OAuth2Api,
+	webhookApi *api.WebhookApi,
+	failoverApi *api.FailoverApi,
+	reconcileApi *api.ReconcileApi) *gin.Engine {
 	server := gin.Default()
 	server.Use(mdls...)
 	group := server.Group("/api/go-job")
@@ -22,6 +25,9 @@ func NewWebRouter(mdls []gin.HandlerFunc,
 	userApi.RegisterRoutes(group)
 	dashboardApi.RegisterRoutes(group)
 	iamOAuthApi.RegisterRoutes(group)
+	webhookApi.RegisterRoutes(group)
+	failoverApi.RegisterRoutes(group)
+	reconcileApi.RegisterRoutes(group)
 	// oauth2Api.RegisterRoutes(group)
 	return server
 }
@@ -7,6 +7,7 @@ import (
 	"go-job/internal/pkg/utils"
 	"go-job/master/pkg/metrics"
 	"go-job/master/repo"
+	"math"
 	"time"
 )
 
@@ -26,22 +27,28 @@ type DashboardService struct {
 
 func (s *DashboardService) GetDataSummary(uid int) (dto.RespDashboardTotalData, error) {
 	var (
-		data      dto.RespDashboardTotalData
-		onlineCnt int
+		data           dto.RespDashboardTotalData
+		onlineCnt      int
+		maintenanceCnt int
 	)
 
 	// 获取节点数据
 	nodes := metrics.GetNodeMetrics().All()
 	totalNode := len(nodes)
 	for _, node := range nodes {
-		if node.Online {
+		switch {
+		case !node.Online:
+		case node.Maintenance:
+			maintenanceCnt++
+		default:
 			onlineCnt++
 		}
 	}
 	data.Node = dto.NodeDashboard{
-		Total:   totalNode,
-		Online:  onlineCnt,
-		Offline: totalNode - onlineCnt,
+		Total:       totalNode,
+		Online:      onlineCnt,
+		Offline:     totalNode - onlineCnt - maintenanceCnt,
+		Maintenance: maintenanceCnt,
 	}
 
 	// 查询任务数量
@@ -101,17 +108,17 @@ func (s *DashboardService) GetChartData(req model.ReqDashboardChart, uid int) (m
 		}
 
 		for _, name := range jobMap {
-			result[name] = []int{0, 0, 0, 0}
+			result[name] = make([]int, model.JobStatusNum)
 		}
 		for _, item := range data {
-			if name, ok := jobMap[item.JobId]; ok {
+			if name, ok := jobMap[item.JobId]; ok && int(item.Status) < model.JobStatusNum {
 				result[name][item.Status] = item.Count
 			}
 		}
 
 	case model.DashboardKeyDayStatus:
 		for beginTime.Before(endTime) {
-			result[beginTime.Format(time.DateOnly)] = []int{0, 0, 0, 0}
+			result[beginTime.Format(time.DateOnly)] = make([]int, model.JobStatusNum)
 			beginTime = beginTime.AddDate(0, 0, 1)
 		}
 		data, err := s.JobRecordRepo.QueryDayStatusByUid(
@@ -122,7 +129,25 @@ func (s *DashboardService) GetChartData(req model.ReqDashboardChart, uid int) (m
 			return result, err
 		}
 		for _, item := range data {
-			result[item.Date.Format(time.DateOnly)][item.Status] = item.Count
+			if counts, ok := result[item.Date.Format(time.DateOnly)]; ok && int(item.Status) < model.JobStatusNum {
+				counts[item.Status] = item.Count
+			}
+		}
+
+	case model.DashboardKeyNodeLoad:
+		// 每个节点依次为 CPU、内存、磁盘的使用率，正在执行和排队中的数量
+		for _, node := range metrics.GetNodeMetrics().Nodes() {
+			res := node.Resource
+			if res == nil {
+				continue
+			}
+			result[node.Name] = []int{
+				int(math.Round(res.CPUPercent)),
+				int(math.Round(res.MemPercent())),
+				int(math.Round(res.DiskPercent())),
+				res.Running,
+				res.Queued,
+			}
 		}
 
 	default:
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go-job/internal/dto"
+	"go-job/internal/model"
+	"go-job/internal/pkg/cronx"
+	"go-job/master/pkg/cluster"
+	"go-job/master/pkg/config"
+	"go-job/master/pkg/metrics"
+	"go-job/master/repo"
+	"log/slog"
+	"time"
+)
+
+// master 触发的执行等待结果的默认时间，和检查超时的间隔
+const (
+	defaultRunTimeout    = 24 * time.Hour
+	runTimeoutInterval   = time.Minute
+	runTimeoutBatchLimit = 100
+)
+
+// IDispatchService master 调度模式下，到达执行时间后创建执行记录并下发到节点
+type IDispatchService interface {
+	Dispatch(jobId int, scheduledTime, nextExecTime time.Time)
+	Run(ctx context.Context)
+}
+
+type DispatchService struct {
+	jobRepo       repo.IJobRepo
+	jobRecordRepo repo.IJobRecordRepo
+	jobSvc        IJobService
+	jobRecordSvc  IJobRecordService
+}
+
+func (s *DispatchService) Dispatch(jobId int, scheduledTime, nextExecTime time.Time) {
+	// 多个 master 时每个 master 都维护调度，只由 leader 下发
+	if !cluster.IsLeader() {
+		return
+	}
+	job, err := s.jobRepo.QueryById(jobId)
+	if err != nil {
+		slog.Error("dispatch query job error", "job id", jobId, "err", err)
+		return
+	}
+	if job.Active != model.JobStart {
+		return
+	}
+
+	// 随机延迟也由 master 统一处理
+	jitter := cronx.JitterDelay(job.Id,
+		time.Duration(job.Internal.Jitter.MaxSeconds)*time.Second, job.Internal.Jitter.Hash)
+	time.Sleep(jitter)
+
+	// 执行前先选择节点并创建记录，记录id即本次执行的id
+	// 广播和分片的任务不选择节点，该记录作为父记录，下发时为每个节点创建子记录
+	var (
+		node    model.Node
+		pickErr error
+	)
+	if !job.Internal.Target.MultiNode() {
+		node, pickErr = s.jobSvc.PickNode(job)
+	}
+	now := time.Now()
+	record := model.JobRecord{
+		JobId:         job.Id,
+		Status:        model.Pending,
+		StartTime:     now,
+		EndTime:       now,
+		NextExecTime:  nextExecTime,
+		TriggerType:   model.TriggerCron,
+		ScheduledTime: scheduledTime,
+		JitterDelay:   jitter.Seconds(),
+		NodeId:        node.Id,
+	}
+	if err = s.jobRecordRepo.Insert(&record); err != nil {
+		slog.Error("dispatch create run error", "job id", jobId, "err", err)
+		return
+	}
+
+	result := model.JobExecResult{
+		StartTime:     now.Unix(),
+		EndTime:       now.Unix(),
+		TriggerType:   model.TriggerCron,
+		ScheduledTime: scheduledTime.Unix(),
+		JitterDelay:   jitter.Seconds(),
+		RunId:         record.Id,
+	}
+	if pickErr != nil {
+		result.Status = model.Failed
+		result.Error = "选择执行节点失败：" + pickErr.Error()
+		s.finishRun(job, result, nextExecTime)
+		return
+	}
+	// 节点维护中的执行直接记录为跳过，不再下发
+	if m, ok := metrics.GetNodeMetrics().Get(node.Id); ok && m.Maintenance {
+		result.Status = model.Skipped
+		result.Error = "节点维护中，跳过执行"
+		s.finishRun(job, result, nextExecTime)
+		return
+	}
+
+	err = s.jobRecordSvc.TriggerJob(job, dto.ReqNodeJobTrigger{
+		TriggerType:   model.TriggerCron,
+		RunId:         record.Id,
+		ScheduledTime: scheduledTime.Unix(),
+		NodeId:        node.Id,
+	})
+	if err != nil {
+		// 下发失败同样记录为执行失败，节点宕机时不会静默错过
+		result.Status = model.Failed
+		result.Error = "下发到节点失败：" + err.Error()
+		s.finishRun(job, result, nextExecTime)
+		return
+	}
+	slog.Info("dispatch job to node", "job id", job.Id, "node id", node.Id, "run id", record.Id)
+}
+
+// finishRun 未下发到节点的执行由 master 更新记录，同样会触发通知和处理任务
+// result 中不设置执行节点，记录中保留创建时选择的节点，也不会释放节点的执行数
+func (s *DispatchService) finishRun(job model.Job, result model.JobExecResult, nextExecTime time.Time) {
+	err := s.jobRecordSvc.AddJobRecord(model.CallbackJobResult{
+		JobExecResult: result,
+		JobID:         job.Id,
+		NextExecTime:  nextExecTime.Unix(),
+	})
+	if err != nil {
+		slog.Error("dispatch finish run error", "job id", job.Id, "run id", result.RunId, "err", err)
+	}
+}
+
+// Run 定期将超时没有结果的执行记录为失败
+func (s *DispatchService) Run(ctx context.Context) {
+	ticker := time.NewTicker(runTimeoutInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.expireRuns(time.Now())
+		}
+	}
+}
+
+// expireRuns master 触发的执行超过 run_timeout 还没有结果时记录为失败，广播和分片的子记录超时后父记录随之汇总，
+// 不设置执行节点，超时后节点再上报的结果仍会覆盖该记录
+func (s *DispatchService) expireRuns(now time.Time) {
+	// 多个 master 时只由 leader 检查
+	if !cluster.IsLeader() {
+		return
+	}
+	timeout := time.Duration(config.App.Scheduler.RunTimeout) * time.Second
+	if timeout < 0 {
+		return
+	}
+	if timeout == 0 {
+		timeout = defaultRunTimeout
+	}
+	records, err := s.jobRecordRepo.QueryStalePending(now.Add(-timeout), runTimeoutBatchLimit)
+	if err != nil {
+		slog.Error("query timeout runs error", "err", err)
+		return
+	}
+	for _, record := range records {
+		err = s.jobRecordSvc.AddJobRecord(model.CallbackJobResult{
+			JobExecResult: model.JobExecResult{
+				StartTime:     record.StartTime.Unix(),
+				EndTime:       now.Unix(),
+				Duration:      now.Sub(record.StartTime).Seconds(),
+				Status:        model.Failed,
+				Error:         fmt.Sprintf("超过 %s 没有上报执行结果", timeout),
+				TriggerType:   record.TriggerType,
+				ScheduledTime: record.ScheduledTime.Unix(),
+				JitterDelay:   record.JitterDelay,
+				RunId:         record.Id,
+			},
+			JobID:        record.JobId,
+			NextExecTime: record.NextExecTime.Unix(),
+		})
+		if err != nil {
+			slog.Error("expire run error", "job id", record.JobId, "run id", record.Id, "err", err)
+			continue
+		}
+		slog.Warn("run timeout, recorded as failed", "job id", record.JobId, "run id", record.Id, "node id", record.NodeId)
+	}
+}
+
+func NewDispatchService(jobRepo repo.IJobRepo, jobRecordRepo repo.IJobRecordRepo,
+	jobSvc IJobService, jobRecordSvc IJobRecordService) IDispatchService {
+	return &DispatchService{
+		jobRepo:       jobRepo,
+		jobRecordRepo: jobRecordRepo,
+		jobSvc:        jobSvc,
+		jobRecordSvc:  jobRecordSvc,
+	}
+}
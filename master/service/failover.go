@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go-job/internal/dto"
+	"go-job/internal/model"
+	"go-job/master/pkg/balancer"
+	"go-job/master/pkg/cluster"
+	"go-job/master/pkg/metrics"
+	"go-job/master/repo"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// failoverCheckInterval 检查节点离线和恢复的间隔，节点状态由 metrics 定期检测
+const failoverCheckInterval = 15 * time.Second
+
+// IFailoverService 节点离线超过宽限时间后转移任务，原节点恢复后停用或转回
+type IFailoverService interface {
+	GetEventList(uid int, req dto.ReqFailoverEventList) (model.Page, error)
+	Run(ctx context.Context)
+}
+
+type FailoverService struct {
+	jobRepo      repo.IJobRepo
+	failoverRepo repo.IFailoverRepo
+	jobSvc       IJobService
+
+	// 转移失败的任务及失败时节点的离线时间，同一次离线只记录一次失败事件
+	failed map[int]time.Time
+}
+
+func (s *FailoverService) GetEventList(uid int, req dto.ReqFailoverEventList) (model.Page, error) {
+	job, err := s.jobRepo.QueryById(req.JobId)
+	if err != nil {
+		return req.Page, err
+	}
+	if job.UserId != uid {
+		return req.Page, ErrUserNotPermission
+	}
+	return s.failoverRepo.QueryList(req.Page, req.JobId)
+}
+
+// Run 定期检查所有开启故障转移的任务，需要在初始化节点指标后调用
+func (s *FailoverService) Run(ctx context.Context) {
+	ticker := time.NewTicker(failoverCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.check(time.Now())
+		}
+	}
+}
+
+func (s *FailoverService) check(now time.Time) {
+	// 多个 master 时只由 leader 转移任务
+	if !cluster.IsLeader() {
+		return
+	}
+	jobs, err := s.jobRepo.QueryAll()
+	if err != nil {
+		slog.Error("failover query jobs error", "err", err)
+		return
+	}
+	nodes := make(map[int]model.Node)
+	for _, node := range metrics.GetNodeMetrics().Nodes() {
+		nodes[node.Id] = node
+	}
+
+	for _, job := range jobs {
+		if job.Internal.Target.Enabled() {
+			continue
+		}
+		// 先处理已经恢复的转移前节点
+		if s.recoverNodes(&job, nodes) {
+			continue
+		}
+
+		failover := job.Internal.Failover
+		node, ok := nodes[job.NodeID]
+		if !failover.Enabled || !ok || node.Online || node.OfflineTime.IsZero() {
+			delete(s.failed, job.Id)
+			continue
+		}
+		if now.Sub(node.OfflineTime) < time.Duration(failover.GraceSeconds)*time.Second {
+			continue
+		}
+		if offline, ok := s.failed[job.Id]; ok && offline.Equal(node.OfflineTime) {
+			continue
+		}
+		s.failover(job, node, nodes)
+	}
+}
+
+// failover 将任务转移到同分组的其他可用节点
+func (s *FailoverService) failover(job model.Job, from model.Node, nodes map[int]model.Node) {
+	var candidates []model.Node
+	for _, node := range nodes {
+		if node.Id == from.Id || !node.Online || node.Maintenance {
+			continue
+		}
+		if from.Group != "" && node.Group != from.Group {
+			continue
+		}
+		candidates = append(candidates, node)
+	}
+	to, err := balancer.GetBalancer().Pick(model.BalanceLeastRunning, job.Id, candidates)
+	if err != nil {
+		s.fail(job, from, err)
+		return
+	}
+
+	moved := job
+	moved.NodeID = to.Id
+	if err = s.jobSvc.SendDataToNode(moved, to); err != nil {
+		s.fail(job, from, err)
+		return
+	}
+	failover := &moved.Internal.Failover
+	if failover.OriginNodeId == 0 {
+		failover.OriginNodeId = from.Id
+	}
+	failover.StaleNodeIds = appendNode(failover.StaleNodeIds, from.Id)
+	if err = s.jobRepo.Update(&moved); err != nil {
+		// 节点上的任务在下次转移时会重新发送，这里只移除新节点上的任务
+		slog.Error("failover update job error", "job id", job.Id, "err", err)
+		if err := s.jobSvc.RemoveJobInNode(to, job.Id); err != nil {
+			slog.Error("failover remove job in new node error", "job id", job.Id, "node id", to.Id, "err", err)
+		}
+		return
+	}
+	delete(s.failed, job.Id)
+	s.addEvent(job.Id, model.FailoverMoved, from.Id, to.Id,
+		fmt.Sprintf("节点 %s 离线超过 %d 秒，任务转移到节点 %s", from.Name, failover.GraceSeconds, to.Name))
+}
+
+func (s *FailoverService) fail(job model.Job, from model.Node, err error) {
+	s.failed[job.Id] = from.OfflineTime
+	s.addEvent(job.Id, model.FailoverFailed, from.Id, 0,
+		fmt.Sprintf("节点 %s 离线，任务转移失败：%s", from.Name, err))
+}
+
+// recoverNodes 转移前的节点恢复后停用其上的任务，开启转回时将任务转回原节点，任务有变化时返回 true
+func (s *FailoverService) recoverNodes(job *model.Job, nodes map[int]model.Node) bool {
+	failover := &job.Internal.Failover
+	changed := false
+	for _, id := range slices.Clone(failover.StaleNodeIds) {
+		node, ok := nodes[id]
+		if !ok || !node.Online {
+			continue
+		}
+		if id == failover.OriginNodeId && failover.Enabled && failover.Failback && !node.Maintenance {
+			if s.failback(job, node, nodes) {
+				changed = true
+			}
+			continue
+		}
+		if err := s.jobSvc.RemoveJobInNode(node, job.Id); err != nil {
+			slog.Error("failover disable job in recovered node error", "job id", job.Id, "node id", id, "err", err)
+			continue
+		}
+		failover.StaleNodeIds = removeNode(failover.StaleNodeIds, id)
+		if id == failover.OriginNodeId {
+			failover.OriginNodeId = 0
+		}
+		changed = true
+		s.addEvent(job.Id, model.FailoverDisabled, id, 0,
+			fmt.Sprintf("节点 %s 已恢复，停用该节点上的任务", node.Name))
+	}
+	if !changed {
+		return false
+	}
+	if err := s.jobRepo.Update(job); err != nil {
+		slog.Error("failover update job error", "job id", job.Id, "err", err)
+	}
+	return true
+}
+
+// failback 将任务转回原节点，原节点上的任务先移除再重新发送，避免使用转移前的旧配置
+func (s *FailoverService) failback(job *model.Job, origin model.Node, nodes map[int]model.Node) bool {
+	if err := s.jobSvc.RemoveJobInNode(origin, job.Id); err != nil {
+		slog.Error("failback remove old job error", "job id", job.Id, "node id", origin.Id, "err", err)
+		return false
+	}
+	back := *job
+	back.NodeID = origin.Id
+	if err := s.jobSvc.SendDataToNode(back, origin); err != nil {
+		slog.Error("failback send job error", "job id", job.Id, "node id", origin.Id, "err", err)
+		return false
+	}
+
+	failover := &back.Internal.Failover
+	failover.OriginNodeId = 0
+	failover.StaleNodeIds = removeNode(failover.StaleNodeIds, origin.Id)
+	current, ok := nodes[job.NodeID]
+	if !ok || s.jobSvc.RemoveJobInNode(current, job.Id) != nil {
+		// 当前节点无法移除时，等它恢复后再停用
+		failover.StaleNodeIds = appendNode(failover.StaleNodeIds, job.NodeID)
+	}
+	*job = back
+	s.addEvent(job.Id, model.FailoverBack, current.Id, origin.Id,
+		fmt.Sprintf("节点 %s 已恢复，任务从节点 %s 转回", origin.Name, current.Name))
+	return true
+}
+
+func (s *FailoverService) addEvent(jobId int, typ model.FailoverEventType, from, to int, msg string) {
+	slog.Warn("job failover", "job id", jobId, "type", typ, "from", from, "to", to, "msg", msg)
+	event := model.JobFailoverEvent{
+		JobId:      jobId,
+		Type:       typ,
+		FromNodeId: from,
+		ToNodeId:   to,
+		Message:    msg,
+	}
+	if err := s.failoverRepo.Insert(&event); err != nil {
+		slog.Error("insert failover event error", "job id", jobId, "err", err)
+	}
+}
+
+func appendNode(ids []int, id int) []int {
+	if slices.Contains(ids, id) {
+		return ids
+	}
+	return append(ids, id)
+}
+
+func removeNode(ids []int, id int) []int {
+	return slices.DeleteFunc(slices.Clone(ids), func(v int) bool { return v == id })
+}
+
+func NewFailoverService(jobRepo repo.IJobRepo, failoverRepo repo.IFailoverRepo, jobSvc IJobService) IFailoverService {
+	return &FailoverService{
+		jobRepo:      jobRepo,
+		failoverRepo: failoverRepo,
+		jobSvc:       jobSvc,
+		failed:       make(map[int]time.Time),
+	}
+}
@@ -4,19 +4,28 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/robfig/cron/v3"
 	"go-job/internal/dto"
 	"go-job/internal/model"
+	"go-job/internal/pkg/cronx"
 	"go-job/internal/pkg/httpClient"
 	"go-job/internal/pkg/paths"
 	"go-job/internal/pkg/utils"
 	"go-job/internal/upload"
+	"go-job/master/pkg/balancer"
+	"go-job/master/pkg/cluster"
 	"go-job/master/pkg/config"
+	"go-job/master/pkg/dispatcher"
+	"go-job/master/pkg/metrics"
 	"go-job/master/pkg/notify"
 	"go-job/master/repo"
+	"gorm.io/gorm"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"resty.dev/v3"
+	"sort"
+	"strings"
+	"time"
 )
 
 type jobOperation string
@@ -26,20 +35,59 @@ const (
 	SendJobByCreate jobOperation = "sendJobByCreate"
 )
 
+const (
+	defaultCronPreviewCount = 5
+	maxCronPreviewCount     = 20
+)
+
+// maxMisfireCount 补偿执行次数的上限，避免节点长时间离线后恢复时集中执行大量任务
+const maxMisfireCount = 100
+
+// maxJitterSeconds 执行前随机延迟的上限
+const maxJitterSeconds = 3600
+
+// maxJobPriority 任务在节点执行池中的最高优先级
+const maxJobPriority = 9
+
+// 节点离线多少秒后故障转移
+const (
+	defaultFailoverGraceSeconds = 300
+	minFailoverGraceSeconds     = 30
+	maxFailoverGraceSeconds     = 86400
+)
+
+// 文件触发时，文件保持不变多少秒后才执行
+const (
+	defaultWatchStableSeconds = 5
+	maxWatchStableSeconds     = 3600
+)
+
 type IJobService interface {
 	GetJob(uid, id int) (model.Job, error)
 	GetJobList(uid int, req dto.ReqJobList) (model.Page, error)
+	GetNodeJobList(nodeId int, req dto.ReqJobList) (model.Page, error)
 	AddJob(job dto.ReqJob) error
 	DeleteJob(uid, id int) error
 	UpdateJob(job dto.ReqJob) error
 	SendJobToNode(job model.Job, node model.Node, operation jobOperation) error
+	SendDataToNode(job model.Job, node model.Node) error
+	SendJobFileToNode(job model.Job, node model.Node) error
+	RemoveJobInNode(node model.Node, id int) error
+	PreviewCron(req dto.ReqCronPreview) (dto.RespCronPreview, error)
+	TriggerJob(job model.Job, req dto.ReqNodeJobTrigger) error
+	PickNode(job model.Job) (model.Node, error)
+	MatchNodes(job model.Job) []model.Node
+	ScheduleDispatch(job model.Job)
+	ReloadJob(id int)
+	SyncTargetJobs(node model.Node) error
 }
 
 type JobService struct {
-	JobRepo     repo.IJobRepo
-	NodeRepo    repo.INodeRepo
-	notifyStore notify.INotifyStore
-	userRepo    repo.IUserRepo
+	JobRepo       repo.IJobRepo
+	NodeRepo      repo.INodeRepo
+	notifyStore   notify.INotifyStore
+	userRepo      repo.IUserRepo
+	jobRecordRepo repo.IJobRecordRepo
 }
 
 func (j *JobService) GetJob(uid, id int) (model.Job, error) {
@@ -71,7 +119,24 @@ func (j *JobService) GetJobList(uid int, req dto.ReqJobList) (model.Page, error)
 	if err != nil {
 		return p, err
 	}
+	return j.respJobPage(uid, p)
+}
 
+// GetNodeJobList 节点同步任务时只返回应该在该节点上的任务，在查询中按节点过滤后再分页
+func (j *JobService) GetNodeJobList(nodeId int, req dto.ReqJobList) (model.Page, error) {
+	m, ok := metrics.GetNodeMetrics().Get(nodeId)
+	if !ok {
+		return model.Page{}, ErrNodeNotExists
+	}
+	p, err := j.JobRepo.QueryListByNode(m.Node, req.Page, req.Active)
+	if err != nil {
+		return p, err
+	}
+	return j.respJobPage(model.InternalDefaultUser, p)
+}
+
+// respJobPage 把分页中的任务转换为接口返回的任务，uid 为内部用户时是节点在同步任务
+func (j *JobService) respJobPage(uid int, p model.Page) (model.Page, error) {
 	jobs, ok := p.Data.([]model.Job)
 	if !ok {
 		return p, errors.New("data isn't model job struct")
@@ -94,6 +159,24 @@ func (j *JobService) GetJobList(uid int, req dto.ReqJobList) (model.Page, error)
 	for _, node := range nodes {
 		nodeMap[node.Id] = node.Name
 	}
+
+	// 节点同步任务时需要最近一次的下一次执行时间，用于计算错过的执行
+	lastNextExecTimeMap := make(map[int]int64)
+	if uid == model.InternalDefaultUser {
+		jobIds := make([]int, 0, len(jobs))
+		for _, v := range jobs {
+			jobIds = append(jobIds, v.Id)
+		}
+		lastTimes, err := j.jobRecordRepo.QueryLastNextExecTime(jobIds)
+		if err != nil {
+			return p, err
+		}
+		for _, v := range lastTimes {
+			if !v.NextExecTime.IsZero() {
+				lastNextExecTimeMap[v.JobId] = v.NextExecTime.Unix()
+			}
+		}
+	}
 	// model.Job to dto.RespJob
 	for _, v := range jobs {
 		nodeIds = append(nodeIds, v.NodeID)
@@ -112,10 +195,33 @@ func (j *JobService) GetJobList(uid int, req dto.ReqJobList) (model.Page, error)
 			NotifyStrategy: v.Internal.Notify.NotifyStrategy,
 			NotifyMark:     v.Internal.Notify.NotifyMark,
 			UserId:         v.UserId,
+			MisfirePolicy:  v.Internal.Misfire.Policy,
+			MisfireMax:     v.Internal.Misfire.MaxCount,
+			JitterMax:      v.Internal.Jitter.MaxSeconds,
+			JitterHash:     v.Internal.Jitter.Hash,
+			Priority:       v.Internal.Priority,
+			ScheduleType:   v.Internal.ScheduleType,
+			WatchPath:      v.Internal.FileWatch.Path,
+			WatchStable:    v.Internal.FileWatch.StableSeconds,
+			WatchProcessed: v.Internal.FileWatch.ProcessedDir,
+			OnFailureJobId: v.Internal.Handler.OnFailure,
+			OnSuccessJobId: v.Internal.Handler.OnSuccess,
+			Dispatched:     IsDispatched(v),
+			TargetGroup:    v.Internal.Target.Group,
+			TargetLabels:   v.Internal.Target.Labels,
+			Strategy:       v.Internal.Target.Strategy,
+			TargetMode:     v.Internal.Target.Mode,
+			MinSuccess:     v.Internal.Target.MinSuccess,
+
+			FailoverEnabled:  v.Internal.Failover.Enabled,
+			FailoverGrace:    v.Internal.Failover.GraceSeconds,
+			FailoverFailback: v.Internal.Failover.Failback,
+			FailoverOrigin:   v.Internal.Failover.OriginNodeId,
 		}
 
 		if uid == model.InternalDefaultUser {
 			respJob.UUIDFileName = v.Internal.FileMeta.UUIDFileName
+			respJob.LastNextExecTime = lastNextExecTimeMap[v.Id]
 		}
 		data = append(data, respJob)
 	}
@@ -139,6 +245,38 @@ func reqJobToModelJob(req dto.ReqJob) model.Job {
 				NotifyStrategy: req.NotifyStrategy,
 				NotifyMark:     req.NotifyMark,
 			},
+			Misfire: model.JobMisfire{
+				Policy:   req.MisfirePolicy,
+				MaxCount: req.MisfireMax,
+			},
+			Jitter: model.JobJitter{
+				MaxSeconds: req.JitterMax,
+				Hash:       req.JitterHash,
+			},
+			Priority:     req.Priority,
+			ScheduleType: req.ScheduleType,
+			FileWatch: model.JobFileWatch{
+				Path:          strings.TrimSpace(req.WatchPath),
+				StableSeconds: req.WatchStable,
+				ProcessedDir:  strings.TrimSpace(req.WatchProcessed),
+			},
+			Handler: model.JobHandler{
+				OnFailure: req.OnFailureJobId,
+				OnSuccess: req.OnSuccessJobId,
+			},
+			Target: model.JobTarget{
+				Group:    strings.TrimSpace(req.TargetGroup),
+				Labels:   req.TargetLabels,
+				Strategy: req.Strategy,
+
+				Mode:       req.TargetMode,
+				MinSuccess: req.MinSuccess,
+			},
+			Failover: model.JobFailover{
+				Enabled:      req.FailoverEnabled,
+				GraceSeconds: req.FailoverGrace,
+				Failback:     req.FailoverFailback,
+			},
 		},
 		FileName: req.FileName,
 		FileKey:  req.FileKey,
@@ -153,16 +291,36 @@ func (j *JobService) AddJob(req dto.ReqJob) error {
 		return err
 	}
 
-	// 解析cron表达式
-	if err := j.parseCrontab(job.CronExpr); err != nil {
-		slog.Error("parse crontab error", "err", err)
-		return ErrCronExprParse
+	// 解析调度方式
+	if err := j.parseSchedule(&job); err != nil {
+		return err
+	}
+	if err := j.parseMisfire(&job.Internal.Misfire); err != nil {
+		return err
+	}
+	if err := j.parseJitter(job.Internal.Jitter); err != nil {
+		return err
+	}
+	if err := j.parsePriority(job.Internal.Priority); err != nil {
+		return err
+	}
+	if err := j.parseHandler(job); err != nil {
+		return err
+	}
+	if err := j.parseTarget(&job); err != nil {
+		return err
+	}
+	if err := j.parseFailover(&job); err != nil {
+		return err
 	}
 
 	// 查询节点，用户，校验信息
-	node, err := j.NodeRepo.QueryById(job.NodeID)
+	nodes, err := j.targetNodes(job)
 	if err != nil {
-		return ErrNodeNotExists
+		return err
+	}
+	if len(nodes) == 0 {
+		return ErrNoMatchNode
 	}
 	user, err := j.userRepo.QueryById(job.UserId)
 	if err != nil {
@@ -181,7 +339,9 @@ func (j *JobService) AddJob(req dto.ReqJob) error {
 	}
 
 	// 发送任务到节点中， 由node处理是否开始执行
-	err = j.sendDataToNode(job, node)
+	err = j.sendToNodes(nodes, func(node model.Node) error {
+		return j.SendDataToNode(job, node)
+	})
 	if err != nil {
 		if job.Id != 0 {
 			if err := j.JobRepo.Delete(job.Id); err != nil {
@@ -194,11 +354,13 @@ func (j *JobService) AddJob(req dto.ReqJob) error {
 
 	// 清除缓存中的文件
 	upload.DeleteFileMeta(job.FileKey)
+	j.ScheduleDispatch(job)
 
 	// 添加通知数据到缓存中
 	if job.Internal.Notify.NotifyStatus == model.NotifyStatusEnabled {
 		j.notifyStore.Set(context.Background(), job.Id, GenNotifyConfig(job))
 	}
+	cluster.Publish(cluster.Event{Type: cluster.EventJobChanged, JobId: job.Id})
 
 	return nil
 }
@@ -213,7 +375,7 @@ func GenNotifyConfig(job model.Job) notify.NotifyConfig {
 	}
 }
 
-func (j *JobService) sendDataToNode(job model.Job, node model.Node) error {
+func (j *JobService) SendDataToNode(job model.Job, node model.Node) error {
 	switch job.ExecType {
 	case model.ExecTypeFile:
 		// 发送文件到节点
@@ -234,6 +396,15 @@ func (j *JobService) sendDataToNode(job model.Job, node model.Node) error {
 	return nil
 }
 
+// SendJobFileToNode 重新发送job文件到节点，节点执行时读取文件，不需要重新添加任务
+func (j *JobService) SendJobFileToNode(job model.Job, node model.Node) error {
+	if err := j.sendJobFileInNode(job, node); err != nil {
+		slog.Error("send job file in node error", "err", err)
+		return ErrSyncExecFileToNode
+	}
+	return nil
+}
+
 // sendJobFileToNode 发送job文件到节点
 func (j *JobService) sendJobFileInNode(job model.Job, node model.Node) error {
 	fileColName := "file"
@@ -248,8 +419,7 @@ func (j *JobService) sendJobFileInNode(job model.Job, node model.Node) error {
 		return err
 	}
 
-	url := fmt.Sprintf("http://%s%s%s", node.Address,
-		paths.NodeJobAPI.BasePath, paths.NodeJobAPI.Upload)
+	url := httpClient.URL(node.Address, paths.NodeJobAPI.BasePath+paths.NodeJobAPI.Upload)
 	resp, err := httpClient.PostFormDataWithFile(context.Background(), fileColName,
 		f, url, formData, httpClient.DefaultTimeout)
 	if err != nil {
@@ -267,24 +437,35 @@ func (j *JobService) sendJobFileInNode(job model.Job, node model.Node) error {
 	return nil
 }
 
-// sendJobToNode 发送任务到节点
-func (j *JobService) SendJobToNode(job model.Job, node model.Node, operation jobOperation) error {
-	req := dto.ReqNodeJob{
+// nodeJobReq 发送到节点的任务定义，master 对账时根据它计算节点上应有的摘要
+func nodeJobReq(job model.Job) dto.ReqNodeJob {
+	return dto.ReqNodeJob{
 		Id:       job.Id,
 		Name:     job.Name,
 		ExecType: job.ExecType,
 		CronExpr: job.CronExpr,
 		Active:   job.Active,
 		Filename: job.Internal.FileMeta.UUIDFileName,
+		Misfire:  job.Internal.Misfire,
+		Jitter:   job.Internal.Jitter,
+		Priority: job.Internal.Priority,
+
+		ScheduleType: job.Internal.ScheduleType,
+		FileWatch:    job.Internal.FileWatch,
+		Dispatched:   IsDispatched(job),
 	}
+}
+
+// sendJobToNode 发送任务到节点
+func (j *JobService) SendJobToNode(job model.Job, node model.Node, operation jobOperation) error {
+	req := nodeJobReq(job)
 
 	// TODO 感觉这块代码还可以优化处理
 	var (
 		resp *resty.Response
 		err  error
 	)
-	url := fmt.Sprintf("http://%s%s", node.Address,
-		paths.NodeJobAPI.BasePath)
+	url := httpClient.URL(node.Address, paths.NodeJobAPI.BasePath)
 	switch operation {
 	case SendJobByCreate:
 		url = url + paths.NodeJobAPI.Create
@@ -316,10 +497,149 @@ func (j *JobService) SendJobToNode(job model.Job, node model.Node, operation job
 	return nil
 }
 
-// removeJobInNode 移除任务
-func (j *JobService) removeJobInNode(node model.Node, id int) error {
-	url := fmt.Sprintf("http://%s%s%s", node.Address,
-		paths.NodeJobAPI.BasePath, paths.NodeJobAPI.DeleteById(id)) // Note 感觉这种写法还是不太好，后面需要调整
+// TriggerJob 通知节点立即执行一次任务，req.NodeId 为0时选择本次执行的节点
+func (j *JobService) TriggerJob(job model.Job, req dto.ReqNodeJobTrigger) error {
+	var (
+		node model.Node
+		err  error
+	)
+	if req.NodeId > 0 {
+		node, err = j.NodeRepo.QueryById(req.NodeId)
+	} else {
+		node, err = j.PickNode(job)
+	}
+	if err != nil {
+		return err
+	}
+	req.NodeId = node.Id
+
+	url := httpClient.URL(node.Address, paths.NodeJobAPI.BasePath+paths.NodeJobAPI.TriggerById(job.Id))
+	resp, err := httpClient.PostJson(context.Background(), url, nil, req, httpClient.DefaultTimeout)
+	if err != nil {
+		slog.Error("trigger job in node error", "url", url, "err", err)
+		return err
+	}
+	nodeResp, err := httpClient.ParseResponse(resp)
+	if err != nil {
+		slog.Error("trigger job parse error", "resp", resp, "err", err)
+		return err
+	}
+	if nodeResp.Code != 0 {
+		slog.Error("trigger job resp code isn't zero", "resp", resp)
+		return errors.New("resp code isn't zero in trigger job")
+	}
+	// 执行结束上报记录后释放
+	balancer.GetBalancer().Acquire(node.Id)
+	return nil
+}
+
+// PickNode 选择本次执行的节点，设置了节点选择器时按策略从匹配的健康节点中选择
+func (j *JobService) PickNode(job model.Job) (model.Node, error) {
+	target := job.Internal.Target
+	if !target.Enabled() {
+		return j.NodeRepo.QueryById(job.NodeID)
+	}
+	return balancer.GetBalancer().Pick(target.Strategy, job.Id, j.MatchNodes(job))
+}
+
+// MatchNodes 匹配节点选择器的健康节点，即在线并且不在维护中，按节点id排序
+func (j *JobService) MatchNodes(job model.Job) []model.Node {
+	var nodes []model.Node
+	for _, node := range metrics.GetNodeMetrics().Nodes() {
+		if node.Online && !node.Maintenance && job.Internal.Target.Match(node) {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Slice(nodes, func(a, b int) bool { return nodes[a].Id < nodes[b].Id })
+	return nodes
+}
+
+// targetNodes 任务需要同步到的节点，设置了节点选择器时是所有匹配的节点
+func (j *JobService) targetNodes(job model.Job) ([]model.Node, error) {
+	if !job.Internal.Target.Enabled() {
+		node, err := j.NodeRepo.QueryById(job.NodeID)
+		if err != nil {
+			return nil, ErrNodeNotExists
+		}
+		return []model.Node{node}, nil
+	}
+	all, err := j.NodeRepo.QueryAll()
+	if err != nil {
+		return nil, err
+	}
+	var nodes []model.Node
+	for _, node := range all {
+		if job.Internal.Target.Match(node) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
+
+// sendToNodes 依次发送到每个节点，匹配多个节点时部分节点失败只记录日志，全部失败才返回错误
+func (j *JobService) sendToNodes(nodes []model.Node, send func(node model.Node) error) error {
+	var (
+		success int
+		lastErr error
+	)
+	for _, node := range nodes {
+		if err := send(node); err != nil {
+			slog.Error("send job to node error", "node id", node.Id, "node name", node.Name, "err", err)
+			lastErr = err
+			continue
+		}
+		success++
+	}
+	if success == 0 {
+		return lastErr
+	}
+	return nil
+}
+
+// SyncTargetJobs 节点新增或修改分组、标签后，将匹配的节点选择器任务同步到该节点
+func (j *JobService) SyncTargetJobs(node model.Node) error {
+	jobs, err := j.JobRepo.QueryAll()
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if !job.Internal.Target.Enabled() || !job.Internal.Target.Match(node) {
+			continue
+		}
+		if err = j.SendDataToNode(job, node); err != nil {
+			slog.Error("sync target job to node error", "job id", job.Id,
+				"node id", node.Id, "err", err)
+		}
+	}
+	return nil
+}
+
+// IsDispatched 定时调度的任务在 master 调度模式下，或者设置了节点选择器时由 master 调度，文件触发的任务仍由节点监听
+func IsDispatched(job model.Job) bool {
+	if job.Internal.ScheduleType == model.ScheduleFile {
+		return false
+	}
+	return config.App.Scheduler.MasterMode() || job.Internal.Target.Enabled()
+}
+
+// ScheduleDispatch 由 master 调度的任务，根据任务的启用状态更新 master 调度器中的调度
+func (j *JobService) ScheduleDispatch(job model.Job) {
+	d := dispatcher.GetDispatcher()
+	if d == nil {
+		return
+	}
+	if job.Active != model.JobStart || !IsDispatched(job) {
+		d.Remove(job.Id)
+		return
+	}
+	if err := d.Schedule(job.Id, job.CronExpr); err != nil {
+		slog.Error("schedule dispatch job error", "job id", job.Id, "cron", job.CronExpr, "err", err)
+	}
+}
+
+// RemoveJobInNode 移除任务
+func (j *JobService) RemoveJobInNode(node model.Node, id int) error {
+	url := httpClient.URL(node.Address, paths.NodeJobAPI.BasePath+paths.NodeJobAPI.DeleteById(id)) // Note 感觉这种写法还是不太好，后面需要调整
 	resp, err := httpClient.Delete(context.Background(), url, nil, httpClient.DefaultTimeout, nil)
 	if err != nil {
 		slog.Error("remove job from node error by delete", "url", url,
@@ -354,12 +674,225 @@ func (j *JobService) parseExecType(job *model.Job) error {
 	return nil
 }
 
+// parseSchedule 校验调度方式，定时调度校验表达式，文件触发校验监听配置
+func (j *JobService) parseSchedule(job *model.Job) error {
+	switch job.Internal.ScheduleType {
+	case 0, model.ScheduleCron:
+		job.Internal.ScheduleType = model.ScheduleCron
+		job.Internal.FileWatch = model.JobFileWatch{}
+		if err := j.parseCrontab(job.CronExpr); err != nil {
+			slog.Error("parse crontab error", "err", err)
+			return ErrCronExprParse
+		}
+	case model.ScheduleFile:
+		job.CronExpr = ""
+		return j.parseFileWatch(&job.Internal.FileWatch)
+	default:
+		return ErrScheduleType
+	}
+	return nil
+}
+
+// parseFileWatch 校验文件触发的监听配置，路径需要是节点上的绝对路径
+func (j *JobService) parseFileWatch(watch *model.JobFileWatch) error {
+	if !filepath.IsAbs(watch.Path) {
+		return ErrWatchPath
+	}
+	if _, err := filepath.Match(watch.Path, ""); err != nil {
+		return ErrWatchPath
+	}
+	if watch.StableSeconds == 0 {
+		watch.StableSeconds = defaultWatchStableSeconds
+	}
+	if watch.StableSeconds < 0 || watch.StableSeconds > maxWatchStableSeconds {
+		return ErrWatchStableSeconds
+	}
+	if watch.ProcessedDir != "" && !filepath.IsAbs(watch.ProcessedDir) {
+		return ErrWatchProcessedDir
+	}
+	return nil
+}
+
 func (j *JobService) parseCrontab(cronExpr string) error {
-	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	_, err := parser.Parse(cronExpr)
+	_, err := cronx.Parse(cronExpr)
 	return err
 }
 
+// parseMisfire 校验补偿策略，未设置时默认忽略错过的执行
+func (j *JobService) parseMisfire(misfire *model.JobMisfire) error {
+	switch misfire.Policy {
+	case 0:
+		misfire.Policy = model.MisfireIgnore
+		misfire.MaxCount = 0
+	case model.MisfireIgnore, model.MisfireOnce:
+		misfire.MaxCount = 0
+	case model.MisfireAll:
+		if misfire.MaxCount <= 0 || misfire.MaxCount > maxMisfireCount {
+			return ErrMisfireMaxCount
+		}
+	default:
+		return ErrMisfirePolicy
+	}
+	return nil
+}
+
+// parseJitter 校验执行前的随机延迟
+func (j *JobService) parseJitter(jitter model.JobJitter) error {
+	if jitter.MaxSeconds < 0 || jitter.MaxSeconds > maxJitterSeconds {
+		return ErrJitterMaxSeconds
+	}
+	return nil
+}
+
+func (j *JobService) parsePriority(priority int) error {
+	if priority < 0 || priority > maxJobPriority {
+		return ErrJobPriority
+	}
+	return nil
+}
+
+// parseHandler 校验处理任务，需要是当前用户的其他任务
+func (j *JobService) parseHandler(job model.Job) error {
+	for _, id := range []int{job.Internal.Handler.OnFailure, job.Internal.Handler.OnSuccess} {
+		if id == 0 {
+			continue
+		}
+		if id < 0 || id == job.Id {
+			return ErrHandlerJob
+		}
+		handler, err := j.JobRepo.QueryById(id)
+		if err != nil || handler.UserId != job.UserId {
+			return ErrHandlerJob
+		}
+	}
+	return nil
+}
+
+// parseTarget 校验节点选择器，设置后每次触发由 master 选择节点，不再固定节点
+func (j *JobService) parseTarget(job *model.Job) error {
+	target := &job.Internal.Target
+	labels, err := parseLabels(target.Labels)
+	if err != nil || len(target.Group) > maxLabelLen {
+		return ErrNodeLabel
+	}
+	target.Labels = labels
+	if !target.Enabled() {
+		// 广播和分片需要通过节点选择器选择节点
+		if target.Mode > model.TargetOne {
+			return ErrTargetMode
+		}
+		target.Strategy = 0
+		target.Mode = 0
+		target.MinSuccess = 0
+		return nil
+	}
+	// 文件需要在固定的节点上监听
+	if job.Internal.ScheduleType == model.ScheduleFile {
+		return ErrTargetSchedule
+	}
+	switch target.Strategy {
+	case 0:
+		target.Strategy = model.BalanceRoundRobin
+	case model.BalanceRoundRobin, model.BalanceRandom, model.BalanceLeastRunning, model.BalanceHash:
+	default:
+		return ErrBalanceStrategy
+	}
+	switch target.Mode {
+	case 0, model.TargetOne:
+		target.Mode = model.TargetOne
+		target.MinSuccess = 0
+	case model.TargetBroadcast, model.TargetShard:
+		if target.MinSuccess < 0 {
+			return ErrMinSuccess
+		}
+	default:
+		return ErrTargetMode
+	}
+	job.NodeID = 0
+	return nil
+}
+
+// parseFailover 校验故障转移配置，使用节点选择器的任务每次触发都会选择健康节点，不需要转移
+func (j *JobService) parseFailover(job *model.Job) error {
+	failover := &job.Internal.Failover
+	failover.OriginNodeId = 0
+	failover.StaleNodeIds = nil
+	if !failover.Enabled {
+		failover.GraceSeconds = 0
+		failover.Failback = false
+		return nil
+	}
+	if job.Internal.Target.Enabled() {
+		return ErrFailoverTarget
+	}
+	if failover.GraceSeconds == 0 {
+		failover.GraceSeconds = defaultFailoverGraceSeconds
+	}
+	if failover.GraceSeconds < minFailoverGraceSeconds || failover.GraceSeconds > maxFailoverGraceSeconds {
+		return ErrFailoverGrace
+	}
+	return nil
+}
+
+// keepFailoverState 修改任务时保留故障转移的状态，转移前的节点如果就是修改后的节点则不再处理
+func keepFailoverState(job *model.Job, dbJob model.Job) {
+	old := dbJob.Internal.Failover
+	if old.OriginNodeId != job.NodeID {
+		job.Internal.Failover.OriginNodeId = old.OriginNodeId
+	}
+	for _, id := range old.StaleNodeIds {
+		if id != job.NodeID {
+			job.Internal.Failover.StaleNodeIds = append(job.Internal.Failover.StaleNodeIds, id)
+		}
+	}
+}
+
+// PreviewCron 预览表达式接下来的执行时间，并给出自然语言描述和可能存在的问题
+func (j *JobService) PreviewCron(req dto.ReqCronPreview) (dto.RespCronPreview, error) {
+	var resp dto.RespCronPreview
+	loc := time.Local
+	expr := strings.TrimSpace(req.CronExpr)
+	if req.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(req.TimeZone); err != nil {
+			return resp, ErrTimeZone
+		}
+		if !strings.HasPrefix(expr, "CRON_TZ=") && !strings.HasPrefix(expr, "TZ=") {
+			expr = fmt.Sprintf("CRON_TZ=%s %s", req.TimeZone, expr)
+		}
+	}
+	schedule, err := cronx.Parse(expr)
+	if err != nil {
+		slog.Error("parse crontab error", "err", err)
+		return resp, ErrCronExprParse
+	}
+	descZh, err := cronx.Describe(expr, cronx.LangZh)
+	if err != nil {
+		return resp, ErrCronExprParse
+	}
+	descEn, err := cronx.Describe(expr, cronx.LangEn)
+	if err != nil {
+		return resp, ErrCronExprParse
+	}
+
+	count := req.Count
+	if count <= 0 {
+		count = defaultCronPreviewCount
+	} else if count > maxCronPreviewCount {
+		count = maxCronPreviewCount
+	}
+	now := time.Now().In(loc)
+	resp = dto.RespCronPreview{
+		CronExpr:      req.CronExpr,
+		TimeZone:      loc.String(),
+		NextTimes:     cronx.NextTimes(schedule, now, count),
+		DescriptionZh: descZh,
+		DescriptionEn: descEn,
+		Warnings:      cronx.Check(expr, schedule, now),
+	}
+	return resp, nil
+}
+
 func (j *JobService) DeleteJob(uid, id int) error {
 	job, err := j.JobRepo.QueryById(id)
 	if err != nil {
@@ -368,32 +901,56 @@ func (j *JobService) DeleteJob(uid, id int) error {
 	if job.UserId != uid {
 		return ErrUserNotPermission
 	}
-	node, err := j.NodeRepo.QueryById(job.NodeID)
+	nodes, err := j.targetNodes(job)
 	if err != nil {
 		return err
 	}
 
-	if err = j.removeJobInNode(node, id); err != nil {
+	err = j.sendToNodes(nodes, func(node model.Node) error {
+		return j.RemoveJobInNode(node, id)
+	})
+	if err != nil {
 		return err
 	}
 	err = j.JobRepo.Delete(id)
 	if err != nil {
 		return err
 	}
+	if d := dispatcher.GetDispatcher(); d != nil {
+		d.Remove(id)
+	}
 
 	if job.Internal.Notify.NotifyStatus == model.NotifyStatusEnabled {
 		j.notifyStore.Delete(context.Background(), job.Id)
 	}
+	cluster.Publish(cluster.Event{Type: cluster.EventJobChanged, JobId: id})
 	return nil
 }
 
 func (j *JobService) UpdateJob(req dto.ReqJob) error {
 	var job = reqJobToModelJob(req)
 
-	// 校验cron表达式
-	if err := j.parseCrontab(job.CronExpr); err != nil {
-		slog.Error("parse crontab error", "err", err)
-		return ErrCronExprParse
+	// 校验调度方式
+	if err := j.parseSchedule(&job); err != nil {
+		return err
+	}
+	if err := j.parseMisfire(&job.Internal.Misfire); err != nil {
+		return err
+	}
+	if err := j.parseJitter(job.Internal.Jitter); err != nil {
+		return err
+	}
+	if err := j.parsePriority(job.Internal.Priority); err != nil {
+		return err
+	}
+	if err := j.parseHandler(job); err != nil {
+		return err
+	}
+	if err := j.parseTarget(&job); err != nil {
+		return err
+	}
+	if err := j.parseFailover(&job); err != nil {
+		return err
 	}
 
 	// 校验节点，身份信息
@@ -404,9 +961,23 @@ func (j *JobService) UpdateJob(req dto.ReqJob) error {
 	if dbJob.UserId != job.UserId {
 		return ErrUserNotPermission
 	}
-	node, err := j.NodeRepo.QueryById(job.NodeID)
+	// 已经转移的任务，原节点恢复后仍需要停用其上的任务
+	keepFailoverState(&job, dbJob)
+	nodes, err := j.targetNodes(job)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return ErrNoMatchNode
+	}
+	// 已经有该任务的节点，修改节点选择器后新匹配的节点需要重新创建任务
+	oldNodes, err := j.targetNodes(dbJob)
 	if err != nil {
-		return errors.New("node not found")
+		slog.Error("query job old nodes error", "job id", dbJob.Id, "err", err)
+	}
+	synced := make(map[int]struct{}, len(oldNodes))
+	for _, node := range oldNodes {
+		synced[node.Id] = struct{}{}
 	}
 
 	// 处理文件信息
@@ -421,18 +992,23 @@ func (j *JobService) UpdateJob(req dto.ReqJob) error {
 			if !b {
 				return ErrFileNotExists
 			}
-			job.Internal = dbJob.Internal
 			job.Internal.FileMeta = fileMeta
 			upload.DeleteFileMeta(job.FileKey)
-			err = j.sendJobFileInNode(job, node)
-			if err != nil {
-				slog.Error("send job file to node error", "err", err)
-				return ErrSyncExecFileToNode
-			}
 		} else {
 			// 没有更新文件，则需要将数据库中的文件信息获取到，发送给node
 			job.Internal.FileMeta = dbJob.Internal.FileMeta
 		}
+		// 执行文件修改了，或者节点上还没有该任务时发送文件
+		err = j.sendToNodes(nodes, func(node model.Node) error {
+			if _, ok := synced[node.Id]; ok && len(job.FileKey) == 0 {
+				return nil
+			}
+			return j.sendJobFileInNode(job, node)
+		})
+		if err != nil {
+			slog.Error("send job file to node error", "err", err)
+			return ErrSyncExecFileToNode
+		}
 	default:
 		return errors.New("not support exec type")
 	}
@@ -441,7 +1017,12 @@ func (j *JobService) UpdateJob(req dto.ReqJob) error {
 	if err != nil {
 		return err
 	}
-	err = j.SendJobToNode(job, node, SendJobByUpdate)
+	err = j.sendToNodes(nodes, func(node model.Node) error {
+		if _, ok := synced[node.Id]; ok {
+			return j.SendJobToNode(job, node, SendJobByUpdate)
+		}
+		return j.SendJobToNode(job, node, SendJobByCreate)
+	})
 	if err != nil {
 		// TODO 回退job，是否要使用事务回滚？目前没有，担心http请求会阻塞job表
 		if err := j.JobRepo.Update(&dbJob); err != nil {
@@ -449,6 +1030,8 @@ func (j *JobService) UpdateJob(req dto.ReqJob) error {
 		}
 		return ErrSyncExecFileToNode
 	}
+	j.removeUnmatchedNodes(job, oldNodes, nodes)
+	j.ScheduleDispatch(job)
 
 	err = j.notifyStore.Delete(context.Background(), job.Id)
 	if err != nil {
@@ -457,16 +1040,58 @@ func (j *JobService) UpdateJob(req dto.ReqJob) error {
 	if job.Internal.Notify.NotifyStatus == model.NotifyStatusEnabled {
 		j.notifyStore.Set(context.Background(), job.Id, GenNotifyConfig(job))
 	}
+	cluster.Publish(cluster.Event{Type: cluster.EventJobChanged, JobId: job.Id})
 
 	return nil
 }
 
+// ReloadJob 其他 master 修改任务后，从数据库重新加载调度和通知配置
+func (j *JobService) ReloadJob(id int) {
+	ctx := context.Background()
+	if err := j.notifyStore.Delete(ctx, id); err != nil {
+		slog.Error("reload job delete notify error", "job id", id, "err", err)
+	}
+	job, err := j.JobRepo.QueryById(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if d := dispatcher.GetDispatcher(); d != nil {
+			d.Remove(id)
+		}
+		return
+	}
+	if err != nil {
+		slog.Error("reload job error", "job id", id, "err", err)
+		return
+	}
+	j.ScheduleDispatch(job)
+	if job.Internal.Notify.NotifyStatus == model.NotifyStatusEnabled {
+		j.notifyStore.Set(ctx, job.Id, GenNotifyConfig(job))
+	}
+}
+
+// removeUnmatchedNodes 修改节点选择器后，从不再匹配的节点上移除任务
+func (j *JobService) removeUnmatchedNodes(job model.Job, oldNodes, nodes []model.Node) {
+	matched := make(map[int]struct{}, len(nodes))
+	for _, node := range nodes {
+		matched[node.Id] = struct{}{}
+	}
+	for _, node := range oldNodes {
+		if _, ok := matched[node.Id]; ok {
+			continue
+		}
+		if err := j.RemoveJobInNode(node, job.Id); err != nil {
+			slog.Error("remove job in unmatched node error", "job id", job.Id,
+				"node id", node.Id, "err", err)
+		}
+	}
+}
+
 func NewJobService(jobRepo repo.IJobRepo, nodeRepo repo.INodeRepo,
-	userRepo repo.IUserRepo, notify notify.INotifyStore) IJobService {
+	userRepo repo.IUserRepo, jobRecordRepo repo.IJobRecordRepo, notify notify.INotifyStore) IJobService {
 	return &JobService{
-		JobRepo:     jobRepo,
-		NodeRepo:    nodeRepo,
-		userRepo:    userRepo,
-		notifyStore: notify,
+		JobRepo:       jobRepo,
+		NodeRepo:      nodeRepo,
+		userRepo:      userRepo,
+		jobRecordRepo: jobRecordRepo,
+		notifyStore:   notify,
 	}
 }
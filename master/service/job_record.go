@@ -2,23 +2,56 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"go-job/internal/dto"
 	"go-job/internal/model"
 	"go-job/internal/pkg/utils"
+	"go-job/master/pkg/balancer"
+	"go-job/master/pkg/metrics"
 	"go-job/master/pkg/notify"
 	"go-job/master/repo"
+	"gorm.io/gorm"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
+)
+
+// 处理任务通过环境变量获取触发它的执行记录
+const (
+	handlerEnvJobId    = "GO_JOB_PARENT_JOB_ID"
+	handlerEnvRecordId = "GO_JOB_PARENT_RECORD_ID"
+	handlerEnvStatus   = "GO_JOB_PARENT_STATUS"
+	handlerEnvOutput   = "GO_JOB_PARENT_OUTPUT"
+	handlerEnvError    = "GO_JOB_PARENT_ERROR"
+
+	// maxHandlerEnvLen 单个环境变量的长度上限，避免超过系统限制导致处理任务无法启动
+	maxHandlerEnvLen = 32 << 10
+)
+
+// 分片执行时通过环境变量传给每个节点的分片信息，分片序号从0开始
+const (
+	shardEnvIndex = "SHARD_INDEX"
+	shardEnvTotal = "SHARD_TOTAL"
 )
 
 type IJobRecordService interface {
 	GetJobRecord(id int) (model.JobRecord, error)
 	GetJobRecordList(page model.Page, jobId, uid int) (model.Page, error)
 	AddJobRecord(req model.CallbackJobResult) error
+	CheckNodeResult(nodeId int, req model.CallbackJobResult) error
 	DeleteJobRecord(id int) error
+	TriggerJob(job model.Job, req dto.ReqNodeJobTrigger) error
 }
 
 type JobRecordService struct {
 	JobRecordRepo repo.IJobRecordRepo
 	notifyStore   notify.INotifyStore
+	jobRepo       repo.IJobRepo
+	jobSvc        IJobService
 }
 
 func (s *JobRecordService) GetJobRecord(id int) (model.JobRecord, error) {
@@ -48,17 +81,309 @@ func (s *JobRecordService) AddJobRecord(req model.CallbackJobResult) error {
 		Duration:     req.Duration,
 		Output:       req.Output,
 		Error:        req.Error,
+		TriggerType:  req.TriggerType,
+		JitterDelay:  req.JitterDelay,
+		NodeId:       req.NodeId,
+	}
+	// 兼容未上报触发信息的旧版本节点
+	if jobRecord.TriggerType == 0 {
+		jobRecord.TriggerType = model.TriggerCron
+	}
+	if req.ScheduledTime > 0 {
+		jobRecord.ScheduledTime = utils.TimestampToTime(req.ScheduledTime)
+	} else {
+		jobRecord.ScheduledTime = jobRecord.StartTime
+	}
+	// 节点上报失败后会重试，已经保存过的结果直接返回成功
+	if s.isDuplicate(req.ExecutionId) {
+		slog.Info("duplicate job result, ignored", "job id", req.JobID, "execution id", req.ExecutionId)
+		return nil
+	}
+	jobRecord.ExecutionId = req.ExecutionId
+	// master 触发的执行结束后释放节点的执行数
+	if req.NodeId > 0 {
+		balancer.GetBalancer().Release(req.NodeId)
+	}
+	if err := s.saveJobRecord(&jobRecord, req.RunId); err != nil {
+		// 同一个结果并发上报时，只有一次能保存成功
+		if s.isDuplicate(req.ExecutionId) {
+			slog.Info("duplicate job result, ignored", "job id", req.JobID, "execution id", req.ExecutionId)
+			return nil
+		}
+		return err
+	}
+	// 广播和分片的子记录全部结束后汇总到父记录，通知和处理任务只针对父记录
+	if req.RunId > 0 {
+		saved, err := s.JobRecordRepo.QueryById(req.RunId)
+		if err != nil {
+			slog.Error("query saved run error", "run id", req.RunId, "err", err)
+		} else if saved.ParentId > 0 {
+			s.finishParent(saved.ParentId)
+			return nil
+		}
+	}
+	s.afterFinish(jobRecord)
+	return nil
+}
+
+// CheckNodeResult 节点只能上报在该节点上的任务的执行结果，故障转移前的节点可能还有未上报的结果
+func (s *JobRecordService) CheckNodeResult(nodeId int, req model.CallbackJobResult) error {
+	// master 触发时选择的执行节点需要是上报结果的节点，否则会释放其他节点的执行数
+	if req.NodeId != 0 && req.NodeId != nodeId {
+		return ErrNodeJobForbidden
+	}
+	m, ok := metrics.GetNodeMetrics().Get(nodeId)
+	if !ok {
+		return ErrNodeNotExists
+	}
+	job, err := s.jobRepo.QueryById(req.JobID)
+	// 任务已经删除时和之前一样保存结果，避免节点一直重试
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	failover := job.Internal.Failover
+	if jobOnNode(job, m.Node) || failover.OriginNodeId == nodeId || slices.Contains(failover.StaleNodeIds, nodeId) {
+		return nil
+	}
+	return ErrNodeJobForbidden
+}
+
+// afterFinish 执行结束后发送通知，触发处理任务
+func (s *JobRecordService) afterFinish(record model.JobRecord) {
+	if nc, ok := s.notifyStore.Get(context.Background(), record.JobId); ok {
+		s.notifyStore.PushNotifyUnit(context.Background(), record.JobId, s.jobToNotifyUnit(record, nc))
+	}
+	// 处理任务触发的执行不再触发其他处理任务，避免相互配置时循环触发
+	if record.TriggerType != model.TriggerHandler {
+		go s.dispatchHandler(record)
+	}
+}
+
+// TriggerJob 触发一次执行，广播和分片的任务在所有匹配的健康节点上执行，每个节点一条子记录
+// req.RunId 不为0时作为父记录，否则创建父记录
+func (s *JobRecordService) TriggerJob(job model.Job, req dto.ReqNodeJobTrigger) error {
+	target := job.Internal.Target
+	if !target.MultiNode() {
+		return s.jobSvc.TriggerJob(job, req)
+	}
+	nodes := s.jobSvc.MatchNodes(job)
+	if len(nodes) == 0 {
+		return ErrNoMatchNode
+	}
+
+	now := time.Now()
+	scheduledTime := now.Truncate(time.Second)
+	if req.ScheduledTime > 0 {
+		scheduledTime = utils.TimestampToTime(req.ScheduledTime)
+	}
+	parent := model.JobRecord{
+		Id:            req.RunId,
+		JobId:         job.Id,
+		Status:        model.Pending,
+		StartTime:     now,
+		EndTime:       now,
+		NextExecTime:  utils.TimestampToTime(0),
+		TriggerType:   req.TriggerType,
+		ScheduledTime: scheduledTime,
+	}
+	if parent.Id == 0 {
+		if err := s.JobRecordRepo.Insert(&parent); err != nil {
+			return err
+		}
+	}
+
+	// 先创建所有子记录再下发，避免先结束的节点汇总时还没有其他子记录
+	children := make([]model.JobRecord, 0, len(nodes))
+	for _, node := range nodes {
+		child := parent
+		child.Id = 0
+		child.ParentId = parent.Id
+		child.NodeId = node.Id
+		children = append(children, child)
 	}
-	if err := s.JobRecordRepo.Insert(&jobRecord); err != nil {
+	if err := s.JobRecordRepo.Inserts(children); err != nil {
 		return err
 	}
-	if nc, ok := s.notifyStore.Get(context.Background(), req.JobID); ok {
-		s.notifyStore.PushNotifyUnit(context.Background(), req.JobID, s.jobToNotifyUnit(req, nc))
+
+	for i, child := range children {
+		childReq := req
+		childReq.RunId = child.Id
+		childReq.NodeId = child.NodeId
+		if target.Mode == model.TargetShard {
+			childReq.Env = make(map[string]string, len(req.Env)+2)
+			for k, v := range req.Env {
+				childReq.Env[k] = v
+			}
+			childReq.Env[shardEnvIndex] = strconv.Itoa(i)
+			childReq.Env[shardEnvTotal] = strconv.Itoa(len(children))
+		}
+		if err := s.jobSvc.TriggerJob(job, childReq); err != nil {
+			// 下发失败的子记录直接记录为失败，不影响其他节点
+			slog.Error("trigger job in node error", "job id", job.Id, "node id", child.NodeId, "err", err)
+			err = s.AddJobRecord(model.CallbackJobResult{
+				JobExecResult: model.JobExecResult{
+					StartTime:     now.Unix(),
+					EndTime:       time.Now().Unix(),
+					Status:        model.Failed,
+					Error:         "下发到节点失败：" + err.Error(),
+					TriggerType:   req.TriggerType,
+					ScheduledTime: scheduledTime.Unix(),
+					RunId:         child.Id,
+				},
+				JobID: job.Id,
+			})
+			if err != nil {
+				slog.Error("finish child run error", "job id", job.Id, "run id", child.Id, "err", err)
+			}
+		}
 	}
+	slog.Info("trigger job in nodes", "job id", job.Id, "mode", target.Mode,
+		"parent id", parent.Id, "nodes", len(children))
 	return nil
 }
 
-func (s *JobRecordService) jobToNotifyUnit(req model.CallbackJobResult, nc notify.NotifyConfig) notify.NotifyUnit {
+// finishParent 按任务的成功规则汇总父记录的状态，成功数已经达到或者不可能再达到要求时提前结束，
+// 不需要等待所有子记录结束，之后结束的子记录不再修改父记录
+func (s *JobRecordService) finishParent(parentId int) {
+	children, err := s.JobRecordRepo.QueryByParentId(parentId)
+	if err != nil {
+		slog.Error("query child runs error", "parent id", parentId, "err", err)
+		return
+	}
+	if len(children) == 0 {
+		return
+	}
+	var (
+		success int
+		pending int
+		end     time.Time
+		failed  []string
+	)
+	for _, child := range children {
+		switch child.Status {
+		case model.Pending, model.Running:
+			pending++
+			continue
+		case model.Success:
+			success++
+		default:
+			failed = append(failed, fmt.Sprintf("节点 %d %s：%s", child.NodeId, child.Status, child.Error))
+		}
+		if child.EndTime.After(end) {
+			end = child.EndTime
+		}
+	}
+	need := len(children)
+	if job, err := s.jobRepo.QueryById(children[0].JobId); err == nil && job.Internal.Target.MinSuccess > 0 {
+		need = job.Internal.Target.MinSuccess
+	}
+	if pending > 0 && success < need && success+pending >= need {
+		return
+	}
+
+	parent, err := s.JobRecordRepo.QueryById(parentId)
+	if err != nil {
+		slog.Error("query parent run error", "parent id", parentId, "err", err)
+		return
+	}
+	parent.Status = model.Failed
+	if success >= need {
+		parent.Status = model.Success
+	}
+	parent.EndTime = end
+	parent.Duration = end.Sub(parent.StartTime).Seconds()
+	parent.Output = fmt.Sprintf("%d/%d 个节点执行成功，至少需要 %d 个", success, len(children), need)
+	if pending > 0 {
+		parent.Output += fmt.Sprintf("，提前结束时 %d 个节点未结束", pending)
+	}
+	parent.Error = truncate(strings.Join(failed, "\n"), maxHandlerEnvLen)
+
+	ok, err := s.JobRecordRepo.FinishParent(&parent)
+	if err != nil {
+		slog.Error("finish parent run error", "parent id", parentId, "err", err)
+		return
+	}
+	if ok {
+		s.afterFinish(parent)
+	}
+}
+
+// isDuplicate 执行id对应的结果是否已经保存，旧版本节点不会上报执行id
+func (s *JobRecordService) isDuplicate(executionId string) bool {
+	if executionId == "" {
+		return false
+	}
+	_, err := s.JobRecordRepo.QueryByExecutionId(executionId)
+	return err == nil
+}
+
+// saveJobRecord master 调度的执行更新预先创建的记录，其他情况新增记录
+func (s *JobRecordService) saveJobRecord(record *model.JobRecord, runId int) error {
+	if runId == 0 {
+		return s.JobRecordRepo.Insert(record)
+	}
+	record.Id = runId
+	return s.JobRecordRepo.UpdateRun(record)
+}
+
+// dispatchHandler 根据执行结果触发任务配置的处理任务
+func (s *JobRecordService) dispatchHandler(record model.JobRecord) {
+	job, err := s.jobRepo.QueryById(record.JobId)
+	if err != nil {
+		slog.Error("query job for handler error", "job id", record.JobId, "err", err)
+		return
+	}
+	var handlerId int
+	switch record.Status {
+	case model.Success:
+		handlerId = job.Internal.Handler.OnSuccess
+	case model.Failed, model.Dropped:
+		handlerId = job.Internal.Handler.OnFailure
+	}
+	if handlerId == 0 {
+		return
+	}
+
+	handler, err := s.jobRepo.QueryById(handlerId)
+	if err != nil {
+		slog.Error("query handler job error", "job id", record.JobId, "handler id", handlerId, "err", err)
+		return
+	}
+	req := dto.ReqNodeJobTrigger{
+		TriggerType: model.TriggerHandler,
+		Env: map[string]string{
+			handlerEnvJobId:    strconv.Itoa(record.JobId),
+			handlerEnvRecordId: strconv.Itoa(record.Id),
+			handlerEnvStatus:   strconv.Itoa(int(record.Status)),
+			handlerEnvOutput:   truncate(record.Output, maxHandlerEnvLen),
+			handlerEnvError:    truncate(record.Error, maxHandlerEnvLen),
+		},
+	}
+	if err = s.TriggerJob(handler, req); err != nil {
+		slog.Error("trigger handler job error", "job id", record.JobId,
+			"record id", record.Id, "handler id", handlerId, "err", err)
+		return
+	}
+	slog.Info("trigger handler job", "job id", record.JobId, "record id", record.Id,
+		"status", record.Status, "handler id", handlerId)
+}
+
+// truncate 保留字符串末尾的 n 个字节，错误信息通常在输出的最后
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	start := len(s) - n
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	return s[start:]
+}
+
+func (s *JobRecordService) jobToNotifyUnit(record model.JobRecord, nc notify.NotifyConfig) notify.NotifyUnit {
 	return notify.NotifyUnit{
 		NotifyConfig: notify.NotifyConfig{
 			JobID:          nc.JobID,
@@ -67,11 +392,11 @@ func (s *JobRecordService) jobToNotifyUnit(req model.CallbackJobResult, nc notif
 			NotifyType:     nc.NotifyType,
 			NotifyMark:     nc.NotifyMark,
 		},
-		StartExecTime: time.Unix(req.StartTime, 0).Local(),
-		Status:        req.Status,
-		Duration:      req.Duration,
-		Output:        req.Output,
-		Error:         req.Error,
+		StartExecTime: record.StartTime.Local(),
+		Status:        record.Status,
+		Duration:      record.Duration,
+		Output:        record.Output,
+		Error:         record.Error,
 	}
 }
 
@@ -79,9 +404,12 @@ func (s *JobRecordService) DeleteJobRecord(id int) error {
 	return s.JobRecordRepo.Delete(id)
 }
 
-func NewJobRecordService(nodeRepo repo.IJobRecordRepo, notify notify.INotifyStore) IJobRecordService {
+func NewJobRecordService(nodeRepo repo.IJobRecordRepo, notify notify.INotifyStore,
+	jobRepo repo.IJobRepo, jobSvc IJobService) IJobRecordService {
 	return &JobRecordService{
 		JobRecordRepo: nodeRepo,
 		notifyStore:   notify,
+		jobRepo:       jobRepo,
+		jobSvc:        jobSvc,
 	}
 }
@@ -2,17 +2,36 @@ package service
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
-	"fmt"
 	"go-job/internal/dto"
 	"go-job/internal/model"
+	"go-job/internal/pkg/auth"
 	"go-job/internal/pkg/httpClient"
+	"go-job/internal/pkg/paths"
+	"go-job/internal/pkg/pki"
+	"go-job/internal/pkg/utils"
+	"go-job/master/pkg/cluster"
+	"go-job/master/pkg/config"
 	"go-job/master/pkg/metrics"
+	"go-job/master/pkg/nodecert"
+	"go-job/master/pkg/nodeconn"
 	"go-job/master/repo"
+	"gorm.io/gorm"
 	"log/slog"
+	"strings"
 	"time"
 )
 
+// maxDrainSeconds 节点等待正在执行的任务结束的最长时间
+const maxDrainSeconds = 600
+
+// maxLabelLen 节点分组、标签名和标签值的最大长度
+const maxLabelLen = 63
+
+// defaultHeartbeatInterval 未配置时节点上报心跳的间隔秒数
+const defaultHeartbeatInterval = 10
+
 type INodeService interface {
 	GetNode(id int) (model.Node, error)
 	GetNodeList(page model.Page) (model.Page, error)
@@ -21,11 +40,34 @@ type INodeService interface {
 	UpdateNode(job model.Node) error
 	InstallRef(req dto.ReqNodeRef) (any, error)
 	NodeInfo(id int) (any, error)
+	PauseScheduler(id int) error
+	ResumeScheduler(id int) error
+	EnterMaintenance(id int) error
+	ExitMaintenance(id int) error
+	DrainNode(id int, req dto.ReqNodeDrain) (dto.RespNodeDrain, error)
+	Register(token string, req dto.ReqNodeRegister) (dto.RespNodeRegister, error)
+	Heartbeat(req dto.ReqNodeHeartbeat) error
+	GetNodeResource(id int, req dto.ReqNodeResource) (dto.RespNodeResource, error)
+	GetNodeJobs(id int) (dto.RespNodeJobInventory, error)
+	ConnectNode(nodeId int, address string) (model.Node, error)
+	GetNodeSecret(uid, id int) (dto.RespNodeSecret, error)
+	ResetNodeSecret(uid, id int) (dto.RespNodeSecret, error)
+	ResetNodeTokenSecret(uid, id int) (dto.RespNodeSecret, error)
+	IssueNodeCert(id int, req dto.ReqNodeCert) (dto.RespNodeCert, error)
+	RevokeNodeCert(id int) error
+}
+
+// nodeDataResp 节点接口的响应
+type nodeDataResp[T any] struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+	Data T      `json:"data"`
 }
 
 type NodeService struct {
 	NodeRepo repo.INodeRepo
 	JobRepo  repo.IJobRepo
+	jobSvc   IJobService
 }
 
 func (s *NodeService) GetNode(id int) (model.Node, error) {
@@ -45,16 +87,32 @@ func (s *NodeService) GetNodeList(page model.Page) (model.Page, error) {
 		if m, ok := nodeMetric.Get(node.Id); ok {
 			nodes[i].Online = m.Online
 			nodes[i].CheckTime = m.CheckTime
+			nodes[i].Maintenance = m.Maintenance
+			nodes[i].HeartbeatTime = m.HeartbeatTime
+			nodes[i].Resource = m.Resource
 		}
+		nodes[i].Tunnel = nodeconn.Connected(node.Address)
 	}
 	return data, nil
 }
 
 func (s *NodeService) AddNode(node model.Node) error {
+	if err := parseNodeLabels(&node); err != nil {
+		return err
+	}
+	var err error
+	if node.Secret, err = auth.NewSecret(); err != nil {
+		return err
+	}
+	if node.TokenSecret, err = auth.NewSecret(); err != nil {
+		return err
+	}
 	if err := s.NodeRepo.Insert(&node); err != nil {
 		return err
 	}
 	metrics.GetNodeMetrics().SetAndCheck(node.Id, node)
+	publishNodeChanged(node.Id)
+	go s.syncTargetJobs(node)
 	return nil
 }
 
@@ -70,25 +128,387 @@ func (s *NodeService) DeleteNode(id int) error {
 		return err
 	}
 	metrics.GetNodeMetrics().Remove(id)
+	publishNodeChanged(id)
 	return nil
 }
 
 func (s *NodeService) UpdateNode(node model.Node) error {
+	if err := parseNodeLabels(&node); err != nil {
+		return err
+	}
 	if err := s.NodeRepo.Update(node); err != nil {
 		return err
 	}
-	metrics.GetNodeMetrics().Remove(node.Id)
+	// 维护状态和自注册上报的信息不通过更新接口修改，以数据库中的为准
+	node, err := s.NodeRepo.QueryById(node.Id)
+	if err != nil {
+		return err
+	}
+	if m, ok := metrics.GetNodeMetrics().Get(node.Id); ok {
+		node.HeartbeatTime = m.HeartbeatTime
+	}
 	metrics.GetNodeMetrics().Set(node.Id, node)
+	publishNodeChanged(node.Id)
+	go s.syncTargetJobs(node)
+	return nil
+}
+
+// Register 节点启动时自注册，已有同名的自注册节点时更新节点信息并重新生成密钥，之后通过心跳判断节点是否在线
+func (s *NodeService) Register(token string, req dto.ReqNodeRegister) (dto.RespNodeRegister, error) {
+	var resp dto.RespNodeRegister
+	bootstrap := config.App.Register.BootstrapToken
+	if bootstrap == "" {
+		return resp, ErrRegisterDisabled
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(bootstrap)) != 1 {
+		return resp, ErrBootstrapToken
+	}
+
+	node, err := s.NodeRepo.QueryByName(req.Name)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return resp, err
+	}
+	// 手动添加的节点不允许通过自注册接管
+	if node.Id > 0 && (node.UserId != 0 || !node.Heartbeat) {
+		slog.Warn("register with the name of a manually added node", "node id", node.Id, "name", req.Name,
+			"address", req.Address)
+		return resp, ErrRegisterNodeName
+	}
+	if node.Id > 0 {
+		slog.Warn("node re-registered, secrets rotated", "node id", node.Id, "name", req.Name,
+			"old address", node.Address, "address", req.Address)
+	}
+	node.Name = req.Name
+	node.Address = req.Address
+	node.Hostname = req.Hostname
+	node.Version = req.Version
+	node.Capabilities = req.Capabilities
+	node.Heartbeat = true
+	// 没有上报分组和标签时保留页面上设置的
+	if req.Group != "" || len(req.Labels) > 0 {
+		node.Group = req.Group
+		node.Labels = req.Labels
+	}
+	if err = parseNodeLabels(&node); err != nil {
+		return resp, err
+	}
+	// 每次注册都重新生成密钥，不通过同名注册返回已有的密钥，之前的密钥和 token 立即失效
+	if node.Secret, err = auth.NewSecret(); err != nil {
+		return resp, err
+	}
+	if node.TokenSecret, err = auth.NewSecret(); err != nil {
+		return resp, err
+	}
+	if node.Id == 0 {
+		err = s.NodeRepo.Insert(&node)
+	} else {
+		err = s.NodeRepo.UpdateRegister(node)
+	}
+	if err != nil {
+		return resp, err
+	}
+
+	nodeMetrics := metrics.GetNodeMetrics()
+	if m, ok := nodeMetrics.Get(node.Id); ok {
+		node.Maintenance = m.Maintenance
+	}
+	nodeMetrics.Set(node.Id, node)
+	nodeMetrics.Heartbeat(node.Id, nil)
+	publishNodeChanged(node.Id)
+	go s.syncTargetJobs(node)
+	slog.Info("node registered", "node id", node.Id, "name", node.Name, "address", node.Address,
+		"version", node.Version)
+
+	resp.NodeId = node.Id
+	resp.Secret = node.Secret
+	resp.TokenSecret = node.TokenSecret
+	resp.HeartbeatInterval = config.App.Register.HeartbeatInterval
+	if resp.HeartbeatInterval <= 0 {
+		resp.HeartbeatInterval = defaultHeartbeatInterval
+	}
+	return resp, nil
+}
+
+// Heartbeat 更新节点的心跳时间，节点已被删除时返回 ErrNodeNotExists，节点需要重新注册
+func (s *NodeService) Heartbeat(req dto.ReqNodeHeartbeat) error {
+	if !metrics.GetNodeMetrics().Heartbeat(req.NodeId, req.Resource) {
+		return ErrNodeNotExists
+	}
+	cluster.Publish(cluster.Event{Type: cluster.EventNodeHeartbeat, NodeId: req.NodeId, Resource: req.Resource})
 	return nil
 }
 
+// ConnectNode 节点建立反向连接前查找节点，节点使用自己的 token 时按节点id查找，否则按地址查找，
+// 节点需要先手动添加或自注册
+func (s *NodeService) ConnectNode(nodeId int, address string) (model.Node, error) {
+	if nodeId > 0 {
+		m, ok := metrics.GetNodeMetrics().Get(nodeId)
+		if !ok {
+			return model.Node{}, ErrNodeNotExists
+		}
+		return m.Node, nil
+	}
+	if address == "" {
+		return model.Node{}, ErrNodeNotExists
+	}
+	for _, node := range metrics.GetNodeMetrics().Nodes() {
+		if node.Address == address {
+			return node, nil
+		}
+	}
+	return model.Node{}, ErrNodeNotExists
+}
+
+// nodeSecretKind 节点的两个密钥，分别重置
+type nodeSecretKind string
+
+const (
+	nodeSignSecret  nodeSecretKind = "secret"       // master 请求节点时签名的密钥
+	nodeTokenSecret nodeSecretKind = "token_secret" // 节点生成访问 master 的 token 的密钥
+)
+
+// GetNodeSecret 查询节点的两个密钥，配置到手动添加的节点的 master.secret 和 master.token_secret，没有密钥时生成
+func (s *NodeService) GetNodeSecret(uid, id int) (dto.RespNodeSecret, error) {
+	node, err := s.secretNode(uid, id)
+	if err != nil {
+		return dto.RespNodeSecret{}, err
+	}
+	if node.Secret == "" {
+		if node, err = s.resetNodeSecret(uid, node, nodeSignSecret); err != nil {
+			return dto.RespNodeSecret{}, err
+		}
+	}
+	if node.TokenSecret == "" {
+		if node, err = s.resetNodeSecret(uid, node, nodeTokenSecret); err != nil {
+			return dto.RespNodeSecret{}, err
+		}
+	}
+	return dto.RespNodeSecret{Secret: node.Secret, TokenSecret: node.TokenSecret}, nil
+}
+
+// ResetNodeSecret 重新生成 master 请求节点时签名的密钥，之后需要同步修改节点的配置，自注册的节点重新注册后生效
+func (s *NodeService) ResetNodeSecret(uid, id int) (dto.RespNodeSecret, error) {
+	node, err := s.secretNode(uid, id)
+	if err != nil {
+		return dto.RespNodeSecret{}, err
+	}
+	if node, err = s.resetNodeSecret(uid, node, nodeSignSecret); err != nil {
+		return dto.RespNodeSecret{}, err
+	}
+	return dto.RespNodeSecret{Secret: node.Secret}, nil
+}
+
+// ResetNodeTokenSecret 重新生成节点 token 的密钥，节点之前的 token 立即失效，自注册的节点心跳失败后重新注册获取新的密钥
+func (s *NodeService) ResetNodeTokenSecret(uid, id int) (dto.RespNodeSecret, error) {
+	node, err := s.secretNode(uid, id)
+	if err != nil {
+		return dto.RespNodeSecret{}, err
+	}
+	if node, err = s.resetNodeSecret(uid, node, nodeTokenSecret); err != nil {
+		return dto.RespNodeSecret{}, err
+	}
+	return dto.RespNodeSecret{TokenSecret: node.TokenSecret}, nil
+}
+
+// secretNode 只有添加节点的用户和管理员可以查询和重置节点的密钥，自注册的节点只有管理员可以
+func (s *NodeService) secretNode(uid, id int) (model.Node, error) {
+	node, err := s.NodeRepo.QueryById(id)
+	if err != nil {
+		return node, err
+	}
+	if node.UserId != uid && !config.App.Server.IsAdmin(uid) {
+		return node, ErrUserNotPermission
+	}
+	return node, nil
+}
+
+func (s *NodeService) resetNodeSecret(uid int, node model.Node, kind nodeSecretKind) (model.Node, error) {
+	secret, err := auth.NewSecret()
+	if err != nil {
+		return node, err
+	}
+	switch kind {
+	case nodeTokenSecret:
+		node.TokenSecret = secret
+		err = s.NodeRepo.UpdateTokenSecret(node.Id, secret)
+	default:
+		node.Secret = secret
+		err = s.NodeRepo.UpdateSecret(node.Id, secret)
+	}
+	if err != nil {
+		return node, err
+	}
+	nodeMetrics := metrics.GetNodeMetrics()
+	if m, ok := nodeMetrics.Get(node.Id); ok {
+		n := m.Node
+		n.Secret = node.Secret
+		n.TokenSecret = node.TokenSecret
+		nodeMetrics.Set(node.Id, n)
+	}
+	publishNodeChanged(node.Id)
+	slog.Warn("node secret reset", "node id", node.Id, "kind", kind, "uid", uid)
+	return node, nil
+}
+
+// IssueNodeCert 给节点签发证书，节点首次申请和续期都调用，之前签发给节点的证书立即失效
+func (s *NodeService) IssueNodeCert(id int, req dto.ReqNodeCert) (dto.RespNodeCert, error) {
+	if !nodecert.Enabled() {
+		return dto.RespNodeCert{}, ErrTLSDisabled
+	}
+	node, err := s.NodeRepo.QueryById(id)
+	if err != nil {
+		return dto.RespNodeCert{}, err
+	}
+	cert, certPEM, err := nodecert.Issue(node, []byte(req.Csr))
+	if errors.Is(err, pki.ErrInvalidPEM) || errors.Is(err, pki.ErrInvalidCSR) {
+		return dto.RespNodeCert{}, ErrNodeCsr
+	}
+	if err != nil {
+		return dto.RespNodeCert{}, err
+	}
+	serial, expireTime := pki.Serial(cert), cert.NotAfter.Unix()
+	if err = s.updateNodeCert(id, serial, expireTime); err != nil {
+		return dto.RespNodeCert{}, err
+	}
+	slog.Info("node cert issued", "node id", id, "serial", serial, "expire", cert.NotAfter)
+	return dto.RespNodeCert{
+		Cert:       string(certPEM),
+		CA:         string(nodecert.CACertPEM()),
+		ExpireTime: expireTime,
+	}, nil
+}
+
+// RevokeNodeCert 吊销节点当前的证书，节点仍可以使用自己的 token 重新申请，需要同时重置密钥才能禁止节点访问
+func (s *NodeService) RevokeNodeCert(id int) error {
+	if _, err := s.NodeRepo.QueryById(id); err != nil {
+		return err
+	}
+	if err := s.updateNodeCert(id, "", 0); err != nil {
+		return err
+	}
+	slog.Warn("node cert revoked", "node id", id)
+	return nil
+}
+
+func (s *NodeService) updateNodeCert(id int, serial string, expireTime int64) error {
+	if err := s.NodeRepo.UpdateCert(id, serial, expireTime); err != nil {
+		return err
+	}
+	nodeMetrics := metrics.GetNodeMetrics()
+	if m, ok := nodeMetrics.Get(id); ok {
+		n := m.Node
+		n.CertSerial = serial
+		n.CertExpireTime = expireTime
+		nodeMetrics.Set(id, n)
+	}
+	publishNodeChanged(id)
+	return nil
+}
+
+// publishNodeChanged 通知其他 master 重新加载节点
+func publishNodeChanged(id int) {
+	cluster.Publish(cluster.Event{Type: cluster.EventNodeChanged, NodeId: id})
+}
+
+// GetNodeResource 节点当前的状态和保留的资源数据
+func (s *NodeService) GetNodeResource(id int, req dto.ReqNodeResource) (dto.RespNodeResource, error) {
+	var resp dto.RespNodeResource
+	node, err := s.NodeRepo.QueryById(id)
+	if err != nil {
+		return resp, err
+	}
+	nodeMetrics := metrics.GetNodeMetrics()
+	if m, ok := nodeMetrics.Get(id); ok {
+		node.Online = m.Online
+		node.CheckTime = m.CheckTime
+		node.HeartbeatTime = m.HeartbeatTime
+		node.Resource = m.Resource
+	}
+	history, _ := nodeMetrics.History(id, req.Begin, req.End)
+	resp.Node = node
+	resp.History = history
+	return resp, nil
+}
+
+// GetNodeJobs 节点上实际持有的任务及正在执行的进程
+func (s *NodeService) GetNodeJobs(id int) (dto.RespNodeJobInventory, error) {
+	inventory := dto.RespNodeJobInventory{NodeId: id}
+	node, err := s.NodeRepo.QueryById(id)
+	if err != nil {
+		return inventory, err
+	}
+	inventory.NodeName = node.Name
+	url := httpClient.URL(node.Address, paths.NodeJobAPI.BasePath+paths.NodeJobAPI.List)
+	resp, err := httpClient.GetJson(context.Background(), url, nil, nil, httpClient.DefaultTimeout)
+	if err != nil {
+		slog.Error("get job list from node error", "url", url, "err", err)
+		return inventory, err
+	}
+	listResp, err := httpClient.ParseResponseWith[nodeDataResp[[]dto.RespNodeJob]](resp)
+	if err != nil {
+		slog.Error("get job list parse error", "url", url, "resp", resp, "err", err)
+		return inventory, err
+	}
+	if listResp.Code != 0 {
+		slog.Error("resp code isn't zero", "resp", resp)
+		return inventory, errors.New("resp code isn't zero in get job list")
+	}
+	inventory.Jobs = listResp.Data
+	for _, job := range inventory.Jobs {
+		inventory.Running += len(job.Processes)
+	}
+	return inventory, nil
+}
+
+// syncTargetJobs 分组、标签变化后，节点选择器匹配到该节点的任务需要同步过去
+func (s *NodeService) syncTargetJobs(node model.Node) {
+	if err := s.jobSvc.SyncTargetJobs(node); err != nil {
+		slog.Error("sync target jobs to node error", "node id", node.Id, "err", err)
+	}
+}
+
+// parseNodeLabels 校验节点分组和标签
+func parseNodeLabels(node *model.Node) error {
+	node.Group = strings.TrimSpace(node.Group)
+	if len(node.Group) > maxLabelLen {
+		return ErrNodeLabel
+	}
+	labels, err := parseLabels(node.Labels)
+	if err != nil {
+		return err
+	}
+	node.Labels = labels
+	return nil
+}
+
+// parseLabels 去掉标签名和值两端的空格，标签名不能为空
+func parseLabels(labels map[string]string) (map[string]string, error) {
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	result := make(map[string]string, len(labels))
+	for k, v := range labels {
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if k == "" || len(k) > maxLabelLen || len(v) > maxLabelLen {
+			return nil, ErrNodeLabel
+		}
+		result[k] = v
+	}
+	return result, nil
+}
+
 func (s *NodeService) InstallRef(req dto.ReqNodeRef) (any, error) {
+	if req.Type == model.NodeInstallPyRefType {
+		if _, ok := utils.PyRequirementName(req.PkgName); !ok {
+			return nil, ErrPyPkgName
+		}
+	}
 	node, err := s.NodeRepo.QueryById(req.Id)
 	if err != nil {
 		return nil, err
 	}
 
-	url := fmt.Sprintf("http://%s/api/go-job/node/install_ref", node.Address)
+	url := httpClient.URL(node.Address, "/api/go-job/node/install_ref")
 	resp, err := httpClient.PostJson(context.Background(), url, nil, req, time.Second*10)
 	if err != nil {
 		slog.Error("send pkg name to node error by install ref", "url", url,
@@ -113,7 +533,7 @@ func (s *NodeService) NodeInfo(id int) (any, error) {
 	if err != nil {
 		return nil, err
 	}
-	url := fmt.Sprintf("http://%s/api/go-job/node/info", node.Address)
+	url := httpClient.URL(node.Address, "/api/go-job/node/info")
 	resp, err := httpClient.GetJson(context.Background(), url, nil, nil, time.Second*10)
 	if err != nil {
 		slog.Error("send pkg name to node error by install ref", "url", url, "err", err)
@@ -132,9 +552,103 @@ func (s *NodeService) NodeInfo(id int) (any, error) {
 	return nodeResp.Data, nil
 }
 
-func NewNodeService(nodeRepo repo.INodeRepo, jobRepo repo.IJobRepo) INodeService {
+// PauseScheduler 暂停节点上所有任务的调度
+func (s *NodeService) PauseScheduler(id int) error {
+	return s.postToNode(id, paths.NodeSchedulerPauseAPI)
+}
+
+// ResumeScheduler 恢复节点上所有任务的调度
+func (s *NodeService) ResumeScheduler(id int) error {
+	return s.postToNode(id, paths.NodeSchedulerResumeAPI)
+}
+
+// postToNode 调用节点上不需要参数的接口，如暂停调度，进入维护
+func (s *NodeService) postToNode(id int, path string) error {
+	node, err := s.NodeRepo.QueryById(id)
+	if err != nil {
+		return err
+	}
+	url := httpClient.URL(node.Address, path)
+	resp, err := httpClient.PostJson(context.Background(), url, nil, nil, httpClient.DefaultTimeout)
+	if err != nil {
+		slog.Error("post to node error", "url", url, "err", err)
+		return err
+	}
+	nodeResp, err := httpClient.ParseResponse(resp)
+	if err != nil {
+		slog.Error("post to node parse error", "url", url, "resp", resp, "err", err)
+		return err
+	}
+	if nodeResp.Code != 0 {
+		slog.Error("resp code isn't zero", "resp", resp)
+		return errors.New("resp code isn't zero in post to node")
+	}
+	return nil
+}
+
+func NewNodeService(nodeRepo repo.INodeRepo, jobRepo repo.IJobRepo, jobSvc IJobService) INodeService {
 	return &NodeService{
 		NodeRepo: nodeRepo,
 		JobRepo:  jobRepo,
+		jobSvc:   jobSvc,
 	}
 }
+
+// EnterMaintenance 节点进入维护状态，节点上的触发都跳过执行
+func (s *NodeService) EnterMaintenance(id int) error {
+	if err := s.postToNode(id, paths.NodeMaintenanceEnterAPI); err != nil {
+		return err
+	}
+	return s.setMaintenance(id, true)
+}
+
+// ExitMaintenance 节点退出维护状态
+func (s *NodeService) ExitMaintenance(id int) error {
+	if err := s.postToNode(id, paths.NodeMaintenanceExitAPI); err != nil {
+		return err
+	}
+	return s.setMaintenance(id, false)
+}
+
+// DrainNode 节点进入维护状态，并等待正在执行的任务结束
+func (s *NodeService) DrainNode(id int, req dto.ReqNodeDrain) (dto.RespNodeDrain, error) {
+	var result dto.RespNodeDrain
+	node, err := s.NodeRepo.QueryById(id)
+	if err != nil {
+		return result, err
+	}
+	// 节点最多等待10分钟，请求超时需要比等待时间更长
+	timeout := time.Duration(req.TimeoutSeconds)*time.Second + httpClient.DefaultTimeout
+	if req.TimeoutSeconds <= 0 || req.TimeoutSeconds > maxDrainSeconds {
+		timeout = maxDrainSeconds*time.Second + httpClient.DefaultTimeout
+	}
+	url := httpClient.URL(node.Address, paths.NodeMaintenanceDrainAPI)
+	resp, err := httpClient.PostJson(context.Background(), url, nil, req, timeout)
+	if err != nil {
+		slog.Error("drain node error", "url", url, "err", err)
+		return result, err
+	}
+	drainResp, err := httpClient.ParseResponseWith[nodeDataResp[dto.RespNodeDrain]](resp)
+	if err != nil {
+		slog.Error("drain node parse error", "url", url, "resp", resp, "err", err)
+		return result, err
+	}
+	if drainResp.Code != 0 {
+		slog.Error("resp code isn't zero", "resp", resp)
+		return result, errors.New("resp code isn't zero in drain node")
+	}
+	// 节点接收到请求后就已经进入维护状态
+	if err = s.setMaintenance(id, true); err != nil {
+		return result, err
+	}
+	return drainResp.Data, nil
+}
+
+func (s *NodeService) setMaintenance(id int, maintenance bool) error {
+	if err := s.NodeRepo.UpdateMaintenance(id, maintenance); err != nil {
+		return err
+	}
+	metrics.GetNodeMetrics().SetMaintenance(id, maintenance)
+	publishNodeChanged(id)
+	return nil
+}
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-job/internal/dto"
+	"go-job/internal/model"
+	"go-job/internal/pkg/httpClient"
+	"go-job/internal/pkg/paths"
+	"go-job/internal/pkg/utils"
+	"go-job/master/pkg/cluster"
+	"go-job/master/pkg/config"
+	"go-job/master/pkg/metrics"
+	"go-job/master/repo"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultReconcileInterval 默认的对账间隔
+const defaultReconcileInterval = 300 * time.Second
+
+// IReconcileService 定期对比节点上的任务和数据库，修复下发失败、节点重启等原因导致的差异
+type IReconcileService interface {
+	Run(ctx context.Context)
+	Reconcile(dryRun bool) (dto.RespReconcileReport, error)
+	GetReport() (dto.RespReconcileReport, bool)
+}
+
+type ReconcileService struct {
+	jobRepo repo.IJobRepo
+	jobSvc  IJobService
+
+	running atomic.Bool
+	mux     sync.RWMutex
+	report  *dto.RespReconcileReport // 最近一次对账的结果，只保存在执行对账的 master 内存中
+}
+
+// Run 定期对账，多个 master 时只由 leader 对账，需要在初始化节点指标后调用
+func (s *ReconcileService) Run(ctx context.Context) {
+	interval := time.Duration(config.App.Reconcile.Interval) * time.Second
+	if interval < 0 {
+		return
+	}
+	if interval == 0 {
+		interval = defaultReconcileInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !cluster.IsLeader() {
+				continue
+			}
+			if _, err := s.Reconcile(config.App.Reconcile.DryRun); err != nil && !errors.Is(err, ErrReconcileRunning) {
+				slog.Error("reconcile jobs error", "err", err)
+			}
+		}
+	}
+}
+
+// Reconcile 对比所有在线节点上的任务，dryRun 为 true 时只记录差异
+func (s *ReconcileService) Reconcile(dryRun bool) (dto.RespReconcileReport, error) {
+	if !cluster.IsLeader() {
+		return dto.RespReconcileReport{}, ErrNotLeader
+	}
+	if !s.running.CompareAndSwap(false, true) {
+		return dto.RespReconcileReport{}, ErrReconcileRunning
+	}
+	defer s.running.Store(false)
+
+	report := dto.RespReconcileReport{
+		StartTime: time.Now(),
+		DryRun:    dryRun,
+	}
+	var nodes []model.Node
+	for _, node := range metrics.GetNodeMetrics().Nodes() {
+		if node.Online {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Slice(nodes, func(a, b int) bool { return nodes[a].Id < nodes[b].Id })
+
+	// 先查询节点再查询数据库，避免把查询数据库之后才下发的任务当作多余的任务
+	states := make(map[int][]dto.RespNodeJobState, len(nodes))
+	for _, node := range nodes {
+		nodeStates, err := s.nodeJobStates(node)
+		if err != nil {
+			report.NodeErrors = append(report.NodeErrors, dto.RespReconcileNodeError{
+				NodeId:   node.Id,
+				NodeName: node.Name,
+				Error:    err.Error(),
+			})
+			continue
+		}
+		states[node.Id] = nodeStates
+	}
+	jobs, err := s.jobRepo.QueryAll()
+	if err != nil {
+		return report, err
+	}
+
+	checksums := make(map[int]string, len(jobs))
+	for _, job := range jobs {
+		checksums[job.Id] = masterFileChecksum(job)
+	}
+	for _, node := range nodes {
+		nodeStates, ok := states[node.Id]
+		if !ok {
+			continue
+		}
+		report.NodeCount++
+		for _, diff := range diffNodeJobs(node, jobs, nodeStates, checksums) {
+			if !dryRun {
+				s.repair(&diff, node)
+			}
+			report.Diffs = append(report.Diffs, diff.RespReconcileDiff)
+		}
+	}
+	report.EndTime = time.Now()
+	slog.Info("reconcile jobs finished", "nodes", report.NodeCount, "diffs", len(report.Diffs),
+		"node errors", len(report.NodeErrors), "dry run", dryRun, "cost", report.EndTime.Sub(report.StartTime))
+
+	s.mux.Lock()
+	s.report = &report
+	s.mux.Unlock()
+	return report, nil
+}
+
+// GetReport 最近一次对账的结果，没有对账过时返回 false
+func (s *ReconcileService) GetReport() (dto.RespReconcileReport, bool) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	if s.report == nil {
+		return dto.RespReconcileReport{}, false
+	}
+	return *s.report, true
+}
+
+// reconcileDiff 对账差异，job 为数据库中的任务，节点上多余并且数据库中不存在的任务为空
+type reconcileDiff struct {
+	dto.RespReconcileDiff
+	job *model.Job
+}
+
+func (d *reconcileDiff) has(typ dto.ReconcileDiffType) bool {
+	return slices.Contains(d.Types, typ)
+}
+
+// diffNodeJobs 对比节点上应有的任务和实际的任务，按任务id排序
+func diffNodeJobs(node model.Node, jobs []model.Job, states []dto.RespNodeJobState,
+	checksums map[int]string) []reconcileDiff {
+	actual := make(map[int]dto.RespNodeJobState, len(states))
+	for _, state := range states {
+		actual[state.Id] = state
+	}
+
+	var diffs []reconcileDiff
+	for i := range jobs {
+		job := &jobs[i]
+		diff := reconcileDiff{
+			RespReconcileDiff: dto.RespReconcileDiff{
+				NodeId:   node.Id,
+				NodeName: node.Name,
+				JobId:    job.Id,
+				JobName:  job.Name,
+			},
+			job: job,
+		}
+		state, ok := actual[job.Id]
+		delete(actual, job.Id)
+		if !jobOnNode(*job, node) {
+			// 故障转移前的节点上的任务由故障转移在节点恢复后停用
+			if ok && !slices.Contains(job.Internal.Failover.StaleNodeIds, node.Id) {
+				diff.Types = append(diff.Types, dto.ReconcileExtra)
+				diffs = append(diffs, diff)
+			}
+			continue
+		}
+		if !ok {
+			diff.Types = append(diff.Types, dto.ReconcileMissing)
+			diffs = append(diffs, diff)
+			continue
+		}
+		if state.Digest != nodeJobReq(*job).Digest() {
+			diff.Types = append(diff.Types, dto.ReconcileDefinition)
+		}
+		if state.Active != job.Active {
+			diff.Types = append(diff.Types, dto.ReconcileActive)
+		}
+		// master 上的文件不存在时无法比较
+		if checksum := checksums[job.Id]; checksum != "" && state.FileChecksum != checksum {
+			diff.Types = append(diff.Types, dto.ReconcileFile)
+		}
+		if len(diff.Types) > 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+	// 数据库中已经删除的任务
+	for id := range actual {
+		diffs = append(diffs, reconcileDiff{
+			RespReconcileDiff: dto.RespReconcileDiff{
+				NodeId:   node.Id,
+				NodeName: node.Name,
+				JobId:    id,
+				Types:    []dto.ReconcileDiffType{dto.ReconcileExtra},
+			},
+		})
+	}
+	sort.Slice(diffs, func(a, b int) bool { return diffs[a].JobId < diffs[b].JobId })
+	return diffs
+}
+
+// jobOnNode 任务是否应该在该节点上，设置了节点选择器时是所有匹配的节点
+func jobOnNode(job model.Job, node model.Node) bool {
+	if job.Internal.Target.Enabled() {
+		return job.Internal.Target.Match(node)
+	}
+	return job.NodeID == node.Id
+}
+
+// masterFileChecksum master 上任务文件的 sha256，文件不存在时为空
+func masterFileChecksum(job model.Job) string {
+	if job.Internal.FileMeta.UUIDFileName == "" {
+		return ""
+	}
+	path := filepath.Join(config.App.Data.UploadJobDir, job.Internal.FileMeta.UUIDFileName)
+	sum, err := utils.FileSHA256(path)
+	if err != nil {
+		slog.Error("reconcile job file checksum error", "job id", job.Id, "path", path, "err", err)
+		return ""
+	}
+	return sum
+}
+
+// repair 按数据库修复节点上的任务，文件不一致时只重新发送文件，定义或启用状态不一致时更新节点上的任务
+func (s *ReconcileService) repair(diff *reconcileDiff, node model.Node) {
+	var err error
+	switch {
+	case diff.has(dto.ReconcileExtra):
+		err = s.jobSvc.RemoveJobInNode(node, diff.JobId)
+	case diff.has(dto.ReconcileMissing):
+		err = s.jobSvc.SendDataToNode(*diff.job, node)
+	default:
+		if diff.has(dto.ReconcileFile) {
+			err = s.jobSvc.SendJobFileToNode(*diff.job, node)
+		}
+		if err == nil && (diff.has(dto.ReconcileDefinition) || diff.has(dto.ReconcileActive)) {
+			err = s.jobSvc.SendJobToNode(*diff.job, node, SendJobByUpdate)
+		}
+	}
+	if err != nil {
+		slog.Error("reconcile repair job error", "job id", diff.JobId, "node id", node.Id,
+			"types", diff.Types, "err", err)
+		diff.Error = err.Error()
+		return
+	}
+	diff.Repaired = true
+	slog.Warn("reconcile repaired job", "job id", diff.JobId, "node id", node.Id, "types", diff.Types)
+}
+
+// nodeJobStates 查询节点上所有任务的实际状态
+func (s *ReconcileService) nodeJobStates(node model.Node) ([]dto.RespNodeJobState, error) {
+	url := httpClient.URL(node.Address, paths.NodeJobAPI.BasePath+paths.NodeJobAPI.States)
+	resp, err := httpClient.GetJson(context.Background(), url, nil, nil, httpClient.DefaultTimeout)
+	if err != nil {
+		slog.Error("get job states from node error", "url", url, "err", err)
+		return nil, err
+	}
+	statesResp, err := httpClient.ParseResponseWith[nodeDataResp[[]dto.RespNodeJobState]](resp)
+	if err != nil {
+		slog.Error("get job states parse error", "url", url, "resp", resp, "err", err)
+		return nil, err
+	}
+	if statesResp.Code != 0 {
+		slog.Error("resp code isn't zero", "resp", resp)
+		return nil, errors.New("resp code isn't zero in get job states")
+	}
+	return statesResp.Data, nil
+}
+
+func NewReconcileService(jobRepo repo.IJobRepo, jobSvc IJobService) IReconcileService {
+	return &ReconcileService{
+		jobRepo: jobRepo,
+		jobSvc:  jobSvc,
+	}
+}
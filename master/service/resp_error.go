@@ -13,6 +13,38 @@ var (
 	ErrInvalidAddress     = errors.New("填写的地址格式不合法，格式：Ip:Port")
 	ErrJobUseCurrentNode  = errors.New("有任务依赖该节点，无法删除")
 	ErrUserNotPermission  = errors.New("您的权限不足，暂无法使用此功能")
+	ErrMisfirePolicy      = errors.New("不支持的补偿策略")
+	ErrMisfireMaxCount    = errors.New("补偿执行次数需要在1到100之间")
+	ErrTimeZone           = errors.New("时区无效")
+	ErrJitterMaxSeconds   = errors.New("随机延迟需要在0到3600秒之间")
+	ErrJobPriority        = errors.New("优先级需要在0到9之间")
+	ErrJobNotActive       = errors.New("任务未启用")
+	ErrWebhookNotFound    = errors.New("webhook不存在或已撤销")
+	ErrWebhookSignature   = errors.New("webhook签名校验失败")
+	ErrWebhookPayloadMode = errors.New("不支持的请求体传递方式")
+	ErrWebhookPayload     = errors.New("请求体不能超过64KB")
+	ErrScheduleType       = errors.New("不支持的调度方式")
+	ErrWatchPath          = errors.New("监听路径需要是节点上的绝对路径，支持 glob")
+	ErrWatchStableSeconds = errors.New("文件稳定时间需要在1到3600秒之间")
+	ErrWatchProcessedDir  = errors.New("处理后的目录需要是节点上的绝对路径")
+	ErrHandlerJob         = errors.New("处理任务不存在或不能选择当前任务")
+	ErrNodeLabel          = errors.New("标签名不能为空，分组、标签名和值不能超过63个字符")
+	ErrTargetSchedule     = errors.New("文件触发的任务不能使用节点选择器")
+	ErrBalanceStrategy    = errors.New("不支持的负载均衡策略")
+	ErrNoMatchNode        = errors.New("没有匹配节点选择器的节点")
+	ErrFailoverTarget     = errors.New("使用节点选择器的任务不需要故障转移")
+	ErrFailoverGrace      = errors.New("故障转移的宽限时间需要在30到86400秒之间")
+	ErrTargetMode         = errors.New("不支持的执行方式，广播和分片需要设置节点选择器")
+	ErrMinSuccess         = errors.New("至少成功的节点数不能小于0")
+	ErrRegisterDisabled   = errors.New("未开启节点自注册")
+	ErrBootstrapToken     = errors.New("节点注册token错误")
+	ErrRegisterNodeName   = errors.New("已有同名的手动添加的节点，不能通过自注册覆盖")
+	ErrNotLeader          = errors.New("当前 master 不是 leader")
+	ErrReconcileRunning   = errors.New("正在对账，请稍后再试")
+	ErrPyPkgName          = errors.New("python 包名无效，只支持包名、extras 和一个版本约束")
+	ErrNodeJobForbidden   = errors.New("节点不能上报其他节点上的任务的执行结果")
+	ErrTLSDisabled        = errors.New("master 未开启 TLS")
+	ErrNodeCsr            = errors.New("证书请求无效")
 )
 
 var returnErrList = []error{
@@ -26,6 +58,38 @@ var returnErrList = []error{
 	ErrInvalidAddress,
 	ErrJobUseCurrentNode,
 	ErrUserNotPermission,
+	ErrMisfirePolicy,
+	ErrMisfireMaxCount,
+	ErrTimeZone,
+	ErrJitterMaxSeconds,
+	ErrJobPriority,
+	ErrJobNotActive,
+	ErrWebhookNotFound,
+	ErrWebhookSignature,
+	ErrWebhookPayloadMode,
+	ErrWebhookPayload,
+	ErrScheduleType,
+	ErrWatchPath,
+	ErrWatchStableSeconds,
+	ErrWatchProcessedDir,
+	ErrHandlerJob,
+	ErrNodeLabel,
+	ErrTargetSchedule,
+	ErrBalanceStrategy,
+	ErrNoMatchNode,
+	ErrFailoverTarget,
+	ErrFailoverGrace,
+	ErrTargetMode,
+	ErrMinSuccess,
+	ErrRegisterDisabled,
+	ErrBootstrapToken,
+	ErrRegisterNodeName,
+	ErrNotLeader,
+	ErrReconcileRunning,
+	ErrPyPkgName,
+	ErrNodeJobForbidden,
+	ErrTLSDisabled,
+	ErrNodeCsr,
 }
 
 func IsRespErr(err error) bool {
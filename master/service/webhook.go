@@ -0,0 +1,173 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"go-job/internal/dto"
+	"go-job/internal/model"
+	"go-job/master/repo"
+	"gorm.io/gorm"
+	"strings"
+)
+
+const (
+	// MaxWebhookPayload webhook 请求体的上限
+	MaxWebhookPayload = 64 << 10
+
+	// WebhookSignatureHeader 请求体的 HMAC-SHA256 签名，格式：sha256=<hex>
+	WebhookSignatureHeader = "X-Go-Job-Signature"
+
+	// webhookPayloadEnv 以环境变量传递请求体时使用的变量名
+	webhookPayloadEnv = "GO_JOB_PAYLOAD"
+
+	webhookTokenBytes  = 24
+	webhookSecretBytes = 32
+)
+
+type IWebhookService interface {
+	GetWebhookList(uid, jobId int) ([]model.JobWebhook, error)
+	AddWebhook(req dto.ReqWebhook) (dto.RespWebhook, error)
+	RevokeWebhook(uid, id int) error
+	Trigger(token, signature string, payload []byte) error
+}
+
+type WebhookService struct {
+	webhookRepo repo.IWebhookRepo
+	jobRepo     repo.IJobRepo
+	recordSvc   IJobRecordService
+}
+
+func (s *WebhookService) GetWebhookList(uid, jobId int) ([]model.JobWebhook, error) {
+	if _, err := s.getUserJob(uid, jobId); err != nil {
+		return nil, err
+	}
+	return s.webhookRepo.QueryByJobId(jobId)
+}
+
+func (s *WebhookService) AddWebhook(req dto.ReqWebhook) (dto.RespWebhook, error) {
+	if _, err := s.getUserJob(req.UserId, req.JobId); err != nil {
+		return dto.RespWebhook{}, err
+	}
+	switch req.PayloadMode {
+	case 0:
+		req.PayloadMode = model.WebhookPayloadStdin
+	case model.WebhookPayloadStdin, model.WebhookPayloadEnv:
+	default:
+		return dto.RespWebhook{}, ErrWebhookPayloadMode
+	}
+
+	token, err := randomHex(webhookTokenBytes)
+	if err != nil {
+		return dto.RespWebhook{}, err
+	}
+	webhook := model.JobWebhook{
+		JobId:       req.JobId,
+		UserId:      req.UserId,
+		Token:       token,
+		PayloadMode: req.PayloadMode,
+		Status:      model.WebhookEnabled,
+	}
+	if req.WithSecret {
+		if webhook.Secret, err = randomHex(webhookSecretBytes); err != nil {
+			return dto.RespWebhook{}, err
+		}
+	}
+	if err = s.webhookRepo.Insert(&webhook); err != nil {
+		return dto.RespWebhook{}, err
+	}
+	return dto.RespWebhook{JobWebhook: webhook, Secret: webhook.Secret}, nil
+}
+
+func (s *WebhookService) RevokeWebhook(uid, id int) error {
+	webhook, err := s.webhookRepo.QueryById(id)
+	if err != nil {
+		return err
+	}
+	if webhook.UserId != uid {
+		return ErrUserNotPermission
+	}
+	return s.webhookRepo.UpdateStatus(id, model.WebhookRevoked)
+}
+
+// Trigger 校验webhook后通知节点立即执行一次任务
+func (s *WebhookService) Trigger(token, signature string, payload []byte) error {
+	if len(payload) > MaxWebhookPayload {
+		return ErrWebhookPayload
+	}
+	webhook, err := s.webhookRepo.QueryByToken(token)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrWebhookNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if webhook.Status != model.WebhookEnabled {
+		return ErrWebhookNotFound
+	}
+	if webhook.Secret != "" && !VerifyWebhookSignature(webhook.Secret, signature, payload) {
+		return ErrWebhookSignature
+	}
+
+	job, err := s.jobRepo.QueryById(webhook.JobId)
+	if err != nil {
+		return err
+	}
+	if job.Active != model.JobStart {
+		return ErrJobNotActive
+	}
+
+	req := dto.ReqNodeJobTrigger{TriggerType: model.TriggerWebhook}
+	if webhook.PayloadMode == model.WebhookPayloadEnv {
+		req.Env = map[string]string{webhookPayloadEnv: string(payload)}
+	} else {
+		req.Stdin = payload
+	}
+	return s.recordSvc.TriggerJob(job, req)
+}
+
+// getUserJob 查询任务并校验是否是当前用户创建的
+func (s *WebhookService) getUserJob(uid, jobId int) (model.Job, error) {
+	job, err := s.jobRepo.QueryById(jobId)
+	if err != nil {
+		return job, err
+	}
+	if job.UserId != uid {
+		return job, ErrUserNotPermission
+	}
+	return job, nil
+}
+
+// VerifyWebhookSignature 校验请求体的 HMAC-SHA256 签名
+func VerifyWebhookSignature(secret, signature string, payload []byte) bool {
+	sig, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func NewWebhookService(webhookRepo repo.IWebhookRepo, jobRepo repo.IJobRepo,
+	recordSvc IJobRecordService) IWebhookService {
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+		jobRepo:     jobRepo,
+		recordSvc:   recordSvc,
+	}
+}
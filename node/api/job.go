@@ -31,7 +31,9 @@ func (h *JobApi) RegisterRoutes(server *gin.RouterGroup) {
 	jh.PUT("", h.UpdateJob)
 	jh.GET("", h.GetJob)
 	jh.POST("/upload", h.UploadFile)
-	//jh.GET("", h.GetJobList)  todo 待实现
+	jh.POST("/:id/trigger", h.TriggerJob)
+	jh.GET("/states", h.GetJobStates)
+	jh.GET("/list", h.GetJobList)
 }
 
 // AddJob 添加任务
@@ -53,6 +55,28 @@ func (h *JobApi) AddJob(ctx *gin.Context) {
 	dto.NewJsonResp(ctx).Success()
 }
 
+// TriggerJob 立即触发一次任务
+func (h *JobApi) TriggerJob(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+	var req dto.ReqNodeJobTrigger
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		slog.Error("trigger job bind json err:", "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+
+	if err := h.JobService.TriggerJob(ctx.Request.Context(), id, req); err != nil {
+		slog.Error("trigger job error", "id", id, "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.JobTriggerFailed)
+		return
+	}
+	dto.NewJsonResp(ctx).Success()
+}
+
 func (h *JobApi) DeleteJob(ctx *gin.Context) {
 	idStr := ctx.Param("id")
 	id, err := strconv.Atoi(idStr)
@@ -87,23 +111,22 @@ func (h *JobApi) GetJob(ctx *gin.Context) {
 		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
 		return
 	}
-	job, err := h.JobService.GetJob(ctx.Request.Context(), req.Id)
+	info, err := h.JobService.GetJobInfo(ctx.Request.Context(), req.Id)
 	if err != nil {
 		dto.NewJsonResp(ctx).Fail(dto.JobNotExist)
 		return
 	}
-	dto.NewJsonResp(ctx).Success(dto.RespNodeJob{
-		Id:            job.JobMeta.Id,
-		Name:          job.JobMeta.Name,
-		ExecType:      job.JobMeta.ExecType,
-		RunningStatus: job.RunningStatus,
-		FileName:      job.JobMeta.FileName,
-	})
+	dto.NewJsonResp(ctx).Success(info)
+}
 
+// GetJobStates 节点上所有任务的实际状态，master 定期对账
+func (h *JobApi) GetJobStates(ctx *gin.Context) {
+	dto.NewJsonResp(ctx).Success(h.JobService.GetJobStates(ctx.Request.Context()))
 }
 
+// GetJobList 节点上所有任务的定义和运行时状态
 func (h *JobApi) GetJobList(ctx *gin.Context) {
-	dto.NewJsonResp(ctx).Success()
+	dto.NewJsonResp(ctx).Success(h.JobService.GetJobList(ctx.Request.Context()))
 }
 
 // UploadFile 接收文件
@@ -21,6 +21,11 @@ func NewNodeApi(NodService service.INodeService) *NodeApi {
 func (h *NodeApi) RegisterRoutes(server *gin.RouterGroup) {
 	server.POST("/install_ref", h.InstallRef)
 	server.GET("info", h.NodeInfo)
+	server.POST("/scheduler/pause", h.PauseScheduler)
+	server.POST("/scheduler/resume", h.ResumeScheduler)
+	server.POST("/maintenance/enter", h.EnterMaintenance)
+	server.POST("/maintenance/exit", h.ExitMaintenance)
+	server.POST("/maintenance/drain", h.Drain)
 }
 
 // InstallRef 安装依赖
@@ -49,3 +54,38 @@ func (h *NodeApi) NodeInfo(ctx *gin.Context) {
 	data := h.NodeService.GetNodeInfo(ctx.Request.Context())
 	dto.NewJsonResp(ctx).Success(data)
 }
+
+// PauseScheduler 暂停节点调度
+func (h *NodeApi) PauseScheduler(ctx *gin.Context) {
+	h.NodeService.PauseScheduler(ctx.Request.Context())
+	dto.NewJsonResp(ctx).Success()
+}
+
+// ResumeScheduler 恢复节点调度
+func (h *NodeApi) ResumeScheduler(ctx *gin.Context) {
+	h.NodeService.ResumeScheduler(ctx.Request.Context())
+	dto.NewJsonResp(ctx).Success()
+}
+
+// EnterMaintenance 节点进入维护状态
+func (h *NodeApi) EnterMaintenance(ctx *gin.Context) {
+	h.NodeService.EnterMaintenance(ctx.Request.Context())
+	dto.NewJsonResp(ctx).Success()
+}
+
+// ExitMaintenance 节点退出维护状态
+func (h *NodeApi) ExitMaintenance(ctx *gin.Context) {
+	h.NodeService.ExitMaintenance(ctx.Request.Context())
+	dto.NewJsonResp(ctx).Success()
+}
+
+// Drain 进入维护状态并等待正在执行的任务结束
+func (h *NodeApi) Drain(ctx *gin.Context) {
+	var req dto.ReqNodeDrain
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		slog.Error("drain node bind json err:", "err", err)
+		dto.NewJsonResp(ctx).Fail(dto.ParamsError)
+		return
+	}
+	dto.NewJsonResp(ctx).Success(h.NodeService.Drain(ctx.Request.Context(), req))
+}
@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"go-job/internal/pkg/auth"
+	"net/http"
+	"sync"
+)
+
+// 节点校验 master 请求签名的密钥，手动添加的节点在配置中设置，自注册的节点使用注册时 master 返回的
+var (
+	secretMux sync.RWMutex
+	secret    string
+	verifier  = auth.NewVerifier(auth.DefaultSignWindow)
+)
+
+// SetSecret 设置校验签名的密钥
+func SetSecret(s string) {
+	secretMux.Lock()
+	defer secretMux.Unlock()
+	secret = s
+}
+
+func GetSecret() string {
+	secretMux.RLock()
+	defer secretMux.RUnlock()
+	return secret
+}
+
+// VerifyRequest 校验 master 请求的签名，body 为已经读取的请求体
+func VerifyRequest(req *http.Request, body []byte) error {
+	return verifier.Verify(GetSecret(), req, body)
+}
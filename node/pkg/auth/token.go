@@ -1,10 +1,12 @@
 package auth
 
 import (
+	"errors"
 	"github.com/golang-jwt/jwt/v5"
 	"go-job/internal/model"
 	"go-job/internal/pkg/auth"
 	"log/slog"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -16,41 +18,96 @@ const (
 	defaultUserId           = -1
 )
 
+var ErrNoCredential = errors.New("节点没有访问 master 的凭证，需要配置 master.node_id 和 master.token_secret 或开启自注册")
+
 type JwtToken struct {
 	builder     *auth.JwtBuilder // TODO 这里可以改成接口，不同的校验都去实现生成和校验接口，这样就热插拔的方式了
 	token       string
+	credential  string        // 生成 token 时使用的节点id和密钥，变化后重新生成
 	ExpireTime  time.Time     `json:"expire_time"`  // 过期时间
 	RefreshTime time.Duration `json:"refresh_time"` // 过期前多久刷新
 	mux         sync.RWMutex
 }
 
-var jt = &JwtToken{}
+var (
+	jt = &JwtToken{}
+
+	credentialMux sync.RWMutex
+	nodeId        int
+	tokenSecret   string // 生成访问 master 的 token 的密钥，和校验 master 请求签名的密钥不同
+)
 
+// InitJwtToken key 为 master 的 jwt key，只用于兼容没有节点凭证的旧版本部署，为空时不使用
 func InitJwtToken(key string) {
-	jt.builder = auth.NewJwtBuilder(key)
+	if key != "" {
+		jt.builder = auth.NewJwtBuilder(key)
+	}
 	jt.RefreshTime = defaultRefreshTimeLimit
 }
 
+// SetNodeId 设置节点在 master 上的id，手动添加的节点在配置中设置，自注册的节点使用注册时 master 返回的
+func SetNodeId(id int) {
+	credentialMux.Lock()
+	defer credentialMux.Unlock()
+	nodeId = id
+}
+
+func GetNodeId() int {
+	credentialMux.RLock()
+	defer credentialMux.RUnlock()
+	return nodeId
+}
+
+// SetTokenSecret 设置生成 token 的密钥，手动添加的节点在配置中设置，自注册的节点使用注册时 master 返回的
+func SetTokenSecret(s string) {
+	credentialMux.Lock()
+	defer credentialMux.Unlock()
+	tokenSecret = s
+}
+
+func GetTokenSecret() string {
+	credentialMux.RLock()
+	defer credentialMux.RUnlock()
+	return tokenSecret
+}
+
+// RefreshToken 有节点id和 token 密钥时生成节点自己的 token，否则使用 master 的 key 生成内部用户的 token
 func RefreshToken() error {
+	id, secret := GetNodeId(), GetTokenSecret()
+	credential := ""
+	if id > 0 && secret != "" {
+		credential = strconv.Itoa(id) + ":" + secret
+	}
+
 	jt.mux.Lock()
 	defer jt.mux.Unlock()
-	// 初始化token， 快过期刷新token
-	if jt.token == "" || jt.ExpireTime.Sub(time.Now()) < jt.RefreshTime {
-		jt.ExpireTime = time.Now().Add(defaultExpireTime)
-		uc := model.UserClaims{
-			RegisteredClaims: jwt.RegisteredClaims{
-				ExpiresAt: jwt.NewNumericDate(jt.ExpireTime),
-			},
-			Uid: defaultUserId,
-		}
-		token, err := jt.builder.GenerateToken(uc)
-		if err != nil {
-			return err
-		}
-		jt.token = token
-		slog.Info("refresh token success", "token", jt.token,
-			"expire_time", jt.ExpireTime, "refresh_time", jt.RefreshTime)
+	// 初始化token， 快过期或凭证变化时刷新token
+	if jt.token != "" && jt.credential == credential && jt.ExpireTime.Sub(time.Now()) >= jt.RefreshTime {
+		return nil
+	}
+	expireTime := time.Now().Add(defaultExpireTime)
+	claims := jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(expireTime),
+	}
+	var (
+		token string
+		err   error
+	)
+	switch {
+	case credential != "":
+		token, err = auth.NewJwtBuilder(secret).GenerateToken(model.NodeClaims{RegisteredClaims: claims, NodeId: id})
+	case jt.builder != nil:
+		token, err = jt.builder.GenerateToken(model.UserClaims{RegisteredClaims: claims, Uid: defaultUserId})
+	default:
+		return ErrNoCredential
+	}
+	if err != nil {
+		return err
 	}
+	jt.token = token
+	jt.credential = credential
+	jt.ExpireTime = expireTime
+	slog.Info("refresh token success", "node id", id, "expire_time", jt.ExpireTime, "refresh_time", jt.RefreshTime)
 	return nil
 }
 
@@ -3,22 +3,66 @@ package config
 var App *Application
 
 type Application struct {
-	Server Server
-	Data   Data
-	Master Master
+	Server   Server
+	Data     Data
+	Master   Master
+	Worker   Worker
+	Register Register
+	Outbox   Outbox
+	TLS      TLS `mapstructure:"tls"`
 }
 
 type Server struct {
-	Port uint16
-	Name string
-	Ip   string
+	Port            uint16
+	Name            string
+	Ip              string
+	ShutdownTimeout int `mapstructure:"shutdown_timeout"` // 退出时等待正在执行的任务和结果上报的最长秒数
 }
 
 type Data struct {
 	UploadJobDir string `mapstructure:"upload_job_dir"`
+	JobStoreFile string `mapstructure:"job_store_file"` // 保存任务定义的本地文件，为空时不保存
+	StateFile    string `mapstructure:"state_file"`     // 保存维护状态的本地文件，为空时不保存
+}
+
+type Worker struct {
+	Size      int `mapstructure:"size"`       // 同时执行的任务数，默认为CPU核数
+	QueueSize int `mapstructure:"queue_size"` // 排队的最大任务数
+	MaxWait   int `mapstructure:"max_wait"`   // 排队的最大等待秒数，超时后丢弃
+}
+
+// Outbox 执行结果先写入本地文件再上报到 master，上报失败时重试
+type Outbox struct {
+	Dir        string `mapstructure:"dir"`         // 保存待上报结果的目录，为空时直接上报不重试
+	MaxBackoff int    `mapstructure:"max_backoff"` // 重试的最长间隔秒数
+	Retention  int    `mapstructure:"retention"`   // 结果最长保留的小时数，超过后丢弃
+}
+
+// Register 启动时自注册到 master，之后定期上报心跳
+type Register struct {
+	Enabled          bool              `mapstructure:"enabled"`
+	BootstrapToken   string            `mapstructure:"bootstrap_token"`
+	AdvertiseAddress string            `mapstructure:"advertise_address"` // master 访问节点的地址，默认为本机IP和 server.port
+	Group            string            `mapstructure:"group"`
+	Labels           map[string]string `mapstructure:"labels"`
 }
 
 type Master struct {
 	Address string `mapstructure:"address"`
-	Key     string `mapstructure:"key"`
+	Key     string `mapstructure:"key"`     // master 的 jwt key，只用于兼容旧版本，配置 node_id 和 token_secret 后不需要
+	NodeId  int    `mapstructure:"node_id"` // 手动添加的节点在 master 上的id，和 token_secret 一起生成访问 master 的 token
+	Tunnel  bool   `mapstructure:"tunnel"`  // 主动连接 master，master 通过该连接访问节点，节点不需要开放端口
+	Secret  string `mapstructure:"secret"`  // 校验 master 请求签名的密钥，自注册的节点使用注册时 master 返回的
+
+	TokenSecret string `mapstructure:"token_secret"` // 生成访问 master 的 token 的密钥，自注册的节点使用注册时 master 返回的
+
+	InsecureSkipVerify bool `mapstructure:"insecure_skip_verify"` // 没有密钥时不校验 master 请求的签名，只用于升级期间
+}
+
+// TLS 和 master 之间使用双向 TLS，节点的证书由 master 签发
+type TLS struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	CAFile   string `mapstructure:"ca_file"`   // master 的 CA 证书，从 master 的 tls.ca_cert 复制
+	CertFile string `mapstructure:"cert_file"` // master 签发的证书，不存在或快过期时向 master 申请
+	KeyFile  string `mapstructure:"key_file"`  // 节点私钥，不存在时生成
 }
@@ -8,13 +8,13 @@ type ExampleStrategy struct {
 	executor IExecutor
 }
 
-func (e *ExampleStrategy) Run() {
-	e.executor.Run()
+func (e *ExampleStrategy) Run(trigger Trigger) {
+	e.executor.Run(trigger)
 }
 
-func (e *ExampleStrategy) Execute() (string, error) {
+func (e *ExampleStrategy) Execute(trigger Trigger) (string, error) {
 	// todo 这里可以做执行前的一些限制
-	return e.executor.Execute()
+	return e.executor.Execute(trigger)
 
 }
 
@@ -22,16 +22,20 @@ func (e *ExampleStrategy) OnResultChange(f func(result model.JobExecResult)) {
 	e.executor.OnResultChange(f)
 }
 
-func (e *ExampleStrategy) ResultCallback(output string, err error) {
-	e.executor.ResultCallback(output, err)
+func (e *ExampleStrategy) ResultCallback(trigger Trigger, state *RunState, output string, err error) {
+	e.executor.ResultCallback(trigger, state, output, err)
 }
 
-func (e *ExampleStrategy) AfterExecute(err error) {
-	e.executor.AfterExecute(err)
+func (e *ExampleStrategy) AfterExecute(state *RunState, err error) {
+	e.executor.AfterExecute(state, err)
 }
 
-func (e *ExampleStrategy) BeforeExecute() {
-	e.executor.BeforeExecute()
+func (e *ExampleStrategy) BeforeExecute() *RunState {
+	return e.executor.BeforeExecute()
+}
+
+func (e *ExampleStrategy) Processes() []Process {
+	return e.executor.Processes()
 }
 
 func NewExampleStrategy(executor IExecutor) IExecutor {
@@ -7,8 +7,11 @@ import (
 	"go-job/internal/model"
 	"go-job/internal/pkg/utils"
 	"go-job/node/pkg/config"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"sync"
 	"time"
 )
 
@@ -19,50 +22,55 @@ type FileExecutor struct {
 	name           string
 	ext            string
 	fileName       string
-	runningStatus  model.JobStatus
-	startExecTime  time.Time
-	endExecTime    time.Time
 	onResultChange func(result model.JobExecResult) // 注册回调事件， 后续可以优化为channel的方式接收结果
+
+	mux   sync.Mutex
+	procs map[int]time.Time // 正在执行的进程及开始时间，同一个任务可能同时执行多次
 }
 
-func (f *FileExecutor) Run() {
-	f.BeforeExecute()
-	output, err := f.Execute()
-	f.AfterExecute(err)
-	f.ResultCallback(output, err)
+func (f *FileExecutor) Run(trigger Trigger) {
+	state := f.BeforeExecute()
+	output, err := f.Execute(trigger)
+	f.AfterExecute(state, err)
+	f.ResultCallback(trigger, state, output, err)
 }
 
-func (f *FileExecutor) BeforeExecute() {
-	f.startExecTime = time.Now()
-	f.runningStatus = model.Running
+func (f *FileExecutor) BeforeExecute() *RunState {
+	return &RunState{Status: model.Running, StartTime: time.Now()}
 }
 
-func (f *FileExecutor) AfterExecute(err error) {
+func (f *FileExecutor) AfterExecute(state *RunState, err error) {
 	if err != nil {
-		f.runningStatus = model.Failed
+		state.Status = model.Failed
 	} else {
-		f.runningStatus = model.Success
+		state.Status = model.Success
 	}
-	f.endExecTime = time.Now()
+	state.EndTime = time.Now()
 }
 
-func (f *FileExecutor) buildJobExecResult(output string, err error) model.JobExecResult {
+func (f *FileExecutor) buildJobExecResult(trigger Trigger, state *RunState, output string, err error) model.JobExecResult {
 	runes := []rune(output)
 	if len(runes) > defaultOutputLen {
 		output = string(runes[:defaultOutputLen-3]) + "..."
 	}
 	result := model.JobExecResult{
-		StartTime: f.startExecTime.Unix(),
-		EndTime:   f.endExecTime.Unix(),
-		Duration:  f.endExecTime.Sub(f.startExecTime).Seconds(),
-		Status:    f.runningStatus,
+		StartTime: state.StartTime.Unix(),
+		EndTime:   state.EndTime.Unix(),
+		Duration:  state.EndTime.Sub(state.StartTime).Seconds(),
+		Status:    state.Status,
 		Output:    output,
 		Error:     utils.ErrorToString(err),
+
+		TriggerType:   trigger.Type,
+		ScheduledTime: trigger.ScheduledTime.Unix(),
+		JitterDelay:   trigger.JitterDelay.Seconds(),
+		RunId:         trigger.RunId,
+		NodeId:        trigger.NodeId,
 	}
 	return result
 }
 
-func (f *FileExecutor) Execute() (string, error) {
+func (f *FileExecutor) Execute(trigger Trigger) (string, error) {
 	var (
 		output string
 		err    error
@@ -70,7 +78,7 @@ func (f *FileExecutor) Execute() (string, error) {
 
 	switch f.ext {
 	case ".py":
-		output, err = f.execFile()
+		output, err = f.execFile(trigger)
 	default:
 		output = "不支持的文件类型"
 		err = errors.New("不支持的文件类型")
@@ -86,9 +94,9 @@ func (f *FileExecutor) OnResultChange(fn func(result model.JobExecResult)) {
 	f.onResultChange = fn
 }
 
-func (f *FileExecutor) ResultCallback(output string, err error) {
+func (f *FileExecutor) ResultCallback(trigger Trigger, state *RunState, output string, err error) {
 	if f.onResultChange != nil {
-		f.onResultChange(f.buildJobExecResult(output, err))
+		f.onResultChange(f.buildJobExecResult(trigger, state, output, err))
 	}
 }
 
@@ -98,13 +106,47 @@ func NewFileExecutor(id int, name, fileName string) *FileExecutor {
 		name:     name,
 		ext:      filepath.Ext(fileName),
 		fileName: fileName,
+		procs:    make(map[int]time.Time),
+	}
+}
+
+// Processes 正在执行的进程，按开始时间排序
+func (f *FileExecutor) Processes() []Process {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	procs := make([]Process, 0, len(f.procs))
+	for pid, start := range f.procs {
+		procs = append(procs, Process{Pid: pid, StartTime: start})
 	}
+	sort.Slice(procs, func(a, b int) bool { return procs[a].StartTime.Before(procs[b].StartTime) })
+	return procs
+}
+
+func (f *FileExecutor) addProcess(pid int) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.procs[pid] = time.Now()
 }
 
-func (f *FileExecutor) execFile() (output string, err error) {
+func (f *FileExecutor) removeProcess(pid int) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	delete(f.procs, pid)
+}
+
+func (f *FileExecutor) execFile(trigger Trigger) (output string, err error) {
 	// 执行文件，这是一次性捕获所有输出，无法实现实时捕获，
 	execFilePath := filepath.Join(config.App.Data.UploadJobDir, f.fileName)
 	cmd := exec.Command("python", execFilePath)
+	if len(trigger.Env) > 0 {
+		cmd.Env = os.Environ()
+		for k, v := range trigger.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+	if len(trigger.Stdin) > 0 {
+		cmd.Stdin = bytes.NewReader(trigger.Stdin)
+	}
 
 	var (
 		stderr bytes.Buffer
@@ -113,7 +155,15 @@ func (f *FileExecutor) execFile() (output string, err error) {
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed: %v, stderr: %s\n",
+			err, stderr.String())
+	}
+	pid := cmd.Process.Pid
+	f.addProcess(pid)
+	err = cmd.Wait()
+	f.removeProcess(pid)
+	if err != nil {
 		return "", fmt.Errorf("failed: %v, stderr: %s\n",
 			err, stderr.String())
 	}
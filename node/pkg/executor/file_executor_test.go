@@ -0,0 +1,40 @@
+package executor
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go-job/internal/model"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileExecutor_ConcurrentRuns(t *testing.T) {
+	// 不支持的文件类型直接失败，不需要 python
+	f := NewFileExecutor(1, "test", "test.txt")
+	var (
+		mux     sync.Mutex
+		results = make(map[int]model.JobExecResult)
+	)
+	f.OnResultChange(func(result model.JobExecResult) {
+		mux.Lock()
+		defer mux.Unlock()
+		results[result.RunId] = result
+	})
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 20; i++ {
+		wg.Add(1)
+		go func(runId int) {
+			defer wg.Done()
+			f.Run(Trigger{Type: model.TriggerCron, ScheduledTime: time.Now(), RunId: runId})
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Len(t, results, 20)
+	for runId, result := range results {
+		assert.Equal(t, runId, result.RunId)
+		assert.Equal(t, model.Failed, result.Status)
+		assert.GreaterOrEqual(t, result.EndTime, result.StartTime)
+	}
+}
@@ -3,6 +3,7 @@ package executor
 import (
 	"go-job/internal/dto"
 	"go-job/internal/model"
+	"slices"
 )
 
 type ExecutorFactory func(req dto.ReqNodeJob) IExecutor
@@ -18,6 +19,16 @@ func GetExecutor(execType model.ExecType) (ExecutorFactory, bool) {
 	return f, ok
 }
 
+// ExecTypes 已注册执行器的执行类型
+func ExecTypes() []model.ExecType {
+	types := make([]model.ExecType, 0, len(factories))
+	for t := range factories {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+	return types
+}
+
 func init() {
 	Register(model.ExecTypeFile, func(req dto.ReqNodeJob) IExecutor {
 		executor := NewFileExecutor(req.Id, req.Name, req.Filename)
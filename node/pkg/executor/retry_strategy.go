@@ -10,16 +10,16 @@ type retryExecutor struct {
 	retries  int
 }
 
-func (r *retryExecutor) ResultCallback(output string, err error) {
-	r.executor.ResultCallback(output, err)
+func (r *retryExecutor) ResultCallback(trigger Trigger, state *RunState, output string, err error) {
+	r.executor.ResultCallback(trigger, state, output, err)
 }
 
-func (r *retryExecutor) AfterExecute(err error) {
-	r.executor.AfterExecute(err)
+func (r *retryExecutor) AfterExecute(state *RunState, err error) {
+	r.executor.AfterExecute(state, err)
 }
 
-func (r *retryExecutor) BeforeExecute() {
-	r.executor.BeforeExecute()
+func (r *retryExecutor) BeforeExecute() *RunState {
+	return r.executor.BeforeExecute()
 }
 
 func NewRetryExecutor(executor IExecutor, retries int) IExecutor {
@@ -29,18 +29,18 @@ func NewRetryExecutor(executor IExecutor, retries int) IExecutor {
 	}
 }
 
-func (r *retryExecutor) Run() {
-	r.executor.BeforeExecute()
-	output, err := r.Execute()
-	r.executor.AfterExecute(err)
-	r.executor.ResultCallback(output, err)
+func (r *retryExecutor) Run(trigger Trigger) {
+	state := r.executor.BeforeExecute()
+	output, err := r.Execute(trigger)
+	r.executor.AfterExecute(state, err)
+	r.executor.ResultCallback(trigger, state, output, err)
 }
 
-func (r *retryExecutor) Execute() (string, error) {
+func (r *retryExecutor) Execute(trigger Trigger) (string, error) {
 	var output string
 	var err error
 	for i := 0; i < r.retries; i++ {
-		output, err = r.executor.Execute()
+		output, err = r.executor.Execute(trigger)
 		if err == nil {
 			return output, nil
 		}
@@ -53,3 +53,7 @@ func (r *retryExecutor) Execute() (string, error) {
 func (r *retryExecutor) OnResultChange(fn func(result model.JobExecResult)) {
 	r.executor.OnResultChange(fn)
 }
+
+func (r *retryExecutor) Processes() []Process {
+	return r.executor.Processes()
+}
@@ -5,16 +5,17 @@ import (
 	"fmt"
 	"go-job/internal/model"
 	"testing"
+	"time"
 )
 
 type MockFileHandler struct {
 }
 
-func (m MockFileHandler) Run() {
-	m.Execute()
+func (m MockFileHandler) Run(trigger Trigger) {
+	m.Execute(trigger)
 }
 
-func (m MockFileHandler) Execute() (string, error) {
+func (m MockFileHandler) Execute(trigger Trigger) (string, error) {
 	return "", errors.New("mock a error")
 }
 
@@ -22,20 +23,25 @@ func (m MockFileHandler) OnResultChange(f func(result model.JobExecResult)) {
 	fmt.Println("OnResultChange")
 }
 
-func (m MockFileHandler) ResultCallback(output string, err error) {
+func (m MockFileHandler) ResultCallback(trigger Trigger, state *RunState, output string, err error) {
 	fmt.Println("ResultCallback")
 }
 
-func (m MockFileHandler) AfterExecute(err error) {
+func (m MockFileHandler) AfterExecute(state *RunState, err error) {
 	fmt.Println("exec after")
 }
 
-func (m MockFileHandler) BeforeExecute() {
+func (m MockFileHandler) BeforeExecute() *RunState {
 	fmt.Println("exec before")
+	return &RunState{}
+}
+
+func (m MockFileHandler) Processes() []Process {
+	return nil
 }
 
 func TestRetryExecutor_Run(t *testing.T) {
 	mockH := MockFileHandler{}
 	executor := NewRetryExecutor(mockH, 3)
-	executor.Run()
+	executor.Run(Trigger{Type: model.TriggerCron, ScheduledTime: time.Now()})
 }
@@ -1,12 +1,41 @@
 package executor
 
-import "go-job/internal/model"
+import (
+	"go-job/internal/model"
+	"time"
+)
+
+// Trigger 单次执行的触发信息
+type Trigger struct {
+	Type          model.TriggerType
+	ScheduledTime time.Time     // 计划执行时间
+	JitterDelay   time.Duration // 执行前的随机延迟
+	RunId         int           // master 调度时预先创建的执行记录id
+	NodeId        int           // master 触发时选择的执行节点
+
+	Stdin []byte            // 传给脚本的标准输入
+	Env   map[string]string // 传给脚本的环境变量
+}
+
+// RunState 单次执行的状态和时间，同一个任务同时执行多次时每次执行各自保存
+type RunState struct {
+	Status    model.JobStatus
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// Process 正在执行的脚本进程
+type Process struct {
+	Pid       int
+	StartTime time.Time
+}
 
 type IExecutor interface {
-	Run()                                            // 为了实现cron库的Job接口
-	Execute() (string, error)                        // 执行方法
-	OnResultChange(func(result model.JobExecResult)) // 注册回调任务状态
-	ResultCallback(output string, err error)         // 执行回调
-	AfterExecute(err error)                          // 执行方法前的调用
-	BeforeExecute()                                  // 执行方法后的调用
+	Run(trigger Trigger)                                                       // 执行一次任务
+	Execute(trigger Trigger) (string, error)                                   // 执行方法
+	OnResultChange(func(result model.JobExecResult))                           // 注册回调任务状态
+	ResultCallback(trigger Trigger, state *RunState, output string, err error) // 执行回调
+	AfterExecute(state *RunState, err error)                                   // 执行方法后的调用
+	BeforeExecute() *RunState                                                  // 执行方法前的调用，返回本次执行的状态
+	Processes() []Process                                                      // 正在执行的进程
 }
@@ -7,17 +7,22 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
 	"go-job/node/api"
+	"go-job/node/pkg/store"
 	"go-job/node/router"
 	"go-job/node/service"
 )
 
 type WebContainer struct {
-	Engine *gin.Engine
-	JobSvc service.IJobService
+	Engine  *gin.Engine
+	JobSvc  service.IJobService
+	NodeSvc service.INodeService
 }
 
 func InitWebServer() *WebContainer {
 	wire.Build(
+		// store
+		store.InitJobStore,
+		store.InitStateStore,
 
 		// service
 		service.NewJobService,
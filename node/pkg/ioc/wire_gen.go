@@ -9,6 +9,7 @@ package ioc
 import (
 	"github.com/gin-gonic/gin"
 	"go-job/node/api"
+	"go-job/node/pkg/store"
 	"go-job/node/router"
 	"go-job/node/service"
 )
@@ -16,14 +17,17 @@ import (
 // Injectors from wire.go:
 
 func InitWebServer() *WebContainer {
-	iJobService := service.NewJobService()
+	jobStore := store.InitJobStore()
+	iJobService := service.NewJobService(jobStore)
 	jobApi := api.NewJobApi(iJobService)
-	iNodeService := service.NewNodeService()
+	stateStore := store.InitStateStore()
+	iNodeService := service.NewNodeService(stateStore)
 	nodeApi := api.NewNodeApi(iNodeService)
 	engine := router.NewWebRouter(jobApi, nodeApi)
 	webContainer := &WebContainer{
-		Engine: engine,
-		JobSvc: iJobService,
+		Engine:  engine,
+		JobSvc:  iJobService,
+		NodeSvc: iNodeService,
 	}
 	return webContainer
 }
@@ -31,6 +35,7 @@ func InitWebServer() *WebContainer {
 // wire.go:
 
 type WebContainer struct {
-	Engine *gin.Engine
-	JobSvc service.IJobService
+	Engine  *gin.Engine
+	JobSvc  service.IJobService
+	NodeSvc service.INodeService
 }
@@ -2,40 +2,78 @@ package job
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"go-job/internal/model"
 	"go-job/internal/pkg/httpClient"
 	"go-job/internal/pkg/paths"
 	"go-job/node/pkg/auth"
 	"go-job/node/pkg/config"
+	"go-job/node/pkg/outbox"
 	"log/slog"
+	"sync"
 )
 
-// CallbackJobResult 回传结果到master,
-func CallbackJobResult(result model.CallbackJobResult) {
+// callbacks 未使用发件箱时正在直接上报的结果，退出前等待上报完成
+var callbacks sync.WaitGroup
+
+// CallbackJobResult 回传结果到master，返回 nil 表示 master 已经保存
+func CallbackJobResult(result model.CallbackJobResult) error {
 	err := auth.RefreshToken()
 	if err != nil {
 		slog.Error("refresh token err:", "err", err)
-		return
+		return err
 	}
 	header := map[string]string{
 		"Content-Type":  "application/json",
 		"Authorization": auth.GetJwtToken(),
 	}
-	url := fmt.Sprintf("http://%s%s", config.App.Master.Address, paths.JobRecordCreateAPI)
+	url := httpClient.URL(config.App.Master.Address, paths.JobRecordCreateAPI)
 	resp, err := httpClient.PostJson(context.Background(), url, header, result, httpClient.DefaultTimeout)
 	if err != nil {
 		slog.Error("callback result error", "url", url, "resp", resp, "err", err)
-		return
+		return err
 	}
 	parseContent, err := httpClient.ParseResponse(resp)
 	if err != nil {
 		slog.Error("callback result parse error", "url", url, "resp", resp,
 			"status", resp.Status(), "err", err)
-		return
+		return err
 	}
 	if parseContent.Code != 0 {
 		slog.Error("code isn't zero in callback result", "parse content", parseContent)
+		return errors.New("code isn't zero in callback result")
+	}
+	return nil
+}
+
+// reportJobResult 写入发件箱，由发件箱上报和重试，未初始化发件箱或写入失败时直接上报一次
+func reportJobResult(result model.CallbackJobResult) {
+	err := outbox.Put(result)
+	if err == nil {
 		return
 	}
+	if !errors.Is(err, outbox.ErrNotInit) {
+		slog.Error("put result to outbox error", "job id", result.JobID,
+			"execution id", result.ExecutionId, "err", err)
+	}
+	callbacks.Add(1)
+	go func() {
+		defer callbacks.Done()
+		_ = CallbackJobResult(result)
+	}()
+}
+
+// WaitCallbacks 等待直接上报的结果发送完成，ctx 结束时返回 ctx 的错误
+func WaitCallbacks(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		callbacks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
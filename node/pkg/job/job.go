@@ -2,11 +2,21 @@ package job
 
 import (
 	"context"
+	"fmt"
+	"github.com/google/uuid"
 	"github.com/robfig/cron/v3"
 	"go-job/internal/dto"
 	"go-job/internal/model"
+	"go-job/internal/pkg/cronx"
+	"go-job/internal/pkg/utils"
 	"go-job/node/pkg/executor"
+	"go-job/node/pkg/watcher"
+	"go-job/node/pkg/worker"
+	"log/slog"
+	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,28 +26,70 @@ type JobMeta struct {
 	ExecType model.ExecType `json:"exec_type"` // 任务类型
 	CronExpr string         `json:"cron_expr"` // crontab 表达式
 	FileName string         `json:"file_name"` // 本地存储的文件名
+
+	Misfire model.JobMisfire `json:"misfire"` // 错过执行时间后的补偿策略
+	Jitter  model.JobJitter  `json:"jitter"`  // 执行前的随机延迟
+
+	Priority int `json:"priority"` // 节点执行池中的优先级
+
+	ScheduleType model.ScheduleType `json:"schedule_type"` // 调度方式
+	FileWatch    model.JobFileWatch `json:"file_watch"`    // 文件触发的监听配置
+	Dispatched   bool               `json:"dispatched"`    // 由 master 调度，节点只执行下发的任务
+
+	Active model.JobActiveType `json:"active"` // 启用状态，master 对账时比较
+	Digest string              `json:"digest"` // 收到的任务定义的摘要，master 对账时比较
 }
 
+const drainCheckInterval = 100 * time.Millisecond
+
+// FileEnv 文件触发时，通过该环境变量将文件路径传给脚本
+const FileEnv = "GO_JOB_FILE"
+
 type Job struct {
 	Ctx           context.Context
 	Cancel        context.CancelFunc
 	JobMeta       JobMeta
 	Executor      executor.IExecutor
-	Cron          *cron.Cron
-	CronEntryID   cron.EntryID
+	Schedule      cron.Schedule
+	CronEntryID   cron.EntryID     // 在节点调度器中的id，为0表示未调度
+	Watcher       *watcher.Watcher // 文件触发的监听器，定时调度时为空
 	RunningStatus model.JobStatus
 	NextExecTime  time.Time
+
+	running sync.WaitGroup // 正在执行的次数，停止job时等待执行完成
+
+	stateMux   sync.Mutex
+	executing  int                  // 执行池中正在执行的次数
+	lastResult *model.JobExecResult // 最近一次执行的结果
+}
+
+// Runtime job的运行时状态
+type Runtime struct {
+	RunningStatus model.JobStatus
+	NextExecTime  time.Time // 节点调度器中的下一次执行时间，未加入节点调度器时为零值
+	LastResult    *model.JobExecResult
+	Processes     []executor.Process
 }
 
 // ============= JobManager 全局job管理 ============= //
 
+// JobManager 管理节点上的所有job，所有job共用一个调度器
 type JobManager struct {
-	mux  sync.RWMutex
-	jobs map[int]*Job
+	mux         sync.RWMutex
+	jobs        map[int]*Job
+	cron        *cron.Cron
+	paused      bool
+	maintenance bool         // 维护中，所有触发都跳过执行
+	running     atomic.Int64 // 已提交到执行池还未结束的执行次数
 }
 
-var jm = &JobManager{
-	jobs: make(map[int]*Job),
+var jm = newJobManager()
+
+func newJobManager() *JobManager {
+	return &JobManager{
+		jobs: make(map[int]*Job),
+		cron: cron.New(cron.WithSeconds()),
+	}
 }
 
 func AddJob(job *Job) {
@@ -46,9 +98,14 @@ func AddJob(job *Job) {
 	jm.jobs[job.JobMeta.Id] = job
 }
 
+// RemoveJob 移除job，仍在调度中的job会同时从调度器中移除
 func RemoveJob(id int) {
 	jm.mux.Lock()
 	defer jm.mux.Unlock()
+	if job, ok := jm.jobs[id]; ok && job.CronEntryID != 0 {
+		jm.cron.Remove(job.CronEntryID)
+		job.CronEntryID = 0
+	}
 	delete(jm.jobs, id)
 }
 
@@ -69,6 +126,91 @@ func GetAllJobs() []*Job {
 	return jobs
 }
 
+// StartScheduler 启动节点调度器
+func StartScheduler() {
+	jm.mux.Lock()
+	defer jm.mux.Unlock()
+	jm.paused = false
+	jm.cron.Start()
+}
+
+// StopScheduler 停止节点调度器，返回的 context 在正在执行的任务结束后完成
+func StopScheduler() context.Context {
+	jm.mux.Lock()
+	defer jm.mux.Unlock()
+	return jm.cron.Stop()
+}
+
+// PauseScheduler 暂停所有job的调度，正在执行的任务不受影响，暂停期间的执行不会补偿
+func PauseScheduler() {
+	jm.mux.Lock()
+	defer jm.mux.Unlock()
+	if jm.paused {
+		return
+	}
+	jm.paused = true
+	jm.cron.Stop()
+}
+
+// ResumeScheduler 恢复调度，从当前时间重新计算每个job的下一次执行时间
+func ResumeScheduler() {
+	jm.mux.Lock()
+	defer jm.mux.Unlock()
+	if !jm.paused {
+		return
+	}
+	jm.paused = false
+	jm.cron.Start()
+}
+
+func SchedulerPaused() bool {
+	jm.mux.RLock()
+	defer jm.mux.RUnlock()
+	return jm.paused
+}
+
+// EnterMaintenance 节点进入维护状态，之后的触发都会跳过并上报为已跳过，正在执行的任务不受影响
+func EnterMaintenance() {
+	jm.mux.Lock()
+	defer jm.mux.Unlock()
+	jm.maintenance = true
+}
+
+// ExitMaintenance 节点退出维护状态
+func ExitMaintenance() {
+	jm.mux.Lock()
+	defer jm.mux.Unlock()
+	jm.maintenance = false
+}
+
+func InMaintenance() bool {
+	jm.mux.RLock()
+	defer jm.mux.RUnlock()
+	return jm.maintenance
+}
+
+// RunningCount 正在执行和排队中的执行次数
+func RunningCount() int64 {
+	return jm.running.Load()
+}
+
+// Drain 等待正在执行和排队中的任务结束，返回 ctx 结束时剩余的执行次数
+func Drain(ctx context.Context) int64 {
+	ticker := time.NewTicker(drainCheckInterval)
+	defer ticker.Stop()
+	for {
+		n := RunningCount()
+		if n == 0 {
+			return 0
+		}
+		select {
+		case <-ctx.Done():
+			return n
+		case <-ticker.C:
+		}
+	}
+}
+
 // ============= job 对象 ============= //
 
 func NewJob(ctx context.Context, cancel context.CancelFunc, req dto.ReqNodeJob, iExecutor executor.IExecutor) *Job {
@@ -81,46 +223,300 @@ func NewJob(ctx context.Context, cancel context.CancelFunc, req dto.ReqNodeJob,
 			ExecType: req.ExecType,
 			CronExpr: req.CronExpr,
 			FileName: req.Filename,
+			Misfire:  req.Misfire,
+			Jitter:   req.Jitter,
+			Priority: req.Priority,
+
+			ScheduleType: req.ScheduleType,
+			FileWatch:    req.FileWatch,
+			Dispatched:   req.Dispatched,
+
+			Active: req.Active,
+			Digest: req.Digest(),
 		},
 		Executor: iExecutor,
 	}
 }
 
-// BuildCrontab 构建一个Cron对象
+// BuildCrontab 解析crontab表达式，文件触发的job创建监听器，调用 Start 后才会开始调度
 func (j *Job) BuildCrontab() error {
-	c := cron.New(cron.WithSeconds())
-	entryID, err := c.AddJob(j.JobMeta.CronExpr, j.Executor)
+	if j.JobMeta.ScheduleType == model.ScheduleFile {
+		stable := time.Duration(j.JobMeta.FileWatch.StableSeconds) * time.Second
+		// 调度暂停或节点维护期间不处理文件，文件保留到恢复后再触发
+		j.Watcher = watcher.New(j.JobMeta.FileWatch.Path, stable, j.onFile).SetSkip(func() bool {
+			return SchedulerPaused() || InMaintenance()
+		})
+		j.RunningStatus = model.Pending
+		return nil
+	}
+	schedule, err := cronx.Parse(j.JobMeta.CronExpr)
 	if err != nil {
 		return err
 	}
-	j.Cron = c
-	j.CronEntryID = entryID
+	j.Schedule = schedule
 	j.RunningStatus = model.Pending
 	j.NextExecTime = j.getNextExecTime()
 	return nil
 }
 
+// Run 实现cron库的Job接口，由定时器触发，配置了随机延迟时先等待再提交到执行池
+func (j *Job) Run() {
+	trigger := executor.Trigger{
+		Type:          model.TriggerCron,
+		ScheduledTime: time.Now().Truncate(time.Second),
+		JitterDelay: cronx.JitterDelay(j.JobMeta.Id,
+			time.Duration(j.JobMeta.Jitter.MaxSeconds)*time.Second, j.JobMeta.Jitter.Hash),
+	}
+	if trigger.JitterDelay > 0 {
+		timer := time.NewTimer(trigger.JitterDelay)
+		defer timer.Stop()
+		select {
+		case <-j.Ctx.Done():
+			return
+		case <-timer.C:
+		}
+	}
+	j.submit(trigger)
+}
+
+// Trigger 立即触发一次执行，不影响定时调度
+func (j *Job) Trigger(trigger executor.Trigger) {
+	j.submit(trigger)
+}
+
+// onFile 监听到文件后提交执行，执行完成后按配置将文件移到处理后的目录
+// 没有实际执行（被丢弃或跳过）的文件保留在原处，等待监听器再次回调
+func (j *Job) onFile(path string) {
+	slog.Info("job file arrived", "job id", j.JobMeta.Id, "job name", j.JobMeta.Name, "path", path)
+	done := j.submit(executor.Trigger{
+		Type:          model.TriggerFile,
+		ScheduledTime: time.Now().Truncate(time.Second),
+		Env:           map[string]string{FileEnv: path},
+	})
+	go func() {
+		if !<-done {
+			slog.Warn("job file not processed, retry later", "job id", j.JobMeta.Id, "path", path)
+			if j.Watcher != nil {
+				j.Watcher.Retry(path)
+			}
+			return
+		}
+		if j.JobMeta.FileWatch.ProcessedDir == "" {
+			return
+		}
+		if err := moveFile(path, j.JobMeta.FileWatch.ProcessedDir); err != nil {
+			slog.Error("move processed file error", "job id", j.JobMeta.Id,
+				"path", path, "err", err)
+		}
+	}()
+}
+
+// moveFile 将文件移到 dir 目录下，目标文件已存在时在文件名后追加时间戳
+func moveFile(path, dir string) error {
+	if err := utils.EnsureDir(dir); err != nil {
+		return err
+	}
+	target := filepath.Join(dir, filepath.Base(path))
+	if _, err := os.Stat(target); err == nil {
+		target = fmt.Sprintf("%s.%d", target, time.Now().UnixNano())
+	}
+	return os.Rename(path, target)
+}
+
+// submit 提交到节点执行池，执行完成或被丢弃后返回是否实际执行
+func (j *Job) submit(trigger executor.Trigger) <-chan bool {
+	done := make(chan bool, 1)
+	if InMaintenance() {
+		defer close(done)
+		j.skip(trigger)
+		done <- false
+		return done
+	}
+	j.running.Add(1)
+	jm.running.Add(1)
+	finish := func(ran bool) {
+		jm.running.Add(-1)
+		j.running.Done()
+		done <- ran
+		close(done)
+	}
+	worker.Submit(&worker.Task{
+		Priority: j.JobMeta.Priority,
+		Run: func() {
+			defer finish(true)
+			j.setExecuting(1)
+			defer j.setExecuting(-1)
+			j.Executor.Run(trigger)
+		},
+		Drop: func(wait time.Duration, err error) {
+			defer finish(false)
+			j.drop(trigger, wait, err)
+		},
+	})
+	return done
+}
+
+// skip 节点维护中跳过执行，上报执行记录，状态为已跳过
+func (j *Job) skip(trigger executor.Trigger) {
+	slog.Info("job skipped in maintenance", "job id", j.JobMeta.Id, "job name", j.JobMeta.Name)
+	now := time.Now().Unix()
+	j.OnResultChange(model.JobExecResult{
+		StartTime:     now,
+		EndTime:       now,
+		Status:        model.Skipped,
+		Error:         "节点维护中，跳过执行",
+		TriggerType:   trigger.Type,
+		ScheduledTime: trigger.ScheduledTime.Unix(),
+		JitterDelay:   trigger.JitterDelay.Seconds(),
+		RunId:         trigger.RunId,
+		NodeId:        trigger.NodeId,
+	})
+}
+
+// drop 未能执行的任务也上报执行记录，状态为已丢弃
+func (j *Job) drop(trigger executor.Trigger, wait time.Duration, err error) {
+	slog.Warn("job dropped", "job id", j.JobMeta.Id, "job name", j.JobMeta.Name,
+		"wait", wait, "err", err)
+	now := time.Now()
+	j.OnResultChange(model.JobExecResult{
+		StartTime:     now.Add(-wait).Unix(),
+		EndTime:       now.Unix(),
+		Duration:      wait.Seconds(),
+		Status:        model.Dropped,
+		Error:         fmt.Sprintf("%s，等待 %.1f 秒", err, wait.Seconds()),
+		TriggerType:   trigger.Type,
+		ScheduledTime: trigger.ScheduledTime.Unix(),
+		JitterDelay:   trigger.JitterDelay.Seconds(),
+		RunId:         trigger.RunId,
+		NodeId:        trigger.NodeId,
+	})
+}
+
+// MisfireTimes 根据补偿策略计算 lastNextExecTime 到 now 之间错过的执行时间
+func (j *Job) MisfireTimes(lastNextExecTime, now time.Time) []time.Time {
+	var limit int
+	switch j.JobMeta.Misfire.Policy {
+	case model.MisfireOnce:
+		limit = 1
+	case model.MisfireAll:
+		limit = j.JobMeta.Misfire.MaxCount
+	default:
+		return nil
+	}
+	// master 调度的job由 master 负责
+	if j.Schedule == nil || j.JobMeta.Dispatched {
+		return nil
+	}
+	return cronx.MissedTimes(j.Schedule, lastNextExecTime, now, limit)
+}
+
+// RunMisfire 按时间先后依次补执行错过的任务，job 被移除后停止补偿
+func (j *Job) RunMisfire(times []time.Time) {
+	for _, t := range times {
+		select {
+		case <-j.Ctx.Done():
+			return
+		default:
+		}
+		<-j.submit(executor.Trigger{
+			Type:          model.TriggerMisfire,
+			ScheduledTime: t,
+		})
+	}
+}
+
+func (j *Job) setExecuting(delta int) {
+	j.stateMux.Lock()
+	defer j.stateMux.Unlock()
+	j.executing += delta
+}
+
+// Runtime 当前的运行时状态，正在执行时状态为执行中，否则为最近一次执行的结果
+func (j *Job) Runtime() Runtime {
+	j.stateMux.Lock()
+	rt := Runtime{
+		RunningStatus: j.RunningStatus,
+		LastResult:    j.lastResult,
+	}
+	if j.lastResult != nil {
+		rt.RunningStatus = j.lastResult.Status
+	}
+	if j.executing > 0 {
+		rt.RunningStatus = model.Running
+	}
+	j.stateMux.Unlock()
+
+	jm.mux.RLock()
+	scheduled := j.CronEntryID != 0
+	jm.mux.RUnlock()
+	if scheduled {
+		rt.NextExecTime = j.getNextExecTime()
+	}
+	rt.Processes = j.Executor.Processes()
+	return rt
+}
+
 // OnResultChange 接收执行器的回调
 func (j *Job) OnResultChange(result model.JobExecResult) {
+	// 每次执行一个执行id，发件箱重试上报时 master 按执行id去重
+	if result.ExecutionId == "" {
+		result.ExecutionId = uuid.NewString()
+	}
+	j.stateMux.Lock()
+	j.lastResult = &result
+	j.stateMux.Unlock()
+
 	callbackResult := model.CallbackJobResult{
 		JobExecResult: result,
 		JobID:         j.JobMeta.Id,
-		NextExecTime:  j.getNextExecTime().Unix(),
 	}
-	go CallbackJobResult(callbackResult)
+	if next := j.getNextExecTime(); !next.IsZero() {
+		callbackResult.NextExecTime = next.Unix()
+	}
+	reportJobResult(callbackResult)
 }
 
-// getNextExecTime 获取job下一次执行时间
+// getNextExecTime 获取job下一次执行时间，文件触发的job返回零值
 func (j *Job) getNextExecTime() time.Time {
-	return j.Cron.Entry(j.CronEntryID).Schedule.Next(time.Now())
+	if j.Schedule == nil {
+		return time.Time{}
+	}
+	return j.Schedule.Next(time.Now())
 }
 
-// Start job开始执行
+// Start 将job加入节点调度器，文件触发的job开始监听，master 调度的job不加入节点调度器
 func (j *Job) Start() {
-	j.Cron.Start()
+	if j.Watcher != nil {
+		j.Watcher.Start()
+		return
+	}
+	if j.JobMeta.Dispatched {
+		return
+	}
+	jm.mux.Lock()
+	defer jm.mux.Unlock()
+	if j.CronEntryID == 0 {
+		j.CronEntryID = jm.cron.Schedule(j.Schedule, j)
+	}
 }
 
-// Stop job停止运行
+// Stop 将job从节点调度器中移除，返回的 context 在正在执行的任务结束后完成
 func (j *Job) Stop() context.Context {
-	return j.Cron.Stop()
+	jm.mux.Lock()
+	if j.CronEntryID != 0 {
+		jm.cron.Remove(j.CronEntryID)
+		j.CronEntryID = 0
+	}
+	jm.mux.Unlock()
+	// 监听器在检查调度是否暂停时需要获取锁，需要在释放锁之后停止
+	if j.Watcher != nil {
+		j.Watcher.Stop()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		j.running.Wait()
+		cancel()
+	}()
+	return ctx
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/robfig/cron/v3"
 	"go-job/internal/dto"
 	"go-job/internal/model"
+	"go-job/node/pkg/executor"
 	"log/slog"
 	"os"
 	"runtime"
@@ -67,7 +68,7 @@ type fileExecutor struct {
 	Name string
 }
 
-func (executor *fileExecutor) Execute() (string, error) {
+func (executor *fileExecutor) Execute(trigger executor.Trigger) (string, error) {
 	fmt.Println("executing file executor")
 	return "", nil
 }
@@ -75,11 +76,25 @@ func (executor *fileExecutor) Execute() (string, error) {
 func (executor *fileExecutor) OnResultChange(f func(result model.JobExecResult)) {
 }
 
-func (executor *fileExecutor) Run() {
+func (executor *fileExecutor) Run(trigger executor.Trigger) {
 	fmt.Printf("[%s]\texecutor start, name: %s\n", time.Now().Format(time.DateTime), executor.Name)
 	slog.Info("test file exec", "name", executor.Name)
 }
 
+func (e *fileExecutor) ResultCallback(trigger executor.Trigger, state *executor.RunState, output string, err error) {
+}
+
+func (e *fileExecutor) AfterExecute(state *executor.RunState, err error) {
+}
+
+func (e *fileExecutor) BeforeExecute() *executor.RunState {
+	return &executor.RunState{}
+}
+
+func (executor *fileExecutor) Processes() []executor.Process {
+	return nil
+}
+
 func buildExecutor(name string) *fileExecutor {
 	return &fileExecutor{
 		Name: name,
@@ -136,6 +151,7 @@ func TestAddJob(t *testing.T) {
 }
 
 func handleCron() {
+	StartScheduler()
 	testCases := generateTestCase()
 	for _, testCase := range testCases {
 		ctx, cancel := context.WithCancel(context.Background())
@@ -0,0 +1,130 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-job/internal/dto"
+	"go-job/internal/model"
+	"runtime"
+	"testing"
+)
+
+func buildTestJob(t testing.TB, id int, cronExpr string) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	jj := NewJob(ctx, cancel, dto.ReqNodeJob{
+		Id:       id,
+		Name:     fmt.Sprintf("test-%d", id),
+		CronExpr: cronExpr,
+		Active:   model.JobStart,
+	}, buildExecutor(fmt.Sprintf("test-%d", id)))
+	require.NoError(t, jj.BuildCrontab())
+	return jj
+}
+
+func TestSharedScheduler(t *testing.T) {
+	jm = newJobManager()
+	StartScheduler()
+	defer StopScheduler()
+
+	testCases := []struct {
+		name   string
+		action func()
+		want   int
+		paused bool
+	}{
+		{
+			name: "add jobs",
+			action: func() {
+				for i := 1; i <= 3; i++ {
+					jj := buildTestJob(t, i, "0 0 * * * *")
+					AddJob(jj)
+					jj.Start()
+				}
+			},
+			want: 3,
+		}, {
+			name: "start twice",
+			action: func() {
+				jj, _ := GetJob(1)
+				jj.Start()
+			},
+			want: 3,
+		}, {
+			name: "stop job",
+			action: func() {
+				jj, _ := GetJob(1)
+				<-jj.Stop().Done()
+			},
+			want: 2,
+		}, {
+			name:   "pause",
+			action: PauseScheduler,
+			want:   2,
+			paused: true,
+		}, {
+			name:   "remove job in paused",
+			action: func() { RemoveJob(2) },
+			want:   1,
+			paused: true,
+		}, {
+			name:   "resume",
+			action: ResumeScheduler,
+			want:   1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.action()
+			assert.Len(t, jm.cron.Entries(), tc.want)
+			assert.Equal(t, tc.paused, SchedulerPaused())
+		})
+	}
+}
+
+// BenchmarkPerJobCron 每个job一个cron实例(旧的实现)，添加后再全部停止
+func BenchmarkPerJobCron(b *testing.B) {
+	for _, n := range []int{100, 1000} {
+		b.Run(fmt.Sprintf("jobs-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				crons := make([]*cron.Cron, 0, n)
+				for id := 1; id <= n; id++ {
+					c := cron.New(cron.WithSeconds())
+					if _, err := c.AddJob("0 0 * * * *", buildTestJob(b, id, "0 0 * * * *")); err != nil {
+						b.Fatal(err)
+					}
+					c.Start()
+					crons = append(crons, c)
+				}
+				b.ReportMetric(float64(runtime.NumGoroutine()), "goroutines")
+				for _, c := range crons {
+					<-c.Stop().Done()
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkSharedScheduler 所有job共用一个调度器，添加后再全部移除
+func BenchmarkSharedScheduler(b *testing.B) {
+	for _, n := range []int{100, 1000} {
+		b.Run(fmt.Sprintf("jobs-%d", n), func(b *testing.B) {
+			jm = newJobManager()
+			StartScheduler()
+			defer StopScheduler()
+			for i := 0; i < b.N; i++ {
+				for id := 1; id <= n; id++ {
+					jj := buildTestJob(b, id, "0 0 * * * *")
+					AddJob(jj)
+					jj.Start()
+				}
+				b.ReportMetric(float64(runtime.NumGoroutine()), "goroutines")
+				for id := 1; id <= n; id++ {
+					RemoveJob(id)
+				}
+			}
+		})
+	}
+}
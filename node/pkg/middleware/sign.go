@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"go-job/node/pkg/auth"
+	"go-job/node/pkg/config"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// VerifySign 校验 master 请求的签名，没有密钥时拒绝所有请求，自注册的节点在注册成功后才有密钥，
+// 只有开启 master.insecure_skip_verify 时没有密钥不校验
+func VerifySign() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if auth.GetSecret() == "" {
+			if config.App.Master.InsecureSkipVerify {
+				c.Next()
+				return
+			}
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		if err = auth.VerifyRequest(c.Request, body); err != nil {
+			slog.Warn("verify master sign error", "method", c.Request.Method, "uri", c.Request.RequestURI,
+				"remote", c.ClientIP(), "err", err)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,320 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-job/internal/model"
+	"go-job/internal/pkg/utils"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMinBackoff = time.Second
+	defaultMaxBackoff = 5 * time.Minute
+	defaultRetention  = 24 * time.Hour
+	fileExt           = ".json"
+)
+
+var (
+	ErrNotInit     = errors.New("发件箱未初始化")
+	ErrExecutionId = errors.New("执行结果缺少执行id")
+)
+
+// SendFunc 上报一次执行结果，返回 nil 表示 master 已经保存
+type SendFunc func(result model.CallbackJobResult) error
+
+// entry 待上报的执行结果
+type entry struct {
+	result   model.CallbackJobResult
+	created  time.Time
+	attempts int
+	next     time.Time // 下一次上报的时间
+}
+
+// Outbox 执行结果先写入本地文件再上报，上报失败时按指数退避重试，节点重启后继续上报，
+// 每个结果一个文件，文件名为执行id，master 按执行id去重
+type Outbox struct {
+	mux     sync.Mutex
+	dir     string
+	send    SendFunc
+	pending map[string]*entry
+	notify  chan struct{}
+
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	retention  time.Duration // 超过该时间仍未上报成功的结果丢弃
+}
+
+type Option func(o *Outbox)
+
+// WithBackoff 重试的最短和最长间隔
+func WithBackoff(minBackoff, maxBackoff time.Duration) Option {
+	return func(o *Outbox) {
+		if minBackoff > 0 {
+			o.minBackoff = minBackoff
+		}
+		if maxBackoff > 0 {
+			o.maxBackoff = maxBackoff
+		}
+	}
+}
+
+// WithRetention 结果最长保留时间
+func WithRetention(retention time.Duration) Option {
+	return func(o *Outbox) {
+		if retention > 0 {
+			o.retention = retention
+		}
+	}
+}
+
+// NewOutbox 创建发件箱，加载目录中上次未上报成功的结果
+func NewOutbox(dir string, send SendFunc, opts ...Option) (*Outbox, error) {
+	o := &Outbox{
+		dir:        dir,
+		send:       send,
+		pending:    make(map[string]*entry),
+		notify:     make(chan struct{}, 1),
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+		retention:  defaultRetention,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := utils.EnsureDir(dir); err != nil {
+		return nil, err
+	}
+	if err := o.load(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *Outbox) load() error {
+	files, err := os.ReadDir(o.dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		path := filepath.Join(o.dir, f.Name())
+		if f.IsDir() {
+			continue
+		}
+		// 写入临时文件的过程中退出，结果没有写入成功
+		if filepath.Ext(f.Name()) == ".tmp" {
+			_ = os.Remove(path)
+			continue
+		}
+		if filepath.Ext(f.Name()) != fileExt {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var result model.CallbackJobResult
+		if err = json.Unmarshal(data, &result); err != nil || result.ExecutionId == "" {
+			slog.Error("outbox invalid file, removed", "path", path, "err", err)
+			_ = os.Remove(path)
+			continue
+		}
+		created := time.Now()
+		if info, err := f.Info(); err == nil {
+			created = info.ModTime()
+		}
+		o.pending[result.ExecutionId] = &entry{result: result, created: created}
+	}
+	if len(o.pending) > 0 {
+		slog.Info("outbox load pending results", "count", len(o.pending))
+	}
+	return nil
+}
+
+// Put 写入本地文件后等待上报，返回 nil 表示结果不会因为节点重启丢失
+func (o *Outbox) Put(result model.CallbackJobResult) error {
+	if result.ExecutionId == "" {
+		return ErrExecutionId
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	// 先写临时文件再重命名，加载时不会读到写了一半的文件
+	path := o.path(result.ExecutionId)
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	o.mux.Lock()
+	o.pending[result.ExecutionId] = &entry{result: result, created: time.Now()}
+	o.mux.Unlock()
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Len 待上报的结果数量
+func (o *Outbox) Len() int {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	return len(o.pending)
+}
+
+// Run 按写入顺序上报到期的结果，直到 ctx 结束
+func (o *Outbox) Run(ctx context.Context) {
+	for {
+		wait := o.flush(ctx, time.Now())
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-o.notify:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// Flush 不等待重试间隔，立即上报所有的结果，返回未上报成功的数量，用于退出前，
+// 和 Run 同时上报时 master 按执行id去重，未上报成功的结果保留在目录中，下次启动后继续上报
+func (o *Outbox) Flush(ctx context.Context) int {
+	o.mux.Lock()
+	for _, e := range o.pending {
+		e.next = time.Time{}
+	}
+	o.mux.Unlock()
+	o.flush(ctx, time.Now())
+	return o.Len()
+}
+
+// flush 上报所有到期的结果，返回距离下一次到期的时间
+func (o *Outbox) flush(ctx context.Context, now time.Time) time.Duration {
+	for _, e := range o.due(now) {
+		if ctx.Err() != nil {
+			return 0
+		}
+		id := e.result.ExecutionId
+		if now.Sub(e.created) > o.retention {
+			slog.Error("outbox result expired, dropped", "execution id", id,
+				"job id", e.result.JobID, "attempts", e.attempts)
+			o.remove(id)
+			continue
+		}
+		if err := o.send(e.result); err != nil {
+			o.retry(e, err)
+			continue
+		}
+		o.remove(id)
+	}
+	return o.nextWait(time.Now())
+}
+
+// due 到期的结果，按写入时间排序
+func (o *Outbox) due(now time.Time) []*entry {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	var entries []*entry
+	for _, e := range o.pending {
+		if !e.next.After(now) {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(a, b int) bool { return entries[a].created.Before(entries[b].created) })
+	return entries
+}
+
+func (o *Outbox) retry(e *entry, err error) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	e.attempts++
+	backoff := o.backoff(e.attempts)
+	e.next = time.Now().Add(backoff)
+	slog.Warn("outbox send result error", "execution id", e.result.ExecutionId,
+		"job id", e.result.JobID, "attempts", e.attempts, "next retry", backoff, "err", err)
+}
+
+// backoff 第 attempts 次失败后的重试间隔，每次翻倍，不超过最长间隔
+func (o *Outbox) backoff(attempts int) time.Duration {
+	backoff := o.minBackoff
+	for i := 1; i < attempts && backoff < o.maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, o.maxBackoff)
+}
+
+func (o *Outbox) nextWait(now time.Time) time.Duration {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	wait := o.maxBackoff
+	for _, e := range o.pending {
+		wait = min(wait, max(e.next.Sub(now), 0))
+	}
+	return wait
+}
+
+func (o *Outbox) remove(id string) {
+	o.mux.Lock()
+	delete(o.pending, id)
+	o.mux.Unlock()
+	if err := os.Remove(o.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Error("outbox remove file error", "execution id", id, "err", err)
+	}
+}
+
+func (o *Outbox) path(id string) string {
+	// 执行id由节点生成，这里只防止异常的id写到目录外
+	id = strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(id)
+	return filepath.Join(o.dir, id+fileExt)
+}
+
+// ============= 节点全局发件箱 ============= //
+
+var defaultOutbox *Outbox
+
+// InitOutbox 初始化节点全局发件箱
+func InitOutbox(dir string, send SendFunc, opts ...Option) error {
+	o, err := NewOutbox(dir, send, opts...)
+	if err != nil {
+		return err
+	}
+	defaultOutbox = o
+	return nil
+}
+
+// Put 写入节点全局发件箱，未初始化时返回 ErrNotInit
+func Put(result model.CallbackJobResult) error {
+	if defaultOutbox == nil {
+		return ErrNotInit
+	}
+	return defaultOutbox.Put(result)
+}
+
+// Flush 立即上报节点全局发件箱中的结果，未初始化时返回0
+func Flush(ctx context.Context) int {
+	if defaultOutbox == nil {
+		return 0
+	}
+	return defaultOutbox.Flush(ctx)
+}
+
+// Run 上报节点全局发件箱中的结果，未初始化时直接返回
+func Run(ctx context.Context) {
+	if defaultOutbox != nil {
+		defaultOutbox.Run(ctx)
+	}
+}
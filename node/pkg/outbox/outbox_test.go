@@ -0,0 +1,127 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-job/internal/model"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder 记录上报的执行id，前 fails 次上报返回错误
+type recorder struct {
+	mux   sync.Mutex
+	fails int
+	sent  []string
+}
+
+func (r *recorder) send(result model.CallbackJobResult) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.fails > 0 {
+		r.fails--
+		return errors.New("master unavailable")
+	}
+	r.sent = append(r.sent, result.ExecutionId)
+	return nil
+}
+
+func (r *recorder) sentIds() []string {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return append([]string{}, r.sent...)
+}
+
+func newResult(id string, jobId int) model.CallbackJobResult {
+	return model.CallbackJobResult{
+		JobExecResult: model.JobExecResult{ExecutionId: id, Status: model.Success},
+		JobID:         jobId,
+	}
+}
+
+func TestOutbox_Retry(t *testing.T) {
+	dir := t.TempDir()
+	r := &recorder{fails: 2}
+	o, err := NewOutbox(dir, r.send, WithBackoff(10*time.Millisecond, 20*time.Millisecond))
+	require.NoError(t, err)
+	require.NoError(t, o.Put(newResult("a", 1)))
+	assert.ErrorIs(t, o.Put(newResult("", 1)), ErrExecutionId)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go o.Run(ctx)
+
+	assert.Eventually(t, func() bool { return o.Len() == 0 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"a"}, r.sentIds())
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestOutbox_Load(t *testing.T) {
+	dir := t.TempDir()
+	// master 不可用时写入，重新创建后继续上报
+	o, err := NewOutbox(dir, (&recorder{fails: 100}).send)
+	require.NoError(t, err)
+	require.NoError(t, o.Put(newResult("a", 1)))
+	require.NoError(t, o.Put(newResult("b", 2)))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "c.json"), []byte("{bad"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "d.json.tmp"), []byte("{}"), 0644))
+
+	r := &recorder{}
+	o, err = NewOutbox(dir, r.send)
+	require.NoError(t, err)
+	assert.Equal(t, 2, o.Len())
+	o.flush(context.Background(), time.Now())
+	assert.ElementsMatch(t, []string{"a", "b"}, r.sentIds())
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestOutbox_Flush(t *testing.T) {
+	r := &recorder{fails: 1}
+	o, err := NewOutbox(t.TempDir(), r.send, WithBackoff(time.Hour, time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, o.Put(newResult("a", 1)))
+	require.NoError(t, o.Put(newResult("b", 2)))
+
+	// a 上报失败后一小时才重试，退出前不等待重试间隔
+	o.flush(context.Background(), time.Now())
+	assert.Equal(t, []string{"b"}, r.sentIds())
+	assert.Equal(t, 0, o.Flush(context.Background()))
+	assert.Equal(t, []string{"b", "a"}, r.sentIds())
+}
+
+func TestOutbox_Expired(t *testing.T) {
+	r := &recorder{}
+	o, err := NewOutbox(t.TempDir(), r.send, WithRetention(time.Minute))
+	require.NoError(t, err)
+	require.NoError(t, o.Put(newResult("a", 1)))
+
+	o.flush(context.Background(), time.Now().Add(2*time.Minute))
+	assert.Equal(t, 0, o.Len())
+	assert.Empty(t, r.sentIds())
+}
+
+func TestOutbox_Backoff(t *testing.T) {
+	o := &Outbox{minBackoff: time.Second, maxBackoff: 10 * time.Second}
+	testCases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Second},
+		{attempts: 2, want: 2 * time.Second},
+		{attempts: 4, want: 8 * time.Second},
+		{attempts: 5, want: 10 * time.Second},
+		{attempts: 100, want: 10 * time.Second},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, o.backoff(tc.attempts), "attempts %d", tc.attempts)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build linux
+
+package resource
+
+import "syscall"
+
+// diskUsage 目录所在磁盘的总容量和已使用的容量
+func diskUsage(dir string) (total, used uint64, err error) {
+	var st syscall.Statfs_t
+	if err = syscall.Statfs(dir, &st); err != nil {
+		return 0, 0, err
+	}
+	total = st.Blocks * uint64(st.Bsize)
+	used = (st.Blocks - st.Bfree) * uint64(st.Bsize)
+	return total, used, nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package resource
+
+import "errors"
+
+// diskUsage 只支持 linux
+func diskUsage(dir string) (total, used uint64, err error) {
+	return 0, 0, errors.New("不支持的系统")
+}
@@ -0,0 +1,172 @@
+package resource
+
+import (
+	"bufio"
+	"errors"
+	"go-job/internal/model"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrProcFormat = errors.New("proc 文件格式错误")
+
+// cpuTimes /proc/stat 中 cpu 行的累计时间
+type cpuTimes struct {
+	idle  uint64
+	total uint64
+}
+
+// Collector 从 /proc 采集节点的资源使用情况，CPU使用率根据两次采集之间的差值计算
+type Collector struct {
+	mux     sync.Mutex
+	procDir string
+	diskDir string
+	prevCPU cpuTimes
+}
+
+// NewCollector diskDir 为统计磁盘使用情况的目录
+func NewCollector(diskDir string) *Collector {
+	return &Collector{
+		procDir: "/proc",
+		diskDir: diskDir,
+	}
+}
+
+// Collect 采集一次资源使用情况，单项采集失败不影响其他项
+func (c *Collector) Collect() (model.NodeResource, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	res := model.NodeResource{Time: time.Now().Unix()}
+	var errs []error
+	if cpu, err := readFile(c.procDir+"/stat", parseStat); err != nil {
+		errs = append(errs, err)
+	} else {
+		res.CPUPercent = cpuPercent(c.prevCPU, cpu)
+		c.prevCPU = cpu
+	}
+	if load, err := readFile(c.procDir+"/loadavg", parseLoadavg); err != nil {
+		errs = append(errs, err)
+	} else {
+		res.Load1, res.Load5, res.Load15 = load[0], load[1], load[2]
+	}
+	if mem, err := readFile(c.procDir+"/meminfo", parseMeminfo); err != nil {
+		errs = append(errs, err)
+	} else {
+		res.MemTotal, res.MemUsed = mem[0], mem[1]
+	}
+	if total, used, err := diskUsage(c.diskDir); err != nil {
+		errs = append(errs, err)
+	} else {
+		res.DiskTotal, res.DiskUsed = total, used
+	}
+	return res, errors.Join(errs...)
+}
+
+func readFile[T any](path string, parse func(r io.Reader) (T, error)) (T, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer f.Close()
+	return parse(f)
+}
+
+// parseStat 解析 /proc/stat 第一行所有CPU的累计时间，idle 包含 iowait
+func parseStat(r io.Reader) (cpuTimes, error) {
+	var times cpuTimes
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && line == "" {
+		return times, err
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return times, ErrProcFormat
+	}
+	for i, field := range fields[1:] {
+		v, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return times, ErrProcFormat
+		}
+		times.total += v
+		// 第4列为 idle，第5列为 iowait
+		if i == 3 || i == 4 {
+			times.idle += v
+		}
+	}
+	return times, nil
+}
+
+func cpuPercent(prev, cur cpuTimes) float64 {
+	if prev.total == 0 || cur.total <= prev.total {
+		return 0
+	}
+	total := cur.total - prev.total
+	idle := cur.idle - prev.idle
+	return float64(total-idle) * 100 / float64(total)
+}
+
+// parseLoadavg 解析 /proc/loadavg 中1分钟、5分钟、15分钟的平均负载
+func parseLoadavg(r io.Reader) ([3]float64, error) {
+	var load [3]float64
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return load, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 {
+		return load, ErrProcFormat
+	}
+	for i := range load {
+		if load[i], err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return load, ErrProcFormat
+		}
+	}
+	return load, nil
+}
+
+// parseMeminfo 解析 /proc/meminfo，返回总内存和已使用的内存，已使用的内存不包含可回收的缓存
+func parseMeminfo(r io.Reader) ([2]uint64, error) {
+	var (
+		mem       [2]uint64
+		total     uint64
+		available uint64
+		found     int
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		var dst *uint64
+		switch fields[0] {
+		case "MemTotal:":
+			dst = &total
+		case "MemAvailable:":
+			dst = &available
+		default:
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return mem, ErrProcFormat
+		}
+		// 单位为 kB
+		*dst = v * 1024
+		found++
+	}
+	if err := scanner.Err(); err != nil {
+		return mem, err
+	}
+	if found < 2 || available > total {
+		return mem, ErrProcFormat
+	}
+	mem[0], mem[1] = total, total-available
+	return mem, nil
+}
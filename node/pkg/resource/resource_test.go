@@ -0,0 +1,65 @@
+package resource
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestParseStat(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		want    cpuTimes
+		wantErr error
+	}{
+		{
+			name:    "cpu line",
+			content: "cpu  100 0 50 800 50 0 0 0 0 0\ncpu0 50 0 25 400 25 0 0 0 0 0\n",
+			want:    cpuTimes{idle: 850, total: 1000},
+		},
+		{
+			name:    "not cpu line",
+			content: "intr 1 2 3 4 5\n",
+			wantErr: ErrProcFormat,
+		},
+		{
+			name:    "invalid number",
+			content: "cpu  a b c d e\n",
+			wantErr: ErrProcFormat,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseStat(strings.NewReader(tc.content))
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestCPUPercent(t *testing.T) {
+	// 第一次采集没有上一次的数据
+	assert.Equal(t, 0.0, cpuPercent(cpuTimes{}, cpuTimes{idle: 850, total: 1000}))
+	assert.Equal(t, 25.0, cpuPercent(cpuTimes{idle: 850, total: 1000}, cpuTimes{idle: 1000, total: 1200}))
+}
+
+func TestParseLoadavg(t *testing.T) {
+	load, err := parseLoadavg(strings.NewReader("0.52 0.38 0.30 2/345 12345\n"))
+	require.NoError(t, err)
+	assert.Equal(t, [3]float64{0.52, 0.38, 0.30}, load)
+
+	_, err = parseLoadavg(strings.NewReader("0.52\n"))
+	assert.ErrorIs(t, err, ErrProcFormat)
+}
+
+func TestParseMeminfo(t *testing.T) {
+	content := "MemTotal:        2048 kB\nMemFree:          512 kB\nMemAvailable:    1024 kB\nBuffers:          100 kB\n"
+	mem, err := parseMeminfo(strings.NewReader(content))
+	require.NoError(t, err)
+	assert.Equal(t, [2]uint64{2048 * 1024, 1024 * 1024}, mem)
+
+	_, err = parseMeminfo(strings.NewReader("MemTotal:        2048 kB\n"))
+	assert.ErrorIs(t, err, ErrProcFormat)
+}
@@ -0,0 +1,124 @@
+package startup
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"go-job/internal/dto"
+	"go-job/internal/pkg/httpClient"
+	"go-job/internal/pkg/paths"
+	"go-job/internal/pkg/pki"
+	"go-job/node/pkg/auth"
+	"go-job/node/pkg/config"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// certCheckInterval 检查证书是否需要申请或续期的间隔
+const certCheckInterval = time.Minute
+
+var errNotRegistered = errors.New("节点还没有节点id，需要先注册或配置 master.node_id")
+
+var (
+	certHolder = &pki.Holder{}
+	certPool   *x509.CertPool
+	certKey    crypto.Signer
+)
+
+// InitTLS 读取 CA 证书、私钥和上次签发的证书，之后访问 master 使用 https，需要在 InitTunnel 之前调用
+func InitTLS() error {
+	var err error
+	if certPool, err = pki.NewCertPool(config.App.TLS.CAFile); err != nil {
+		return err
+	}
+	if certKey, err = pki.LoadOrCreateKey(config.App.TLS.KeyFile); err != nil {
+		return err
+	}
+	// 证书不存在或和私钥不匹配时由 RunCertRenew 重新申请
+	if certPEM, err := os.ReadFile(config.App.TLS.CertFile); err == nil {
+		cert, err := pki.KeyPair(certPEM, certKey)
+		if err != nil {
+			slog.Error("load node cert error", "file", config.App.TLS.CertFile, "err", err)
+		} else {
+			certHolder.Set(cert)
+		}
+	}
+	httpClient.EnableTLS(pki.ClientConfig(certHolder, certPool))
+	return nil
+}
+
+// ServerTLSConfig 节点只接受 master 的客户端证书，其他节点的证书也由同一个 CA 签发，需要校验 CN
+func ServerTLSConfig() *tls.Config {
+	return pki.ServerConfig(certHolder, certPool, tls.RequireAndVerifyClientCert, pki.MasterCommonName)
+}
+
+// RenewCert 没有证书或证书有效期剩余不到三分之一时向 master 申请
+func RenewCert() error {
+	if !certHolder.NeedRenew(time.Now()) {
+		return nil
+	}
+	return requestCert()
+}
+
+// RunCertRenew 定期检查证书，申请失败时下次检查重试，直到 ctx 结束
+func RunCertRenew(ctx context.Context) {
+	ticker := time.NewTicker(certCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := RenewCert(); err != nil {
+			slog.Error("renew node cert error", "err", err)
+		}
+	}
+}
+
+// requestCert 使用节点自己的 token 申请证书，申请成功后 master 上之前的证书失效
+func requestCert() error {
+	if auth.GetNodeId() <= 0 {
+		return errNotRegistered
+	}
+	csr, err := pki.NewCSR(certKey)
+	if err != nil {
+		return err
+	}
+	if err = auth.RefreshToken(); err != nil {
+		return err
+	}
+	header := map[string]string{
+		"Authorization": auth.GetJwtToken(),
+	}
+	url := httpClient.URL(config.App.Master.Address, paths.NodeCertAPI)
+	resp, err := httpClient.PostJson(context.Background(), url, header,
+		dto.ReqNodeCert{Csr: string(csr)}, httpClient.DefaultTimeout)
+	if err != nil {
+		return err
+	}
+	parseResp, err := httpClient.ParseResponseWith[Resp[dto.RespNodeCert]](resp)
+	if err != nil {
+		return err
+	}
+	if parseResp.Code != 0 {
+		return errors.New(parseResp.Msg)
+	}
+	certPEM := []byte(parseResp.Data.Cert)
+	cert, err := pki.KeyPair(certPEM, certKey)
+	if err != nil {
+		return err
+	}
+	if err = pki.WriteFile(config.App.TLS.CertFile, certPEM, 0644); err != nil {
+		slog.Error("save node cert error", "file", config.App.TLS.CertFile, "err", err)
+	}
+	certHolder.Set(cert)
+	// 已经建立的连接仍使用旧的证书，master 会拒绝，断开后使用新的证书重新连接
+	httpClient.CloseIdleConnections()
+	closeTunnel()
+	slog.Info("node cert issued", "serial", pki.Serial(cert.Leaf), "expire", cert.Leaf.NotAfter)
+	return nil
+}
@@ -0,0 +1,182 @@
+package startup
+
+import (
+	"context"
+	"errors"
+	"go-job/internal/dto"
+	"go-job/internal/model"
+	"go-job/internal/pkg/consts"
+	"go-job/internal/pkg/httpClient"
+	"go-job/internal/pkg/paths"
+	"go-job/internal/pkg/version"
+	"go-job/node/pkg/auth"
+	"go-job/node/pkg/config"
+	"go-job/node/pkg/executor"
+	"go-job/node/pkg/resource"
+	"go-job/node/pkg/worker"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// defaultHeartbeatInterval master 没有返回心跳间隔时使用
+const defaultHeartbeatInterval = 10 * time.Second
+
+// RegisterToMaster 使用 bootstrap token 注册到 master，返回节点id和心跳间隔
+func RegisterToMaster() (dto.RespNodeRegister, error) {
+	var result dto.RespNodeRegister
+	req, err := registerInfo()
+	if err != nil {
+		return result, err
+	}
+	header := map[string]string{
+		consts.BootstrapTokenHeader: config.App.Register.BootstrapToken,
+	}
+	url := httpClient.URL(config.App.Master.Address, paths.NodeRegisterAPI)
+	resp, err := httpClient.PostJson(context.Background(), url, header, req, httpClient.DefaultTimeout)
+	if err != nil {
+		return result, err
+	}
+	parseResp, err := httpClient.ParseResponseWith[Resp[dto.RespNodeRegister]](resp)
+	if err != nil {
+		return result, err
+	}
+	if parseResp.Code != 0 {
+		return result, errors.New(parseResp.Msg)
+	}
+	// 自注册的节点使用 master 保存的密钥校验签名和生成访问 master 的 token
+	if parseResp.Data.Secret != "" {
+		auth.SetSecret(parseResp.Data.Secret)
+	}
+	if parseResp.Data.TokenSecret != "" {
+		auth.SetTokenSecret(parseResp.Data.TokenSecret)
+	}
+	auth.SetNodeId(parseResp.Data.NodeId)
+	slog.Info("register to master success", "node id", parseResp.Data.NodeId, "address", req.Address)
+	return parseResp.Data, nil
+}
+
+// RunHeartbeat 定期上报心跳，master 上的节点被删除或心跳失败后重新注册
+func RunHeartbeat(ctx context.Context, reg dto.RespNodeRegister) {
+	interval := time.Duration(reg.HeartbeatInterval) * time.Second
+	if interval <= 0 {
+		interval = defaultHeartbeatInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	collector := resource.NewCollector(config.App.Data.UploadJobDir)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if reg.NodeId > 0 {
+			err := sendHeartbeat(reg.NodeId, collectResource(collector))
+			if err == nil {
+				continue
+			}
+			slog.Error("send heartbeat error", "node id", reg.NodeId, "err", err)
+		}
+		newReg, err := RegisterToMaster()
+		if err != nil {
+			slog.Error("register to master error", "err", err)
+			continue
+		}
+		reg.NodeId = newReg.NodeId
+	}
+}
+
+// collectResource 采集随心跳上报的资源使用情况，采集失败的项为零值
+func collectResource(collector *resource.Collector) *model.NodeResource {
+	res, err := collector.Collect()
+	if err != nil {
+		slog.Error("collect node resource error", "err", err)
+	}
+	stats := worker.GetPool().Stats()
+	res.Running = stats.Running
+	res.Queued = stats.Queued
+	return &res
+}
+
+func sendHeartbeat(nodeId int, res *model.NodeResource) error {
+	if err := auth.RefreshToken(); err != nil {
+		return err
+	}
+	header := map[string]string{
+		"Authorization": auth.GetJwtToken(),
+	}
+	url := httpClient.URL(config.App.Master.Address, paths.NodeHeartbeatAPI)
+	resp, err := httpClient.PostJson(context.Background(), url, header,
+		dto.ReqNodeHeartbeat{NodeId: nodeId, Resource: res}, httpClient.DefaultTimeout)
+	if err != nil {
+		return err
+	}
+	parseResp, err := httpClient.ParseResponse(resp)
+	if err != nil {
+		return err
+	}
+	if parseResp.Code != 0 {
+		return errors.New(parseResp.Msg)
+	}
+	return nil
+}
+
+// registerInfo 收集注册时上报的节点信息
+func registerInfo() (dto.ReqNodeRegister, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		slog.Error("get hostname error", "err", err)
+	}
+	name := config.App.Server.Name
+	if name == "" {
+		name = hostname
+	}
+	address, err := advertiseAddress()
+	if err != nil {
+		return dto.ReqNodeRegister{}, err
+	}
+	return dto.ReqNodeRegister{
+		Name:         name,
+		Hostname:     hostname,
+		Address:      address,
+		Version:      version.Version,
+		Capabilities: capabilities(),
+		Group:        config.App.Register.Group,
+		Labels:       config.App.Register.Labels,
+	}, nil
+}
+
+// advertiseAddress 未配置时使用连接 master 的本机IP和服务端口
+func advertiseAddress() (string, error) {
+	if addr := config.App.Register.AdvertiseAddress; addr != "" {
+		return addr, nil
+	}
+	port := strconv.Itoa(int(config.App.Server.Port))
+	if ip := config.App.Server.Ip; ip != "" && ip != "0.0.0.0" {
+		return net.JoinHostPort(ip, port), nil
+	}
+	// udp 不会真正建立连接，只用于获取访问 master 时使用的本机IP
+	conn, err := net.Dial("udp", config.App.Master.Address)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	ip := conn.LocalAddr().(*net.UDPAddr).IP.String()
+	return net.JoinHostPort(ip, port), nil
+}
+
+// capabilities 节点支持的执行类型，安装了python时可以执行python文件
+func capabilities() []string {
+	var result []string
+	for _, t := range executor.ExecTypes() {
+		result = append(result, t.String())
+	}
+	if _, err := exec.LookPath("python"); err == nil {
+		result = append(result, "python")
+	}
+	return result
+}
@@ -2,26 +2,29 @@ package startup
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"go-job/internal/dto"
 	"go-job/internal/model"
 	"go-job/internal/pkg/httpClient"
 	"go-job/internal/pkg/paths"
+	"go-job/internal/pkg/utils"
 	"go-job/node/pkg/auth"
 	"go-job/node/pkg/config"
 	"go-job/node/service"
 	"log/slog"
+	"slices"
+	"strconv"
 	"time"
 )
 
 // 内层 data
 type respJobListData struct {
-	Total    int         `json:"total"`
-	PageSize int         `json:"page_size"`
-	PageNum  int         `json:"page_num"`
-	Data     []model.Job `json:"data"`
-	Order    string      `json:"order"`
-	Sort     string      `json:"sort"`
+	Total    int           `json:"total"`
+	PageSize int           `json:"page_size"`
+	PageNum  int           `json:"page_num"`
+	Data     []dto.RespJob `json:"data"`
+	Order    string        `json:"order"`
+	Sort     string        `json:"sort"`
 }
 
 type Resp[T any] struct {
@@ -30,43 +33,158 @@ type Resp[T any] struct {
 	Data T      `json:"data"`
 }
 
-func SyncJobFromMaster(jobSvc service.IJobService) error {
+// syncRetryInterval master 不可用时重试同步的间隔
+const (
+	syncRetryInterval    = 5 * time.Second
+	maxSyncRetryInterval = time.Minute
+)
+
+// syncPageSize 每次从 master 查询的任务数，不超过 master 的分页上限
+const syncPageSize = 50
+
+// Restored 节点启动时从本地恢复的任务
+type Restored struct {
+	Ids  []int
+	Time time.Time // 恢复的时间，恢复后的任务已经在调度，只需要补执行之前错过的任务
+}
+
+func (r Restored) contains(id int) bool {
+	return slices.Contains(r.Ids, id)
+}
+
+// SyncJobFromMaster 同步 master 上的任务，本地恢复的任务以 master 为准，定义有变化时更新，
+// 节点离线期间在 master 上删除或转移到其他节点的任务从本地移除
+func SyncJobFromMaster(jobSvc service.IJobService, restored Restored) error {
 	if err := auth.RefreshToken(); err != nil {
 		return err
 	}
 
-	header := map[string]string{
-		"Content-Type":  "application/json",
-		"Authorization": auth.GetJwtToken(),
-	}
-	url := fmt.Sprintf("http://%s%s", config.App.Master.Address, paths.JobListAPI)
-	params := map[string]string{
-		"page_num":  "1",
-		"page_size": "9999",
-	}
-	resp, err := httpClient.GetJson(context.Background(), url, header, params, 3*time.Second)
-	if err != nil {
-		return err
-	}
-	parseResp, err := httpClient.ParseResponseWith[Resp[respJobListData]](resp)
+	jobs, err := queryJobsFromMaster()
 	if err != nil {
 		return err
 	}
+	removeStaleJobs(jobSvc, restored, jobs)
 
-	for _, job := range parseResp.Data.Data {
-		if err = jobSvc.AddJob(context.Background(), dto.ReqNodeJob{
+	for _, job := range jobs {
+		req := dto.ReqNodeJob{
 			Id:       job.Id,
 			Name:     job.Name,
 			ExecType: job.ExecType,
 			CronExpr: job.CronExpr,
 			Active:   job.Active,
 			Filename: job.UUIDFileName,
-		}); err != nil {
+			Misfire: model.JobMisfire{
+				Policy:   job.MisfirePolicy,
+				MaxCount: job.MisfireMax,
+			},
+			Jitter: model.JobJitter{
+				MaxSeconds: job.JitterMax,
+				Hash:       job.JitterHash,
+			},
+			Priority:     job.Priority,
+			ScheduleType: job.ScheduleType,
+			FileWatch: model.JobFileWatch{
+				Path:          job.WatchPath,
+				StableSeconds: job.WatchStable,
+				ProcessedDir:  job.WatchProcessed,
+			},
+			Dispatched: job.Dispatched,
+		}
+		if err = syncJob(jobSvc, req); err != nil {
 			slog.Error("sync job failed", "id", job.Id, "name", job.Name, "err", err)
 			continue
 		}
 		slog.Info("sync job success", "id", job.Id, "name", job.Name)
 
+		// 补执行节点离线期间错过的任务
+		if job.Active == model.JobStart && job.LastNextExecTime > 0 {
+			until := time.Now()
+			if restored.contains(job.Id) {
+				until = restored.Time
+			}
+			err = jobSvc.RunMisfire(context.Background(), job.Id, utils.TimestampToTime(job.LastNextExecTime), until)
+			if err != nil {
+				slog.Error("run misfire job failed", "id", job.Id, "name", job.Name, "err", err)
+			}
+		}
+
 	}
 	return nil
 }
+
+// removeStaleJobs 移除本地恢复但 master 上已经不在该节点的任务，只在完整查询到 master 的任务后调用
+func removeStaleJobs(jobSvc service.IJobService, restored Restored, jobs []dto.RespJob) {
+	onMaster := make(map[int]struct{}, len(jobs))
+	for _, job := range jobs {
+		onMaster[job.Id] = struct{}{}
+	}
+	for _, id := range restored.Ids {
+		if _, ok := onMaster[id]; ok {
+			continue
+		}
+		jobSvc.DeleteJob(context.Background(), id)
+		slog.Info("remove restored job not on master", "id", id)
+	}
+}
+
+// queryJobsFromMaster 分页查询 master 上应该在该节点上的任务，直到查询完 master 返回的总数
+func queryJobsFromMaster() ([]dto.RespJob, error) {
+	header := map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": auth.GetJwtToken(),
+	}
+	url := httpClient.URL(config.App.Master.Address, paths.JobListAPI)
+	var jobs []dto.RespJob
+	for pageNum := 1; ; pageNum++ {
+		params := map[string]string{
+			"page_num":  strconv.Itoa(pageNum),
+			"page_size": strconv.Itoa(syncPageSize),
+		}
+		resp, err := httpClient.GetJson(context.Background(), url, header, params, 3*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		parseResp, err := httpClient.ParseResponseWith[Resp[respJobListData]](resp)
+		if err != nil {
+			return nil, err
+		}
+		if parseResp.Code != 0 {
+			return nil, errors.New(parseResp.Msg)
+		}
+		jobs = append(jobs, parseResp.Data.Data...)
+		if len(parseResp.Data.Data) == 0 || len(jobs) >= parseResp.Data.Total {
+			return jobs, nil
+		}
+	}
+}
+
+// syncJob 节点上没有的任务直接添加，已有的任务定义或启用状态不一致时更新
+func syncJob(jobSvc service.IJobService, req dto.ReqNodeJob) error {
+	j, err := jobSvc.GetJob(context.Background(), req.Id)
+	if err != nil {
+		return jobSvc.AddJob(context.Background(), req)
+	}
+	if j.JobMeta.Digest == req.Digest() && j.JobMeta.Active == req.Active {
+		return nil
+	}
+	return jobSvc.UpdateJob(context.Background(), req)
+}
+
+// RetrySyncJobFromMaster master 不可用时使用本地恢复的任务调度，定期重试直到同步成功
+func RetrySyncJobFromMaster(ctx context.Context, jobSvc service.IJobService, restored Restored) {
+	interval := syncRetryInterval
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		err := SyncJobFromMaster(jobSvc, restored)
+		if err == nil {
+			slog.Info("sync job from master success after retry")
+			return
+		}
+		interval = min(interval*2, maxSyncRetryInterval)
+		slog.Error("retry sync job from master error", "next retry", interval, "err", err)
+	}
+}
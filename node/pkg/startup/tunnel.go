@@ -0,0 +1,97 @@
+package startup
+
+import (
+	"context"
+	"fmt"
+	"go-job/internal/pkg/consts"
+	"go-job/internal/pkg/httpClient"
+	"go-job/internal/pkg/paths"
+	"go-job/internal/pkg/tunnel"
+	"go-job/node/pkg/auth"
+	"go-job/node/pkg/config"
+	"golang.org/x/net/websocket"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// tunnelKeepalive 反向连接的保活间隔
+	tunnelKeepalive   = 15 * time.Second
+	tunnelDialTimeout = 10 * time.Second
+	// 反向连接断开后重新连接的间隔
+	tunnelRetryInterval    = 5 * time.Second
+	maxTunnelRetryInterval = time.Minute
+)
+
+var masterConns = tunnel.NewRegistry()
+
+// InitTunnel 发往 master 的请求在反向连接建立后通过连接发送，需要在发送请求之前调用
+func InitTunnel() {
+	httpClient.WrapTransport(func(base http.RoundTripper) http.RoundTripper {
+		return &tunnel.Transport{Base: base, Registry: masterConns}
+	})
+}
+
+// closeTunnel 断开当前的反向连接，RunTunnel 会重新连接
+func closeTunnel() {
+	if conn := masterConns.Get(config.App.Master.Address); conn != nil {
+		_ = conn.Close()
+	}
+}
+
+// RunTunnel 保持到 master 的反向连接，master 通过该连接访问节点，断开后重新连接，直到 ctx 结束
+func RunTunnel(ctx context.Context, handler http.Handler) {
+	interval := tunnelRetryInterval
+	for {
+		start := time.Now()
+		err := connectMaster(ctx, handler)
+		if ctx.Err() != nil {
+			return
+		}
+		// 连接保持过一段时间后断开，从最短间隔开始重试
+		if time.Since(start) > maxTunnelRetryInterval {
+			interval = tunnelRetryInterval
+		}
+		slog.Error("tunnel to master disconnected", "next retry", interval, "err", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		interval = min(interval*2, maxTunnelRetryInterval)
+	}
+}
+
+// connectMaster 建立反向连接并处理 master 发送的请求，直到连接断开
+func connectMaster(ctx context.Context, handler http.Handler) error {
+	address, err := advertiseAddress()
+	if err != nil {
+		return err
+	}
+	if err = auth.RefreshToken(); err != nil {
+		return err
+	}
+	// 开启 TLS 时使用 wss
+	url := "ws" + strings.TrimPrefix(httpClient.URL(config.App.Master.Address, paths.NodeConnectAPI), "http")
+	cfg, err := websocket.NewConfig(url, fmt.Sprintf("http://%s", address))
+	if err != nil {
+		return err
+	}
+	cfg.TlsConfig = httpClient.TLSConfig()
+	cfg.Header.Set("Authorization", auth.GetJwtToken())
+	cfg.Header.Set(consts.NodeAddressHeader, address)
+	dialCtx, cancel := context.WithTimeout(ctx, tunnelDialTimeout)
+	ws, err := cfg.DialContext(dialCtx)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	conn := tunnel.NewConn(ws, handler)
+	masterConns.Add(config.App.Master.Address, conn)
+	defer masterConns.Remove(config.App.Master.Address, conn)
+	slog.Info("tunnel to master connected", "master", config.App.Master.Address, "address", address)
+	return conn.Serve(ctx, tunnelKeepalive)
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"go-job/internal/dto"
+	"go-job/internal/pkg/utils"
+	"go-job/node/pkg/config"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// JobStore 将节点上的任务定义保存到本地文件，master 不可用时节点重启后从文件恢复任务，
+// 任务文件保存在 upload_job_dir 中，不需要另外保存
+type JobStore struct {
+	mux  sync.Mutex
+	path string // 为空时只保存在内存中
+	jobs map[int]dto.ReqNodeJob
+}
+
+// InitJobStore 使用配置中的文件保存任务定义
+func InitJobStore() *JobStore {
+	return NewJobStore(config.App.Data.JobStoreFile)
+}
+
+// NewJobStore 从 path 加载已保存的任务，文件损坏时重命名为 .corrupt 后从空开始，避免节点无法启动
+func NewJobStore(path string) *JobStore {
+	s := &JobStore{
+		path: path,
+		jobs: make(map[int]dto.ReqNodeJob),
+	}
+	if path == "" {
+		return s
+	}
+	jobs, err := load(path)
+	if err != nil {
+		slog.Error("load job store error", "path", path, "err", err)
+		if err = os.Rename(path, path+".corrupt"); err != nil {
+			slog.Error("rename corrupt job store error", "path", path, "err", err)
+		}
+		return s
+	}
+	for _, job := range jobs {
+		s.jobs[job.Id] = job
+	}
+	return s
+}
+
+func load(path string) ([]dto.ReqNodeJob, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var jobs []dto.ReqNodeJob
+	if err = json.Unmarshal(data, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// Save 新增或更新任务定义
+func (s *JobStore) Save(job dto.ReqNodeJob) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.jobs[job.Id] = job
+	return s.flush()
+}
+
+// Delete 删除任务定义，任务不存在时忽略
+func (s *JobStore) Delete(id int) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if _, ok := s.jobs[id]; !ok {
+		return nil
+	}
+	delete(s.jobs, id)
+	return s.flush()
+}
+
+// List 已保存的任务定义，按任务id排序
+func (s *JobStore) List() []dto.ReqNodeJob {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.list()
+}
+
+func (s *JobStore) list() []dto.ReqNodeJob {
+	jobs := make([]dto.ReqNodeJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].Id < jobs[b].Id })
+	return jobs
+}
+
+// flush 保存所有任务定义
+func (s *JobStore) flush() error {
+	if s.path == "" {
+		return nil
+	}
+	return writeJson(s.path, s.list())
+}
+
+// writeJson 先写临时文件再重命名，写入过程中节点退出不会损坏已保存的文件
+func writeJson(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = utils.EnsureDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-job/internal/dto"
+	"go-job/internal/model"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJobStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "jobs.json")
+	s := NewJobStore(path)
+	require.NoError(t, s.Save(dto.ReqNodeJob{Id: 2, Name: "b", Active: model.JobStart}))
+	require.NoError(t, s.Save(dto.ReqNodeJob{Id: 1, Name: "a", Active: model.JobStop}))
+	require.NoError(t, s.Save(dto.ReqNodeJob{Id: 2, Name: "b2", Active: model.JobStart}))
+	require.NoError(t, s.Delete(3))
+
+	// 重新加载后与保存的一致
+	loaded := NewJobStore(path).List()
+	assert.Equal(t, []dto.ReqNodeJob{
+		{Id: 1, Name: "a", Active: model.JobStop},
+		{Id: 2, Name: "b2", Active: model.JobStart},
+	}, loaded)
+
+	require.NoError(t, s.Delete(1))
+	assert.Equal(t, []dto.ReqNodeJob{{Id: 2, Name: "b2", Active: model.JobStart}}, NewJobStore(path).List())
+}
+
+func TestJobStore_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	require.NoError(t, os.WriteFile(path, []byte("{bad"), 0644))
+
+	s := NewJobStore(path)
+	assert.Empty(t, s.List())
+	_, err := os.Stat(path + ".corrupt")
+	assert.NoError(t, err)
+
+	require.NoError(t, s.Save(dto.ReqNodeJob{Id: 1, Name: "a"}))
+	assert.Len(t, NewJobStore(path).List(), 1)
+}
+
+func TestJobStore_Memory(t *testing.T) {
+	s := NewJobStore("")
+	require.NoError(t, s.Save(dto.ReqNodeJob{Id: 1, Name: "a"}))
+	assert.Len(t, s.List(), 1)
+}
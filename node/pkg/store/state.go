@@ -0,0 +1,68 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"go-job/node/pkg/config"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// NodeState 节点重启后需要保留的状态
+type NodeState struct {
+	Maintenance bool `json:"maintenance"`
+}
+
+// StateStore 将节点状态保存到本地文件，节点重启后恢复，避免 master 上的状态和节点不一致
+type StateStore struct {
+	mux   sync.Mutex
+	path  string // 为空时只保存在内存中
+	state NodeState
+}
+
+// InitStateStore 使用配置中的文件保存节点状态
+func InitStateStore() *StateStore {
+	return NewStateStore(config.App.Data.StateFile)
+}
+
+// NewStateStore 从 path 加载已保存的状态，文件损坏时重命名为 .corrupt 后使用默认状态
+func NewStateStore(path string) *StateStore {
+	s := &StateStore{path: path}
+	if path == "" {
+		return s
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &s.state)
+	}
+	if err != nil {
+		slog.Error("load state store error", "path", path, "err", err)
+		if err = os.Rename(path, path+".corrupt"); err != nil {
+			slog.Error("rename corrupt state store error", "path", path, "err", err)
+		}
+		s.state = NodeState{}
+	}
+	return s
+}
+
+// Get 当前保存的状态
+func (s *StateStore) Get() NodeState {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.state
+}
+
+// SetMaintenance 保存维护状态
+func (s *StateStore) SetMaintenance(maintenance bool) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.state.Maintenance = maintenance
+	if s.path == "" {
+		return nil
+	}
+	return writeJson(s.path, s.state)
+}
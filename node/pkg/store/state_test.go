@@ -0,0 +1,31 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "state.json")
+	assert.False(t, NewStateStore(path).Get().Maintenance)
+
+	s := NewStateStore(path)
+	require.NoError(t, s.SetMaintenance(true))
+	assert.True(t, NewStateStore(path).Get().Maintenance)
+
+	require.NoError(t, s.SetMaintenance(false))
+	assert.False(t, NewStateStore(path).Get().Maintenance)
+}
+
+func TestStateStore_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(path, []byte("{bad"), 0644))
+
+	s := NewStateStore(path)
+	assert.False(t, s.Get().Maintenance)
+	_, err := os.Stat(path + ".corrupt")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,147 @@
+package watcher
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const defaultInterval = time.Second
+
+// fileState 文件最近一次扫描到的状态
+type fileState struct {
+	size    int64
+	modTime time.Time
+	since   time.Time // 从该时间起大小和修改时间没有变化
+	fired   bool      // 当前版本的文件是否已经回调过
+}
+
+// Watcher 轮询匹配 pattern 的文件，文件出现并且在 stable 时间内大小和修改时间都不变后回调 onFile
+// 启动时已经存在的文件也会回调，避免节点离线期间到达的文件被遗漏
+type Watcher struct {
+	pattern  string
+	stable   time.Duration
+	interval time.Duration
+	onFile   func(path string)
+	skip     func() bool // 返回 true 时跳过本次扫描，如节点调度已暂停
+
+	mux      sync.Mutex
+	files    map[string]*fileState
+	filesMux sync.Mutex
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+func New(pattern string, stable time.Duration, onFile func(path string)) *Watcher {
+	return &Watcher{
+		pattern:  pattern,
+		stable:   stable,
+		interval: defaultInterval,
+		onFile:   onFile,
+		files:    make(map[string]*fileState),
+	}
+}
+
+// SetSkip 设置跳过扫描的条件，跳过期间文件不会回调，恢复后继续
+func (w *Watcher) SetSkip(skip func() bool) *Watcher {
+	w.skip = skip
+	return w
+}
+
+// Start 开始轮询，重复调用无影响
+func (w *Watcher) Start() {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if w.stop != nil {
+		return
+	}
+	w.stop = make(chan struct{})
+	w.wg.Add(1)
+	go w.loop(w.stop)
+}
+
+// Stop 停止轮询，等待正在进行的回调结束
+func (w *Watcher) Stop() {
+	w.mux.Lock()
+	if w.stop == nil {
+		w.mux.Unlock()
+		return
+	}
+	close(w.stop)
+	w.stop = nil
+	w.mux.Unlock()
+	w.wg.Wait()
+}
+
+func (w *Watcher) loop(stop chan struct{}) {
+	defer w.wg.Done()
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if w.skip != nil && w.skip() {
+				continue
+			}
+			for _, path := range w.scan(time.Now()) {
+				w.onFile(path)
+			}
+		}
+	}
+}
+
+// Retry 文件没有被处理时调用，重新等待稳定时间后再次回调
+func (w *Watcher) Retry(path string) {
+	w.filesMux.Lock()
+	defer w.filesMux.Unlock()
+	if state, ok := w.files[path]; ok {
+		state.fired = false
+		state.since = time.Now()
+	}
+}
+
+// scan 扫描一次，返回本次达到稳定时间的文件
+func (w *Watcher) scan(now time.Time) []string {
+	matches, err := filepath.Glob(w.pattern)
+	if err != nil {
+		slog.Error("watch glob error", "pattern", w.pattern, "err", err)
+		return nil
+	}
+
+	w.filesMux.Lock()
+	defer w.filesMux.Unlock()
+	var (
+		ready []string
+		seen  = make(map[string]struct{}, len(matches))
+	)
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		seen[path] = struct{}{}
+
+		state, ok := w.files[path]
+		if !ok || state.size != info.Size() || !state.modTime.Equal(info.ModTime()) {
+			// 新文件或文件发生了变化，重新计算稳定时间
+			w.files[path] = &fileState{size: info.Size(), modTime: info.ModTime(), since: now}
+			state = w.files[path]
+		}
+		if !state.fired && now.Sub(state.since) >= w.stable {
+			state.fired = true
+			ready = append(ready, path)
+		}
+	}
+
+	// 已经被移走或删除的文件不再跟踪
+	for path := range w.files {
+		if _, ok := seen[path]; !ok {
+			delete(w.files, path)
+		}
+	}
+	return ready
+}
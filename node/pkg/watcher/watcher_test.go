@@ -0,0 +1,42 @@
+package watcher
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcherScan(t *testing.T) {
+	dir := t.TempDir()
+	w := New(filepath.Join(dir, "*.csv"), 5*time.Second, nil)
+	csv := filepath.Join(dir, "a.csv")
+	now := time.Now()
+
+	require.NoError(t, os.WriteFile(csv, []byte("1"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("1"), 0644))
+
+	// 刚出现的文件需要等待稳定
+	assert.Empty(t, w.scan(now))
+	assert.Empty(t, w.scan(now.Add(3*time.Second)))
+	assert.Equal(t, []string{csv}, w.scan(now.Add(5*time.Second)))
+	// 同一个文件只回调一次
+	assert.Empty(t, w.scan(now.Add(10*time.Second)))
+
+	// 文件被修改后重新等待稳定
+	require.NoError(t, os.WriteFile(csv, []byte("12"), 0644))
+	assert.Empty(t, w.scan(now.Add(11*time.Second)))
+	assert.Equal(t, []string{csv}, w.scan(now.Add(16*time.Second)))
+
+	// 没有被处理的文件重新等待稳定后再次回调
+	w.Retry(csv)
+	assert.Empty(t, w.scan(time.Now()))
+	assert.Equal(t, []string{csv}, w.scan(time.Now().Add(5*time.Second)))
+
+	// 移走的文件不再跟踪
+	require.NoError(t, os.Remove(csv))
+	assert.Empty(t, w.scan(now.Add(17*time.Second)))
+	assert.Empty(t, w.files)
+}
@@ -0,0 +1,269 @@
+package worker
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const (
+	defaultQueueSize = 1000
+	defaultMaxWait   = 5 * time.Minute
+	sweepInterval    = time.Second
+)
+
+var (
+	ErrQueueFull   = errors.New("执行队列已满")
+	ErrPoolClosed  = errors.New("执行队列已关闭")
+	ErrWaitTimeout = errors.New("排队超时")
+)
+
+// Task 提交到 Pool 的一次执行
+type Task struct {
+	Priority int                                 // 优先级，值越大越先执行
+	Run      func()                              // 获取到worker后执行
+	Drop     func(wait time.Duration, err error) // 未能执行时调用，如排队超时，队列已满
+
+	enqueueTime time.Time
+	seq         uint64
+}
+
+type Stats struct {
+	Workers   int `json:"workers"`    // worker 数量
+	Running   int `json:"running"`    // 正在执行的数量
+	Queued    int `json:"queued"`     // 排队中的数量
+	QueueSize int `json:"queue_size"` // 队列容量
+}
+
+// Pool 有界的优先级执行池，同一时间最多 size 个任务在执行
+type Pool struct {
+	mux       sync.Mutex
+	cond      *sync.Cond
+	queue     taskQueue
+	seq       uint64
+	size      int
+	queueSize int
+	maxWait   time.Duration
+	running   int
+	closed    bool
+	wg        sync.WaitGroup
+	stop      chan struct{}
+}
+
+// NewPool 创建执行池，参数为0时使用默认值
+func NewPool(size, queueSize int, maxWait time.Duration) *Pool {
+	if size <= 0 {
+		size = runtime.NumCPU()
+	}
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	if maxWait <= 0 {
+		maxWait = defaultMaxWait
+	}
+	p := &Pool{
+		size:      size,
+		queueSize: queueSize,
+		maxWait:   maxWait,
+		stop:      make(chan struct{}),
+	}
+	p.cond = sync.NewCond(&p.mux)
+	p.wg.Add(size)
+	for i := 0; i < size; i++ {
+		go p.work()
+	}
+	go p.sweep()
+	return p
+}
+
+// Submit 提交任务，队列已满时直接调用 Drop
+func (p *Pool) Submit(task *Task) {
+	p.mux.Lock()
+	if p.closed || len(p.queue) >= p.queueSize {
+		err := ErrQueueFull
+		if p.closed {
+			err = ErrPoolClosed
+		}
+		p.mux.Unlock()
+		task.Drop(0, err)
+		return
+	}
+	p.seq++
+	task.seq = p.seq
+	task.enqueueTime = time.Now()
+	heap.Push(&p.queue, task)
+	p.mux.Unlock()
+	p.cond.Signal()
+}
+
+func (p *Pool) Stats() Stats {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return Stats{
+		Workers:   p.size,
+		Running:   p.running,
+		Queued:    len(p.queue),
+		QueueSize: p.queueSize,
+	}
+}
+
+// Close 停止接收新任务，等待队列中的任务执行完成
+func (p *Pool) Close() {
+	p.close(false)
+	p.wg.Wait()
+}
+
+// Shutdown 停止接收新任务，丢弃排队中的任务，等待正在执行的任务结束，ctx 结束时返回 ctx 的错误
+func (p *Pool) Shutdown(ctx context.Context) error {
+	for _, task := range p.close(true) {
+		task.Drop(time.Since(task.enqueueTime), ErrPoolClosed)
+	}
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close 标记为已关闭并唤醒 worker，dropQueued 为 true 时取出排队中的任务返回
+func (p *Pool) close(dropQueued bool) []*Task {
+	p.mux.Lock()
+	var queued []*Task
+	if dropQueued {
+		queued = p.queue
+		p.queue = nil
+	}
+	if p.closed {
+		p.mux.Unlock()
+		return queued
+	}
+	p.closed = true
+	p.mux.Unlock()
+	close(p.stop)
+	p.cond.Broadcast()
+	return queued
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for {
+		p.mux.Lock()
+		for len(p.queue) == 0 && !p.closed {
+			p.cond.Wait()
+		}
+		if len(p.queue) == 0 {
+			p.mux.Unlock()
+			return
+		}
+		task := heap.Pop(&p.queue).(*Task)
+		wait := time.Since(task.enqueueTime)
+		if wait > p.maxWait {
+			p.mux.Unlock()
+			task.Drop(wait, ErrWaitTimeout)
+			continue
+		}
+		p.running++
+		p.mux.Unlock()
+
+		task.Run()
+
+		p.mux.Lock()
+		p.running--
+		p.mux.Unlock()
+	}
+}
+
+// sweep 定期丢弃排队超时的任务，避免低优先级任务一直等待到被取出
+func (p *Pool) sweep() {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			for _, task := range p.removeExpired(time.Now()) {
+				task.Drop(time.Since(task.enqueueTime), ErrWaitTimeout)
+			}
+		}
+	}
+}
+
+func (p *Pool) removeExpired(now time.Time) []*Task {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	var (
+		expired []*Task
+		remain  = p.queue[:0]
+	)
+	for _, task := range p.queue {
+		if now.Sub(task.enqueueTime) > p.maxWait {
+			expired = append(expired, task)
+		} else {
+			remain = append(remain, task)
+		}
+	}
+	if len(expired) > 0 {
+		p.queue = remain
+		heap.Init(&p.queue)
+	}
+	return expired
+}
+
+// ============= 优先级队列 ============= //
+
+type taskQueue []*Task
+
+func (q taskQueue) Len() int { return len(q) }
+
+func (q taskQueue) Less(i, j int) bool {
+	if q[i].Priority != q[j].Priority {
+		return q[i].Priority > q[j].Priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q taskQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *taskQueue) Push(x any) { *q = append(*q, x.(*Task)) }
+
+func (q *taskQueue) Pop() any {
+	old := *q
+	n := len(old)
+	task := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return task
+}
+
+// ============= 节点全局执行池 ============= //
+
+var defaultPool *Pool
+
+// InitPool 初始化节点全局执行池
+func InitPool(size, queueSize int, maxWait time.Duration) {
+	defaultPool = NewPool(size, queueSize, maxWait)
+}
+
+// GetPool 获取节点全局执行池，未初始化时返回 nil
+func GetPool() *Pool {
+	return defaultPool
+}
+
+// Submit 提交到节点全局执行池，未初始化执行池时直接执行
+func Submit(task *Task) {
+	if defaultPool == nil {
+		go task.Run()
+		return
+	}
+	defaultPool.Submit(task)
+}
@@ -0,0 +1,128 @@
+package worker
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	testCases := []struct {
+		name       string
+		queueSize  int
+		maxWait    time.Duration
+		priorities []int
+		wantOrder  []int
+		wantErrs   []error
+	}{
+		{
+			name:       "priority first",
+			queueSize:  10,
+			maxWait:    time.Minute,
+			priorities: []int{1, 5, 3, 5},
+			wantOrder:  []int{5, 5, 3, 1},
+		}, {
+			name:       "queue full",
+			queueSize:  2,
+			maxWait:    time.Minute,
+			priorities: []int{1, 2, 3},
+			wantOrder:  []int{2, 1},
+			wantErrs:   []error{ErrQueueFull},
+		}, {
+			name:       "wait timeout",
+			queueSize:  10,
+			maxWait:    50 * time.Millisecond,
+			priorities: []int{1, 2},
+			wantErrs:   []error{ErrWaitTimeout, ErrWaitTimeout},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := NewPool(1, tc.queueSize, tc.maxWait)
+
+			// 先占住唯一的worker，保证后续任务都进入队列
+			block := make(chan struct{})
+			started := make(chan struct{})
+			p.Submit(&Task{Run: func() {
+				close(started)
+				<-block
+			}})
+			<-started
+
+			var (
+				mux   sync.Mutex
+				order []int
+				errs  []error
+				wg    sync.WaitGroup
+			)
+			for _, priority := range tc.priorities {
+				wg.Add(1)
+				p.Submit(&Task{
+					Priority: priority,
+					Run: func() {
+						defer wg.Done()
+						mux.Lock()
+						order = append(order, priority)
+						mux.Unlock()
+					},
+					Drop: func(wait time.Duration, err error) {
+						defer wg.Done()
+						mux.Lock()
+						errs = append(errs, err)
+						mux.Unlock()
+					},
+				})
+			}
+			if tc.maxWait < time.Second {
+				time.Sleep(2 * sweepInterval)
+			}
+			close(block)
+			wg.Wait()
+			p.Close()
+
+			assert.Equal(t, tc.wantOrder, order)
+			assert.Equal(t, tc.wantErrs, errs)
+		})
+	}
+}
+
+func TestPoolShutdown(t *testing.T) {
+	testCases := []struct {
+		name    string
+		runFor  time.Duration // 正在执行的任务的耗时
+		timeout time.Duration
+		wantErr error
+	}{
+		{name: "running finished", runFor: 20 * time.Millisecond, timeout: time.Second},
+		{name: "timeout", runFor: time.Second, timeout: 20 * time.Millisecond, wantErr: context.DeadlineExceeded},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := NewPool(1, 10, time.Minute)
+			started := make(chan struct{})
+			p.Submit(&Task{Run: func() {
+				close(started)
+				time.Sleep(tc.runFor)
+			}})
+			<-started
+
+			// 排队中的任务直接丢弃
+			var dropErrs []error
+			p.Submit(&Task{
+				Run:  func() { t.Error("queued task should not run") },
+				Drop: func(wait time.Duration, err error) { dropErrs = append(dropErrs, err) },
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+			defer cancel()
+			assert.ErrorIs(t, p.Shutdown(ctx), tc.wantErr)
+			assert.Equal(t, []error{ErrPoolClosed}, dropErrs)
+
+			// 关闭后提交的任务也直接丢弃
+			p.Submit(&Task{Drop: func(wait time.Duration, err error) { dropErrs = append(dropErrs, err) }})
+			assert.Equal(t, []error{ErrPoolClosed, ErrPoolClosed}, dropErrs)
+		})
+	}
+}
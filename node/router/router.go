@@ -3,11 +3,13 @@ package router
 import (
 	"github.com/gin-gonic/gin"
 	"go-job/node/api"
+	"go-job/node/pkg/middleware"
 )
 
 func NewWebRouter(jobApi *api.JobApi, nodeApi *api.NodeApi) *gin.Engine {
 	server := gin.Default()
-	group := server.Group("/api/go-job/node")
+	// 节点的接口只允许 master 调用，校验 master 请求的签名
+	group := server.Group("/api/go-job/node", middleware.VerifySign())
 	jobApi.RegisterRoutes(group)
 	nodeApi.RegisterRoutes(group)
 	return server
@@ -6,9 +6,15 @@ import (
 	"fmt"
 	"go-job/internal/dto"
 	"go-job/internal/model"
+	"go-job/internal/pkg/utils"
+	"go-job/node/pkg/config"
 	"go-job/node/pkg/executor"
 	"go-job/node/pkg/job"
+	"go-job/node/pkg/store"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
 	"time"
 )
 
@@ -21,13 +27,22 @@ type IJobService interface {
 	DeleteJob(ctx context.Context, id int)
 	UpdateJob(ctx context.Context, req dto.ReqNodeJob) error
 	GetJob(ctx context.Context, id int) (*job.Job, error)
+	RunMisfire(ctx context.Context, id int, lastNextExecTime, until time.Time) error
+	TriggerJob(ctx context.Context, id int, req dto.ReqNodeJobTrigger) error
+	GetJobStates(ctx context.Context) []dto.RespNodeJobState
+	GetJobInfo(ctx context.Context, id int) (dto.RespNodeJob, error)
+	GetJobList(ctx context.Context) []dto.RespNodeJob
+	RestoreJobs(ctx context.Context) []int
 }
 
 type JobService struct {
+	store *store.JobStore
 }
 
-func NewJobService() IJobService {
-	return &JobService{}
+func NewJobService(store *store.JobStore) IJobService {
+	return &JobService{
+		store: store,
+	}
 }
 
 func (s *JobService) AddJob(ctx context.Context, req dto.ReqNodeJob) error {
@@ -47,9 +62,33 @@ func (s *JobService) AddJob(ctx context.Context, req dto.ReqNodeJob) error {
 	}
 
 	job.AddJob(jj)
+	s.saveJob(req)
 	return nil
 }
 
+// saveJob 保存到本地，保存失败不影响节点上的调度，只在节点重启时无法恢复
+func (s *JobService) saveJob(req dto.ReqNodeJob) {
+	if err := s.store.Save(req); err != nil {
+		slog.Error("save job to local store error", "job id", req.Id, "err", err)
+	}
+}
+
+// RestoreJobs 从本地恢复任务定义，节点启动时在同步 master 之前调用，返回恢复成功的任务id
+func (s *JobService) RestoreJobs(ctx context.Context) []int {
+	var ids []int
+	for _, req := range s.store.List() {
+		if err := s.AddJob(ctx, req); err != nil {
+			slog.Error("restore job error", "job id", req.Id, "job name", req.Name, "err", err)
+			continue
+		}
+		ids = append(ids, req.Id)
+	}
+	if len(ids) > 0 {
+		slog.Info("restore jobs from local store", "count", len(ids))
+	}
+	return ids
+}
+
 func (s *JobService) buildJobItem(ctx context.Context, req dto.ReqNodeJob) (*job.Job, error) {
 	// 获取对于的执行器
 	exec, err := s.newExecutor(ctx, req)
@@ -72,6 +111,9 @@ func (s *JobService) DeleteJob(ctx context.Context, id int) {
 	if j, err := s.GetJob(ctx, id); err == nil {
 		s.removeJob(j)
 	}
+	if err := s.store.Delete(id); err != nil {
+		slog.Error("delete job from local store error", "job id", id, "err", err)
+	}
 }
 
 func (s *JobService) removeJob(j *job.Job) {
@@ -102,6 +144,7 @@ func (s *JobService) UpdateJob(ctx context.Context, req dto.ReqNodeJob) error {
 	}
 
 	job.AddJob(jj)
+	s.saveJob(req)
 	return nil
 }
 
@@ -113,6 +156,129 @@ func (s *JobService) GetJob(ctx context.Context, id int) (*job.Job, error) {
 	return j, nil
 }
 
+// RunMisfire 根据job的补偿策略，在后台补执行 lastNextExecTime 到 until 之间错过的任务
+func (s *JobService) RunMisfire(ctx context.Context, id int, lastNextExecTime, until time.Time) error {
+	j, err := s.GetJob(ctx, id)
+	if err != nil {
+		return err
+	}
+	times := j.MisfireTimes(lastNextExecTime, until)
+	if len(times) == 0 {
+		return nil
+	}
+	slog.Info("run misfire job", "job id", j.JobMeta.Id, "job name", j.JobMeta.Name,
+		"policy", j.JobMeta.Misfire.Policy, "count", len(times))
+	go j.RunMisfire(times)
+	return nil
+}
+
+// TriggerJob 立即触发一次执行，提交到执行池后返回
+func (s *JobService) TriggerJob(ctx context.Context, id int, req dto.ReqNodeJobTrigger) error {
+	j, err := s.GetJob(ctx, id)
+	if err != nil {
+		return err
+	}
+	scheduledTime := time.Now().Truncate(time.Second)
+	if req.ScheduledTime > 0 {
+		scheduledTime = utils.TimestampToTime(req.ScheduledTime)
+	}
+	j.Trigger(executor.Trigger{
+		Type:          req.TriggerType,
+		ScheduledTime: scheduledTime,
+		RunId:         req.RunId,
+		NodeId:        req.NodeId,
+		Stdin:         req.Stdin,
+		Env:           req.Env,
+	})
+	return nil
+}
+
+// GetJobStates 节点上所有任务的定义摘要、启用状态和文件校验和，按任务id排序
+func (s *JobService) GetJobStates(ctx context.Context) []dto.RespNodeJobState {
+	jobs := job.GetAllJobs()
+	states := make([]dto.RespNodeJobState, 0, len(jobs))
+	for _, j := range jobs {
+		state := dto.RespNodeJobState{
+			Id:     j.JobMeta.Id,
+			Digest: j.JobMeta.Digest,
+			Active: j.JobMeta.Active,
+		}
+		state.FileChecksum = jobFileChecksum(j)
+		states = append(states, state)
+	}
+	sort.Slice(states, func(a, b int) bool { return states[a].Id < states[b].Id })
+	return states
+}
+
+// GetJobInfo job的定义和运行时状态
+func (s *JobService) GetJobInfo(ctx context.Context, id int) (dto.RespNodeJob, error) {
+	j, err := s.GetJob(ctx, id)
+	if err != nil {
+		return dto.RespNodeJob{}, err
+	}
+	return jobInfo(j, time.Now()), nil
+}
+
+// GetJobList 节点上所有job的定义和运行时状态，按任务id排序，执行结果不包含输出
+func (s *JobService) GetJobList(ctx context.Context) []dto.RespNodeJob {
+	now := time.Now()
+	jobs := job.GetAllJobs()
+	list := make([]dto.RespNodeJob, 0, len(jobs))
+	for _, j := range jobs {
+		info := jobInfo(j, now)
+		if info.LastResult != nil {
+			result := *info.LastResult
+			result.Output = ""
+			info.LastResult = &result
+		}
+		list = append(list, info)
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].Id < list[b].Id })
+	return list
+}
+
+func jobInfo(j *job.Job, now time.Time) dto.RespNodeJob {
+	rt := j.Runtime()
+	info := dto.RespNodeJob{
+		Id:            j.JobMeta.Id,
+		Name:          j.JobMeta.Name,
+		ExecType:      j.JobMeta.ExecType,
+		CronExpr:      j.JobMeta.CronExpr,
+		ScheduleType:  j.JobMeta.ScheduleType,
+		Dispatched:    j.JobMeta.Dispatched,
+		Active:        j.JobMeta.Active,
+		RunningStatus: rt.RunningStatus,
+		LastResult:    rt.LastResult,
+		Processes:     make([]dto.RespNodeJobProcess, 0, len(rt.Processes)),
+		FileName:      j.JobMeta.FileName,
+		FileChecksum:  jobFileChecksum(j),
+	}
+	if !rt.NextExecTime.IsZero() {
+		info.NextExecTime = rt.NextExecTime.Unix()
+	}
+	for _, p := range rt.Processes {
+		info.Processes = append(info.Processes, dto.RespNodeJobProcess{
+			Pid:       p.Pid,
+			StartTime: p.StartTime.Unix(),
+			Elapsed:   now.Sub(p.StartTime).Seconds(),
+		})
+	}
+	return info
+}
+
+// jobFileChecksum job文件的 sha256，文件不存在时为空
+func jobFileChecksum(j *job.Job) string {
+	if j.JobMeta.FileName == "" {
+		return ""
+	}
+	path := filepath.Join(config.App.Data.UploadJobDir, j.JobMeta.FileName)
+	sum, err := utils.FileSHA256(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Error("job file checksum error", "job id", j.JobMeta.Id, "path", path, "err", err)
+	}
+	return sum
+}
+
 func (s *JobService) newExecutor(ctx context.Context, req dto.ReqNodeJob) (executor.IExecutor, error) {
 	factory, ok := executor.GetExecutor(req.ExecType)
 	if !ok {
@@ -7,6 +7,11 @@ import (
 	"fmt"
 	"go-job/internal/dto"
 	"go-job/internal/model"
+	"go-job/internal/pkg/utils"
+	"go-job/node/pkg/job"
+	"go-job/node/pkg/store"
+	"go-job/node/pkg/worker"
+	"log/slog"
 	"os/exec"
 	"strings"
 	"time"
@@ -14,9 +19,23 @@ import (
 
 const defaultTimeout = time.Second * 10
 
+var errPyPkgName = errors.New("invalid python package name")
+
+// 等待正在执行的任务结束的默认和最长时间
+const (
+	defaultDrainTimeout = time.Minute
+	maxDrainTimeout     = 10 * time.Minute
+)
+
 type INodeService interface {
 	InstallRef(ctx context.Context, req dto.ReqNodeRef) (string, error)
 	GetNodeInfo(ctx context.Context) map[string]any
+	PauseScheduler(ctx context.Context)
+	ResumeScheduler(ctx context.Context)
+	EnterMaintenance(ctx context.Context)
+	ExitMaintenance(ctx context.Context)
+	RestoreMaintenance(ctx context.Context)
+	Drain(ctx context.Context, req dto.ReqNodeDrain) dto.RespNodeDrain
 }
 
 type installRefInfo struct {
@@ -24,12 +43,14 @@ type installRefInfo struct {
 }
 
 type NodeService struct {
+	state              *store.StateStore
 	timeout            time.Duration
 	installRefHandlers map[model.NodeInstallRefType]func(ctx context.Context, info installRefInfo) (string, error)
 }
 
-func NewNodeService() INodeService {
+func NewNodeService(state *store.StateStore) INodeService {
 	s := &NodeService{
+		state:   state,
 		timeout: defaultTimeout,
 		// 返回对应的版本和错误
 		installRefHandlers: make(map[model.NodeInstallRefType]func(ctx context.Context, info installRefInfo) (string, error)),
@@ -53,8 +74,11 @@ func (s *NodeService) InstallRef(ctx context.Context, req dto.ReqNodeRef) (strin
 }
 
 func installPyRef(ctx context.Context, info installRefInfo) (string, error) {
-	name := info.pkgName
-	cmd := exec.CommandContext(ctx, "pip", "install", name)
+	name, ok := utils.PyRequirementName(info.pkgName)
+	if !ok {
+		return "", errPyPkgName
+	}
+	cmd := exec.CommandContext(ctx, "pip", "install", "--no-input", info.pkgName)
 
 	var (
 		stderr bytes.Buffer
@@ -67,13 +91,14 @@ func installPyRef(ctx context.Context, info installRefInfo) (string, error) {
 		return "", fmt.Errorf("install py pkg failed: %w, stderr: %s, stdout: %s",
 			err, stderr.String(), stdout.String())
 	}
-	return getInstalledPyVersion(info.pkgName)
+	return getInstalledPyVersion(name)
 }
 
 // getInstalledPyVersion 查询python包的版本
 func getInstalledPyVersion(pkgName string) (string, error) {
-	script := fmt.Sprintf(`from importlib.metadata import version; print(version("%s"))`, pkgName)
-	cmd := exec.Command("python", "-c", script)
+	// 包名通过参数传入，不拼接到脚本中
+	script := `import sys; from importlib.metadata import version; print(version(sys.argv[1]))`
+	cmd := exec.Command("python", "-c", script, pkgName)
 
 	var (
 		stderr bytes.Buffer
@@ -89,7 +114,79 @@ func getInstalledPyVersion(pkgName string) (string, error) {
 }
 
 func (s *NodeService) GetNodeInfo(ctx context.Context) map[string]any {
-	return getPyInfo()
+	info := getPyInfo()
+	info["scheduler_paused"] = job.SchedulerPaused()
+	info["maintenance"] = job.InMaintenance()
+	info["running"] = job.RunningCount()
+	if pool := worker.GetPool(); pool != nil {
+		info["worker_pool"] = pool.Stats()
+	}
+	return info
+}
+
+// PauseScheduler 暂停节点上所有job的调度
+func (s *NodeService) PauseScheduler(ctx context.Context) {
+	job.PauseScheduler()
+	slog.Info("node scheduler paused")
+}
+
+// ResumeScheduler 恢复节点上所有job的调度
+func (s *NodeService) ResumeScheduler(ctx context.Context) {
+	job.ResumeScheduler()
+	slog.Info("node scheduler resumed")
+}
+
+// EnterMaintenance 节点进入维护状态，之后的触发都跳过执行
+func (s *NodeService) EnterMaintenance(ctx context.Context) {
+	s.setMaintenance(true)
+	slog.Info("node enter maintenance")
+}
+
+// ExitMaintenance 节点退出维护状态
+func (s *NodeService) ExitMaintenance(ctx context.Context) {
+	s.setMaintenance(false)
+	slog.Info("node exit maintenance")
+}
+
+// RestoreMaintenance 恢复重启前的维护状态，节点启动时在开始调度之前调用
+func (s *NodeService) RestoreMaintenance(ctx context.Context) {
+	if s.state.Get().Maintenance {
+		job.EnterMaintenance()
+		slog.Info("restore node maintenance from local state")
+	}
+}
+
+// setMaintenance 修改维护状态并保存到本地，保存失败时只记录日志，当前进程中的状态已经生效
+func (s *NodeService) setMaintenance(maintenance bool) {
+	if maintenance {
+		job.EnterMaintenance()
+	} else {
+		job.ExitMaintenance()
+	}
+	if err := s.state.SetMaintenance(maintenance); err != nil {
+		slog.Error("save node maintenance to local state error", "maintenance", maintenance, "err", err)
+	}
+}
+
+// Drain 进入维护状态，并等待正在执行和排队中的任务结束
+func (s *NodeService) Drain(ctx context.Context, req dto.ReqNodeDrain) dto.RespNodeDrain {
+	timeout := time.Duration(req.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultDrainTimeout
+	} else if timeout > maxDrainTimeout {
+		timeout = maxDrainTimeout
+	}
+	s.setMaintenance(true)
+	slog.Info("node draining", "timeout", timeout, "running", job.RunningCount())
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	running := job.Drain(ctx)
+	slog.Info("node drain finished", "running", running)
+	return dto.RespNodeDrain{
+		Drained: running == 0,
+		Running: running,
+	}
 }
 
 func getPyInfo() map[string]any {
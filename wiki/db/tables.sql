@@ -5,7 +5,7 @@ CREATE TABLE `job` (
     `exec_type` smallint NOT NULL COMMENT '执行类型 1: shell; 2: http; 3:file',
     `active` smallint DEFAULT '1' COMMENT '启用状态 1启用；2停用',
     `cron_expr` varchar(128) DEFAULT NULL COMMENT 'cron 表达式',
-    `node_id` int NOT NULL COMMENT '节点id',
+    `node_id` int NOT NULL COMMENT '节点id，使用节点选择器时为0',
     `user_id` int NOT NULL COMMENT '用户id',
     `internal` json DEFAULT NULL,
     `created_time` datetime DEFAULT NULL,
@@ -27,18 +27,55 @@ CREATE TABLE `job_once` (
 CREATE TABLE `job_record` (
     `id` int NOT NULL AUTO_INCREMENT,
     `job_id` int NOT NULL,
-    `status` smallint DEFAULT NULL COMMENT '执行状态 0待执行；1运行中；2成功；3失败',
+    `status` smallint DEFAULT NULL COMMENT '执行状态 0待执行；1运行中；2成功；3失败；4已丢弃；5已跳过',
     `start_time` datetime DEFAULT NULL COMMENT '开始执行时间',
     `end_time` datetime DEFAULT NULL COMMENT '结束执行时间',
     `duration` float DEFAULT NULL COMMENT '运行耗时',
     `output` text COMMENT '执行文件内容输出',
     `error` text COMMENT '节点执行异常日志',
     `next_exec_time` datetime DEFAULT NULL COMMENT '任务下一次执行时间',
+    `trigger_type` smallint DEFAULT '1' COMMENT '触发方式 1定时触发；2补偿触发；3webhook触发；4文件触发；5处理任务触发',
+    `scheduled_time` datetime DEFAULT NULL COMMENT '计划执行时间',
+    `jitter_delay` float DEFAULT '0' COMMENT '执行前的随机延迟(秒)',
+    `node_id` int DEFAULT '0' COMMENT '执行节点id，为0时是任务固定的节点',
+    `parent_id` int DEFAULT '0' COMMENT '广播和分片执行时的父记录id',
+    `execution_id` varchar(64) DEFAULT NULL COMMENT '节点上报的执行id，用于重复上报去重',
     PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_execution_id` (`execution_id`),
     KEY `idx_status` (`status`),
-    KEY `idx_job_id` (`job_id`)
+    KEY `idx_job_id` (`job_id`),
+    KEY `idx_parent_id` (`parent_id`)
 ) ENGINE=InnoDB AUTO_INCREMENT=9655 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
+-- 任务故障转移记录表
+CREATE TABLE `job_failover_event` (
+    `id` int NOT NULL AUTO_INCREMENT,
+    `job_id` int NOT NULL,
+    `type` smallint NOT NULL COMMENT '事件类型 1转移到其他节点；2停用恢复节点上的任务；3转回原节点；4转移失败',
+    `from_node_id` int DEFAULT '0' COMMENT '转移前的节点id',
+    `to_node_id` int DEFAULT '0' COMMENT '转移后的节点id，停用和转移失败时为0',
+    `message` varchar(255) DEFAULT NULL,
+    `created_time` datetime DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_job_id` (`job_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- 任务webhook表
+CREATE TABLE `job_webhook` (
+    `id` int NOT NULL AUTO_INCREMENT,
+    `job_id` int NOT NULL,
+    `user_id` int NOT NULL,
+    `token` varchar(64) NOT NULL COMMENT '触发地址中的token',
+    `secret` varchar(64) DEFAULT NULL COMMENT 'HMAC签名密钥，为空时不校验签名',
+    `payload_mode` smallint DEFAULT '1' COMMENT '请求体传递方式 1标准输入；2环境变量',
+    `status` smallint DEFAULT '1' COMMENT '状态 1启用；2已撤销',
+    `created_time` datetime DEFAULT NULL,
+    `updated_time` datetime DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_token` (`token`),
+    KEY `idx_job_id` (`job_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
 -- 标签表
 CREATE TABLE `tag` (
     `id` int NOT NULL AUTO_INCREMENT,
@@ -67,6 +104,18 @@ CREATE TABLE `node` (
     `name` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL COMMENT '节点名称',
     `description` varchar(200) DEFAULT NULL COMMENT '节点描述',
     `address` varchar(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL COMMENT '节点地址，address=ip:port',
+    `maintenance` tinyint(1) DEFAULT '0' COMMENT '是否维护中',
+    `group_name` varchar(64) DEFAULT NULL COMMENT '节点分组',
+    `labels` json DEFAULT NULL COMMENT '节点标签，如 {"env": "prod"}',
+    `hostname` varchar(128) DEFAULT NULL COMMENT '自注册时上报的主机名',
+    `version` varchar(32) DEFAULT NULL COMMENT '自注册时上报的节点版本',
+    `capabilities` json DEFAULT NULL COMMENT '节点支持的执行类型，如 ["file", "python"]',
+    `heartbeat` tinyint(1) DEFAULT '0' COMMENT '是否自注册的节点，通过心跳判断是否在线',
+    `secret` varchar(64) DEFAULT NULL COMMENT 'master 请求节点时签名的密钥',
+    `token_secret` varchar(64) DEFAULT NULL COMMENT '节点生成访问 master 的 token 的密钥',
+    `user_id` int DEFAULT '0' COMMENT '添加节点的用户，自注册的节点为0',
+    `cert_serial` varchar(64) DEFAULT NULL COMMENT 'master 签发给节点的当前证书序列号，其他证书视为已吊销',
+    `cert_expire_time` bigint DEFAULT '0' COMMENT '节点证书的过期时间戳',
     `created_time` datetime DEFAULT NULL,
     `updated_time` datetime DEFAULT NULL,
     PRIMARY KEY (`id`)